@@ -45,6 +45,25 @@ type ClusterSpec struct {
 	DisplayName        string `json:"displayName,omitempty"`
 	Description        string `json:"description,omitempty"`
 	FleetWorkspaceName string `json:"fleetWorkspaceName,omitempty"`
+	// RKE2Config and K3sConfig hold the Kubernetes version Rancher upgrades imported clusters to.
+	RKE2Config *ImportedClusterConfig `json:"rke2Config,omitempty"`
+	K3sConfig  *ImportedClusterConfig `json:"k3sConfig,omitempty"`
+}
+
+// ImportedClusterConfig is the struct representing the upgrade configuration of an imported RKE2 or K3s Rancher Cluster.
+type ImportedClusterConfig struct {
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+}
+
+// KubernetesVersion returns the Kubernetes version Rancher manages for the imported cluster, if any.
+func (s ClusterSpec) KubernetesVersion() string {
+	for _, config := range []*ImportedClusterConfig{s.RKE2Config, s.K3sConfig} {
+		if config != nil && config.KubernetesVersion != "" {
+			return config.KubernetesVersion
+		}
+	}
+
+	return ""
 }
 
 // ClusterStatus is the struct representing the status of a Rancher Cluster.
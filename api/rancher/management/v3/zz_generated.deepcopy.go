@@ -30,7 +30,7 @@ func (in *Cluster) DeepCopyInto(out *Cluster) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -176,6 +176,16 @@ func (in *ClusterRegistrationTokenStatus) DeepCopy() *ClusterRegistrationTokenSt
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 	*out = *in
+	if in.RKE2Config != nil {
+		in, out := &in.RKE2Config, &out.RKE2Config
+		*out = new(ImportedClusterConfig)
+		**out = **in
+	}
+	if in.K3sConfig != nil {
+		in, out := &in.K3sConfig, &out.K3sConfig
+		*out = new(ImportedClusterConfig)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSpec.
@@ -210,6 +220,21 @@ func (in *ClusterStatus) DeepCopy() *ClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImportedClusterConfig) DeepCopyInto(out *ImportedClusterConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImportedClusterConfig.
+func (in *ImportedClusterConfig) DeepCopy() *ImportedClusterConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ImportedClusterConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Setting) DeepCopyInto(out *Setting) {
 	*out = *in
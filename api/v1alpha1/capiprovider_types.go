@@ -123,6 +123,55 @@ type CAPIProviderStatus struct {
 
 	// Name reflects actual provider name, which will be visible to users in 'kubectl get capiproviders -A -o wide'
 	Name string `json:"name,omitempty"`
+
+	// CertificateMigration reports the progress of the provider certificates migration between cert-manager and wrangler.
+	// +optional
+	CertificateMigration *CertificateMigrationStatus `json:"certificateMigration,omitempty"`
+}
+
+// CertificateIssuer is the component issuing the provider webhook certificates.
+// +kubebuilder:validation:Enum=cert-manager;wrangler
+type CertificateIssuer string
+
+const (
+	// CertManagerIssuer means the certificates are issued by cert-manager Certificates and Issuers.
+	CertManagerIssuer CertificateIssuer = "cert-manager"
+	// WranglerIssuer means the certificates are issued by wrangler for Services with the need-a-cert annotation.
+	WranglerIssuer CertificateIssuer = "wrangler"
+)
+
+// CertificateMigrationPhase is the phase of the provider certificates migration.
+type CertificateMigrationPhase string
+
+const (
+	// CertificateMigrationPending means the provider manifest is being applied to request certificates from the target issuer.
+	CertificateMigrationPending CertificateMigrationPhase = "Pending"
+	// CertificateMigrationVerifying means the migration waits for the target issuer to provide valid certificates.
+	// Resources of the previous issuer are kept until then.
+	CertificateMigrationVerifying CertificateMigrationPhase = "Verifying"
+	// CertificateMigrationCompleted means the certificates are provided by the target issuer.
+	CertificateMigrationCompleted CertificateMigrationPhase = "Completed"
+)
+
+// CertificateMigrationStatus is the status of the provider certificates migration.
+type CertificateMigrationStatus struct {
+	// Target is the issuer the certificates are migrated to.
+	Target CertificateIssuer `json:"target"`
+
+	// Phase is the current phase of the migration.
+	Phase CertificateMigrationPhase `json:"phase"`
+
+	// PendingCertificates lists the certificate Secrets or Certificates, which are not valid yet.
+	// +optional
+	PendingCertificates []string `json:"pendingCertificates,omitempty"`
+
+	// Message is a human readable description of the current phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime is the time of the last phase change.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
 }
 
 // CAPIProvider is the Schema for the CAPI Providers API.
@@ -34,6 +34,41 @@ const (
 
 	// CAPIProviderWranglerManagedCertificatesCondition is the condittion used when provider certificates managed by wrangler.
 	CAPIProviderWranglerManagedCertificatesCondition = "WranglerManagedCertificates"
+
+	// CAPIProviderWebhookCertificatesCondition reports the validity and expiry of the provider webhook serving certificates.
+	CAPIProviderWebhookCertificatesCondition = "WebhookCertificatesValid"
+
+	// RancherClusterReimportedCondition is set on the CAPI Cluster with the result of the last re-import attempt.
+	RancherClusterReimportedCondition = "RancherClusterReimported"
+
+	// RancherImportDryRunCondition is set on the CAPI Cluster with the summary of the last import dry-run.
+	RancherImportDryRunCondition = "RancherImportDryRun"
+
+	// ImportReadinessGatesCondition is set on the CAPI Cluster with the result of the import readiness gates.
+	ImportReadinessGatesCondition = "ImportReadinessGates"
+
+	// FleetNamespaceMigratedCondition is set on the CAPI Cluster with the state of the fleet agent namespace migration
+	// from `fleet-addon-agent` to `cattle-fleet-system` on the workload cluster.
+	FleetNamespaceMigratedCondition = "FleetNamespaceMigrated"
+
+	// KubernetesVersionManagedCondition is set on the CAPI Cluster, reporting whether CAPI or Rancher
+	// manages the Kubernetes version of the cluster.
+	KubernetesVersionManagedCondition = "KubernetesVersionManaged"
+
+	// EtcdSnapshotsSyncedCondition is set on the EtcdSnapshotInventory with the result of the last
+	// synchronization of the etcd snapshots from the workload cluster.
+	EtcdSnapshotsSyncedCondition = "EtcdSnapshotsSynced"
+
+	// EtcdSnapshotCompletedCondition is set on the EtcdSnapshot with the progress of the snapshot on the workload cluster.
+	EtcdSnapshotCompletedCondition = "SnapshotCompleted"
+
+	// UIPluginCompatibleCondition is set on the UIPluginStatus, reporting whether the CAPI UI extension
+	// is compatible with the running Turtles version.
+	UIPluginCompatibleCondition = "UIPluginCompatible"
+
+	// ImportPolicyValidCondition is set on the ImportPolicy, reporting whether its selectors are valid.
+	// Invalid policies are skipped when matching clusters.
+	ImportPolicyValidCondition = "Valid"
 )
 
 const (
@@ -43,3 +78,121 @@ const (
 	// CheckLatestProviderUnknownReason is a reason for an Unknown condition, due to provider not being available.
 	CheckLatestProviderUnknownReason = "ProviderUnknown"
 )
+
+const (
+	// ReimportInProgressReason is a reason for a False condition, while the registration token is regenerated
+	// and the import manifest is not applied yet.
+	ReimportInProgressReason = "ReimportInProgress"
+
+	// ReimportSucceededReason is a reason for a True condition, after the import manifest was re-applied.
+	ReimportSucceededReason = "ReimportSucceeded"
+
+	// ReimportFailedReason is a reason for a False condition, due to an error during the re-import attempt.
+	ReimportFailedReason = "ReimportFailed"
+
+	// ReimportRestartedReason is a reason for a True condition, when the Rancher cluster was missing
+	// and the CAPI cluster is handed back to the regular import flow.
+	ReimportRestartedReason = "ImportRestarted"
+)
+
+const (
+	// ImportDryRunCompletedReason is a reason for a True condition, after the import manifest was rendered
+	// and compared with the state of the workload cluster.
+	ImportDryRunCompletedReason = "DryRunCompleted"
+)
+
+const (
+	// ReadinessGatesPassedReason is a reason for a True condition, when all import readiness gates passed.
+	ReadinessGatesPassedReason = "ReadinessGatesPassed"
+
+	// ReadinessGatesFailedReason is a reason for a False condition, when some import readiness gates did not pass yet.
+	ReadinessGatesFailedReason = "ReadinessGatesFailed"
+
+	// ReadinessGatesErrorReason is a reason for a False condition, when the import readiness gates could not be evaluated.
+	ReadinessGatesErrorReason = "ReadinessGatesError"
+)
+
+const (
+	// WebhookCertificatesValidReason is a reason for a True condition, when all webhook certificates are valid.
+	WebhookCertificatesValidReason = "CertificatesValid"
+
+	// WebhookCertificatesExpiringReason is a reason for a False condition, when a webhook certificate expires soon.
+	WebhookCertificatesExpiringReason = "CertificatesExpiring"
+
+	// WebhookCertificatesInvalidReason is a reason for a False condition, when a webhook certificate is missing,
+	// expired or not valid for the webhook Service.
+	WebhookCertificatesInvalidReason = "CertificatesInvalid"
+
+	// WebhookCertificatesRotatingReason is a reason for a False condition, while webhook certificates are regenerated.
+	WebhookCertificatesRotatingReason = "CertificatesRotating"
+)
+
+const (
+	// FleetMigrationDeletingLegacyNamespaceReason is a reason for a False condition, while the legacy
+	// `fleet-addon-agent` namespace is deleted on the workload cluster.
+	FleetMigrationDeletingLegacyNamespaceReason = "DeletingLegacyNamespace"
+
+	// FleetMigrationWaitingForAgentReason is a reason for a False condition, while the fleet agent
+	// is not yet installed in the `cattle-fleet-system` namespace on the workload cluster.
+	FleetMigrationWaitingForAgentReason = "WaitingForFleetAgent"
+
+	// FleetMigrationStuckReason is a reason for a False condition, when the migration did not complete
+	// within the migration timeout.
+	FleetMigrationStuckReason = "MigrationStuck"
+
+	// FleetMigrationErrorReason is a reason for a False condition, when the workload cluster could not be checked.
+	FleetMigrationErrorReason = "MigrationError"
+
+	// FleetMigrationCompletedReason is a reason for a True condition, after the fleet agent namespace was migrated.
+	FleetMigrationCompletedReason = "MigrationCompleted"
+)
+
+const (
+	// VersionManagedByCAPIReason is a reason for a True condition, when the Kubernetes version is managed
+	// by the CAPI cluster topology.
+	VersionManagedByCAPIReason = "ManagedByCAPI"
+
+	// VersionManagedByRancherReason is a reason for a True condition, when the Kubernetes version is managed
+	// by Rancher.
+	VersionManagedByRancherReason = "ManagedByRancher"
+
+	// VersionManagementInvalidReason is a reason for a False condition, when the version management policy is invalid.
+	VersionManagementInvalidReason = "InvalidPolicy"
+)
+
+const (
+	// EtcdSnapshotsSyncedReason is a reason for a True condition, after the snapshots were read from the workload cluster.
+	EtcdSnapshotsSyncedReason = "SnapshotsSynced"
+
+	// EtcdSnapshotsSyncFailedReason is a reason for a False condition, when the snapshots could not be read
+	// from the workload cluster.
+	EtcdSnapshotsSyncFailedReason = "SyncFailed"
+)
+
+const (
+	// EtcdSnapshotInProgressReason is a reason for a False condition, while the snapshot is taken on the workload cluster.
+	EtcdSnapshotInProgressReason = "SnapshotInProgress"
+
+	// EtcdSnapshotFailedReason is a reason for a False condition, when the snapshot could not be taken.
+	EtcdSnapshotFailedReason = "SnapshotFailed"
+
+	// EtcdSnapshotCompletedReason is a reason for a True condition, when all snapshot files are ready to use.
+	EtcdSnapshotCompletedReason = "SnapshotCompleted"
+)
+
+const (
+	// UIPluginCompatibleReason is a reason for a True condition, when the UI extension is installed and compatible.
+	UIPluginCompatibleReason = "Compatible"
+
+	// UIPluginIncompatibleReason is a reason for a False condition, when the UI extension is not compatible
+	// with the running Turtles version, and was removed or not installed.
+	UIPluginIncompatibleReason = "Incompatible"
+)
+
+const (
+	// ImportPolicyValidReason is a reason for a True condition, when all selectors of the policy are valid.
+	ImportPolicyValidReason = "PolicyValid"
+
+	// ImportPolicyInvalidSelectorReason is a reason for a False condition, when a selector of the policy is invalid.
+	ImportPolicyInvalidSelectorReason = "InvalidSelector"
+)
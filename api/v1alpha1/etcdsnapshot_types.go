@@ -0,0 +1,188 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// EtcdSnapshotFinalizer is the finalizer on EtcdSnapshots, deleting the snapshot files on the workload cluster.
+	EtcdSnapshotFinalizer = "etcdsnapshot.turtles-capi.cattle.io"
+
+	// EtcdSnapshotScheduleLabel is the label on EtcdSnapshots created by an EtcdSnapshotSchedule, holding its name.
+	EtcdSnapshotScheduleLabel = "turtles-capi.cattle.io/etcd-snapshot-schedule"
+)
+
+// EtcdSnapshotPhase is the phase of an etcd snapshot.
+type EtcdSnapshotPhase string
+
+const (
+	// EtcdSnapshotPhasePending is the phase of a snapshot which was not requested on the workload cluster yet.
+	EtcdSnapshotPhasePending = EtcdSnapshotPhase("Pending")
+
+	// EtcdSnapshotPhaseRunning is the phase of a snapshot which is being taken on the workload cluster.
+	EtcdSnapshotPhaseRunning = EtcdSnapshotPhase("Running")
+
+	// EtcdSnapshotPhaseDone is the phase of a snapshot which is ready to be restored.
+	EtcdSnapshotPhaseDone = EtcdSnapshotPhase("Done")
+
+	// EtcdSnapshotPhaseFailed is the phase of a snapshot which could not be taken.
+	EtcdSnapshotPhaseFailed = EtcdSnapshotPhase("Failed")
+)
+
+// EtcdSnapshotSpec requests an etcd snapshot of a CAPI RKE2 cluster.
+//
+// The snapshot is taken with `rke2 etcd-snapshot save` on an etcd node of the workload cluster, using the
+// snapshot configuration of the node, e.g. the S3 settings. Deleting the EtcdSnapshot deletes the snapshot
+// files on the workload cluster.
+type EtcdSnapshotSpec struct {
+	// ClusterName is the name of the CAPI RKE2 cluster in the namespace of the snapshot.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="clusterName is immutable"
+	ClusterName string `json:"clusterName"`
+}
+
+// EtcdSnapshotStatus defines the observed state of an etcd snapshot.
+type EtcdSnapshotStatus struct {
+	// Phase is the phase of the snapshot.
+	// +optional
+	Phase EtcdSnapshotPhase `json:"phase,omitempty"`
+
+	// SnapshotName is the name the snapshot is saved with on the workload cluster.
+	// The ETCDSnapshotFiles of the snapshot are named after it.
+	// +optional
+	SnapshotName string `json:"snapshotName,omitempty"`
+
+	// Files are the ETCDSnapshotFiles of the snapshot on the workload cluster, i.e. the local and the S3 copy.
+	// +optional
+	Files []EtcdSnapshotInfo `json:"files,omitempty"`
+
+	// Conditions defines the current state of the snapshot.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// EtcdSnapshot is the Schema for the etcd snapshots API.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterName"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Snapshot",type="string",JSONPath=".status.snapshotName"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type EtcdSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EtcdSnapshotSpec   `json:"spec,omitempty"`
+	Status EtcdSnapshotStatus `json:"status,omitempty"`
+}
+
+// GetConditions returns the list of conditions for an EtcdSnapshot API object.
+func (s *EtcdSnapshot) GetConditions() []metav1.Condition {
+	return s.Status.Conditions
+}
+
+// SetConditions will set the given conditions on an EtcdSnapshot object.
+func (s *EtcdSnapshot) SetConditions(conditions []metav1.Condition) {
+	s.Status.Conditions = conditions
+}
+
+// IsFinished returns true if the snapshot is done or failed.
+func (s *EtcdSnapshot) IsFinished() bool {
+	return s.Status.Phase == EtcdSnapshotPhaseDone || s.Status.Phase == EtcdSnapshotPhaseFailed
+}
+
+//+kubebuilder:object:root=true
+
+// EtcdSnapshotList contains a list of EtcdSnapshots.
+type EtcdSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []EtcdSnapshot `json:"items"`
+}
+
+// EtcdSnapshotScheduleSpec defines the periodic etcd snapshots of a CAPI RKE2 cluster.
+//
+// Snapshots created by the schedule have the `etcd.turtles.cattle.io/automatic-snapshot` annotation.
+// Only the newest successful snapshots, according to the retention, and the last few failed snapshots are kept.
+// Deleting the schedule keeps its snapshots.
+type EtcdSnapshotScheduleSpec struct {
+	// ClusterName is the name of the CAPI RKE2 cluster in the namespace of the schedule.
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// Interval is the time between two snapshots.
+	Interval metav1.Duration `json:"interval"`
+
+	// Retention is the number of successful snapshots created by the schedule, which are kept.
+	// +optional
+	// +kubebuilder:default=5
+	// +kubebuilder:validation:Minimum=1
+	Retention int32 `json:"retention,omitempty"`
+
+	// Suspend stops the schedule from creating new snapshots. Existing snapshots are kept.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// EtcdSnapshotScheduleStatus defines the observed state of an etcd snapshot schedule.
+type EtcdSnapshotScheduleStatus struct {
+	// LastSnapshotTime is the scheduled time of the last snapshot created by the schedule.
+	// The next snapshot is due one interval later.
+	// +optional
+	LastSnapshotTime *metav1.Time `json:"lastSnapshotTime,omitempty"`
+
+	// LastSnapshotName is the name of the last EtcdSnapshot created by the schedule.
+	// +optional
+	LastSnapshotName string `json:"lastSnapshotName,omitempty"`
+}
+
+// EtcdSnapshotSchedule is the Schema for the etcd snapshot schedules API.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterName"
+// +kubebuilder:printcolumn:name="Interval",type="string",JSONPath=".spec.interval"
+// +kubebuilder:printcolumn:name="Retention",type="integer",JSONPath=".spec.retention"
+// +kubebuilder:printcolumn:name="LastSnapshot",type="date",JSONPath=".status.lastSnapshotTime"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type EtcdSnapshotSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EtcdSnapshotScheduleSpec   `json:"spec,omitempty"`
+	Status EtcdSnapshotScheduleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// EtcdSnapshotScheduleList contains a list of EtcdSnapshotSchedules.
+type EtcdSnapshotScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []EtcdSnapshotSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EtcdSnapshot{}, &EtcdSnapshotList{}, &EtcdSnapshotSchedule{}, &EtcdSnapshotScheduleList{})
+}
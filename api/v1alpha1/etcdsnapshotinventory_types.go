@@ -0,0 +1,141 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EtcdSnapshotInventorySpec defines the CAPI cluster, whose etcd snapshots are listed in the inventory.
+//
+// Inventories are created by Turtles for each CAPI RKE2 cluster, in the namespace and with the name
+// of the cluster, and are deleted together with the cluster.
+type EtcdSnapshotInventorySpec struct {
+	// ClusterName is the name of the CAPI cluster in the namespace of the inventory.
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+}
+
+// EtcdSnapshotInventoryStatus lists the etcd snapshots found on the workload cluster.
+type EtcdSnapshotInventoryStatus struct {
+	// Snapshots are the etcd snapshots of the cluster, mirrored from the ETCDSnapshotFiles
+	// on the workload cluster, the newest first.
+	// +optional
+	Snapshots []EtcdSnapshotInfo `json:"snapshots,omitempty"`
+
+	// SnapshotCount is the number of snapshots in the inventory.
+	// +optional
+	SnapshotCount int32 `json:"snapshotCount,omitempty"`
+
+	// LastSyncTime is the time the snapshots were last read from the workload cluster.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Conditions defines the current state of the inventory.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// EtcdSnapshotInfo describes an etcd snapshot of the workload cluster.
+type EtcdSnapshotInfo struct {
+	// Name of the ETCDSnapshotFile on the workload cluster.
+	Name string `json:"name"`
+
+	// SnapshotName is the name of the snapshot, as used by `rke2 etcd-snapshot`.
+	SnapshotName string `json:"snapshotName"`
+
+	// NodeName is the name of the node which took the snapshot.
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+
+	// Location is the absolute file:// or s3:// URI of the snapshot.
+	// +optional
+	Location string `json:"location,omitempty"`
+
+	// S3 is the S3 bucket holding the snapshot, if it was uploaded to S3.
+	// +optional
+	S3 *EtcdSnapshotS3Location `json:"s3,omitempty"`
+
+	// CreationTime is the time the snapshot was taken by etcd.
+	// +optional
+	CreationTime *metav1.Time `json:"creationTime,omitempty"`
+
+	// ReadyToUse indicates that the snapshot is available to be restored.
+	// +optional
+	ReadyToUse bool `json:"readyToUse,omitempty"`
+
+	// Error is the last error observed during the snapshot creation, if any.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// EtcdSnapshotS3Location defines the S3 bucket holding an etcd snapshot.
+type EtcdSnapshotS3Location struct {
+	// Endpoint is the S3 endpoint.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Bucket is the S3 bucket.
+	// +optional
+	Bucket string `json:"bucket,omitempty"`
+
+	// Region is the S3 region.
+	// +optional
+	Region string `json:"region,omitempty"`
+}
+
+// EtcdSnapshotInventory is the Schema for the etcd snapshot inventories API.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterName"
+// +kubebuilder:printcolumn:name="Snapshots",type="integer",JSONPath=".status.snapshotCount"
+// +kubebuilder:printcolumn:name="LastSync",type="date",JSONPath=".status.lastSyncTime"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type EtcdSnapshotInventory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EtcdSnapshotInventorySpec   `json:"spec,omitempty"`
+	Status EtcdSnapshotInventoryStatus `json:"status,omitempty"`
+}
+
+// GetConditions returns the list of conditions for an EtcdSnapshotInventory API object.
+func (i *EtcdSnapshotInventory) GetConditions() []metav1.Condition {
+	return i.Status.Conditions
+}
+
+// SetConditions will set the given conditions on an EtcdSnapshotInventory object.
+func (i *EtcdSnapshotInventory) SetConditions(conditions []metav1.Condition) {
+	i.Status.Conditions = conditions
+}
+
+//+kubebuilder:object:root=true
+
+// EtcdSnapshotInventoryList contains a list of EtcdSnapshotInventories.
+type EtcdSnapshotInventoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []EtcdSnapshotInventory `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EtcdSnapshotInventory{}, &EtcdSnapshotInventoryList{})
+}
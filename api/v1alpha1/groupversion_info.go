@@ -63,6 +63,11 @@ var (
 func AddKnownTypes(scheme *runtime.Scheme) {
 	scheme.AddKnownTypes(GroupVersion, &CAPIProvider{}, &CAPIProviderList{})
 	scheme.AddKnownTypes(GroupVersion, &ClusterctlConfig{}, &ClusterctlConfigList{})
+	scheme.AddKnownTypes(GroupVersion, &ImportPolicy{}, &ImportPolicyList{})
+	scheme.AddKnownTypes(GroupVersion, &RancherTarget{}, &RancherTargetList{})
+	scheme.AddKnownTypes(GroupVersion, &EtcdSnapshotInventory{}, &EtcdSnapshotInventoryList{})
+	scheme.AddKnownTypes(GroupVersion, &EtcdSnapshot{}, &EtcdSnapshotList{})
+	scheme.AddKnownTypes(GroupVersion, &EtcdSnapshotSchedule{}, &EtcdSnapshotScheduleList{})
 
 	for _, provider := range Providers {
 		if provider, ok := provider.(runtime.Object); ok {
@@ -0,0 +1,131 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImportPolicySpec defines which CAPI clusters are automatically imported into Rancher.
+//
+// A policy matches a cluster when all of the set selectors match, and the cluster is not excluded.
+// The import label on the cluster always takes precedence over policies, and matching policies take
+// precedence over the import label on the namespace. When several policies match, the one with the
+// highest priority is used. If policies with the same priority disagree, the cluster is not imported.
+type ImportPolicySpec struct {
+	// AutoImport defines if the matching clusters are imported into Rancher.
+	// Set to false to exclude the matching clusters from the import.
+	// +optional
+	// +kubebuilder:default=true
+	AutoImport *bool `json:"autoImport,omitempty"`
+
+	// Priority of the policy, used when more than one policy matches a cluster.
+	// +optional
+	// +kubebuilder:default=0
+	Priority int32 `json:"priority,omitempty"`
+
+	// NamespaceSelector selects the namespaces of the matching clusters. All namespaces are selected if not set.
+	// +optional
+	// +kubebuilder:validation:XValidation:message="matchExpressions operator must be In or NotIn with values, or Exists or DoesNotExist without values",rule="!has(self.matchExpressions) || self.matchExpressions.all(e, e.operator in ['In', 'NotIn'] ? has(e.values) && size(e.values) > 0 : e.operator in ['Exists', 'DoesNotExist'] && (!has(e.values) || size(e.values) == 0))"
+	//
+	//nolint:lll
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// ClusterSelector selects the matching clusters by their labels. All clusters are selected if not set.
+	// +optional
+	// +kubebuilder:validation:XValidation:message="matchExpressions operator must be In or NotIn with values, or Exists or DoesNotExist without values",rule="!has(self.matchExpressions) || self.matchExpressions.all(e, e.operator in ['In', 'NotIn'] ? has(e.values) && size(e.values) > 0 : e.operator in ['Exists', 'DoesNotExist'] && (!has(e.values) || size(e.values) == 0))"
+	//
+	//nolint:lll
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// ClusterClassNames restricts the policy to clusters with a managed topology using one of the ClusterClasses.
+	// +optional
+	// +kubebuilder:example={rke2-aws, kubeadm-docker}
+	ClusterClassNames []string `json:"clusterClassNames,omitempty"`
+
+	// Exclusions lists the clusters which are never matched by the policy.
+	// +optional
+	Exclusions *ImportPolicyExclusions `json:"exclusions,omitempty"`
+}
+
+// ImportPolicyExclusions defines the clusters excluded from an import policy.
+type ImportPolicyExclusions struct {
+	// Namespaces is a list of namespace names, whose clusters are excluded.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// ClusterSelector excludes the clusters matching the selector.
+	// +optional
+	// +kubebuilder:validation:XValidation:message="matchExpressions operator must be In or NotIn with values, or Exists or DoesNotExist without values",rule="!has(self.matchExpressions) || self.matchExpressions.all(e, e.operator in ['In', 'NotIn'] ? has(e.values) && size(e.values) > 0 : e.operator in ['Exists', 'DoesNotExist'] && (!has(e.values) || size(e.values) == 0))"
+	//
+	//nolint:lll
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+}
+
+// ImportPolicyStatus reports the state of an import policy.
+type ImportPolicyStatus struct {
+	// Conditions defines the current state of the import policy.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ImportPolicy is the Schema for the import policies API.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="AutoImport",type="boolean",JSONPath=".spec.autoImport"
+// +kubebuilder:printcolumn:name="Priority",type="integer",JSONPath=".spec.priority"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type ImportPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImportPolicySpec   `json:"spec,omitempty"`
+	Status ImportPolicyStatus `json:"status,omitempty"`
+}
+
+// ShouldAutoImport returns true if the clusters matching the policy should be imported.
+func (p *ImportPolicy) ShouldAutoImport() bool {
+	return p.Spec.AutoImport == nil || *p.Spec.AutoImport
+}
+
+// GetConditions returns the list of conditions for an ImportPolicy API object.
+func (p *ImportPolicy) GetConditions() []metav1.Condition {
+	return p.Status.Conditions
+}
+
+// SetConditions will set the given conditions on an ImportPolicy object.
+func (p *ImportPolicy) SetConditions(conditions []metav1.Condition) {
+	p.Status.Conditions = conditions
+}
+
+//+kubebuilder:object:root=true
+
+// ImportPolicyList contains a list of ImportPolicies.
+type ImportPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ImportPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImportPolicy{}, &ImportPolicyList{})
+}
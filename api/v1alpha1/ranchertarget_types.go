@@ -0,0 +1,88 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RancherTargetKubeconfigKey is the default key of the kubeconfig in the RancherTarget Secret.
+	RancherTargetKubeconfigKey = "value"
+)
+
+// RancherTargetSpec defines a Rancher Manager cluster, where CAPI clusters are imported.
+//
+// A CAPI cluster is imported into a target when the `turtles-capi.cattle.io/rancher-target` annotation
+// on the cluster, or on its namespace, names the target. The cluster annotation takes precedence.
+// Clusters without the annotation are imported into the default Rancher Manager cluster.
+type RancherTargetSpec struct {
+	// KubeconfigSecretRef references a Secret holding the kubeconfig for the Rancher Manager cluster.
+	KubeconfigSecretRef KubeconfigSecretReference `json:"kubeconfigSecretRef"`
+}
+
+// KubeconfigSecretReference references a kubeconfig stored in a Secret.
+type KubeconfigSecretReference struct {
+	// Name of the Secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the Secret.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// Key of the Secret data holding the kubeconfig.
+	// +optional
+	// +kubebuilder:default=value
+	Key string `json:"key,omitempty"`
+}
+
+// RancherTarget is the Schema for the Rancher targets API.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Secret",type="string",JSONPath=".spec.kubeconfigSecretRef.name"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type RancherTarget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RancherTargetSpec `json:"spec,omitempty"`
+}
+
+// KubeconfigKey returns the key of the Secret data holding the kubeconfig.
+func (t *RancherTarget) KubeconfigKey() string {
+	if t.Spec.KubeconfigSecretRef.Key == "" {
+		return RancherTargetKubeconfigKey
+	}
+
+	return t.Spec.KubeconfigSecretRef.Key
+}
+
+//+kubebuilder:object:root=true
+
+// RancherTargetList contains a list of RancherTargets.
+type RancherTargetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []RancherTarget `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RancherTarget{}, &RancherTargetList{})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// UIPluginStatusSpec defines the UI extension, whose state is reported.
+//
+// UIPluginStatuses are created by Turtles in its own namespace, with the name of the UIPlugin.
+// The UIPlugin status is owned by Rancher, so the state of the CAPI UI extension is reported here instead.
+type UIPluginStatusSpec struct {
+	// PluginName is the name of the UIPlugin in the `cattle-ui-plugin-system` namespace.
+	// +kubebuilder:validation:MinLength=1
+	PluginName string `json:"pluginName"`
+}
+
+// UIPluginStatusStatus reports the state of the CAPI UI extension managed by Turtles.
+type UIPluginStatusStatus struct {
+	// State is the state of the UI extension: Installed, Upgraded or Incompatible.
+	// +optional
+	State string `json:"state,omitempty"`
+
+	// PluginVersion is the version of the last UI extension provided to Turtles.
+	// +optional
+	PluginVersion string `json:"pluginVersion,omitempty"`
+
+	// TurtlesVersion is the Turtles version, which checked the UI extension compatibility.
+	// +optional
+	TurtlesVersion string `json:"turtlesVersion,omitempty"`
+
+	// Conditions defines the current state of the UI extension.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// UIPluginStatus is the Schema for the UI plugin statuses API.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Plugin",type="string",JSONPath=".spec.pluginName"
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.pluginVersion"
+// +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type UIPluginStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   UIPluginStatusSpec   `json:"spec,omitempty"`
+	Status UIPluginStatusStatus `json:"status,omitempty"`
+}
+
+// GetConditions returns the list of conditions for an UIPluginStatus API object.
+func (s *UIPluginStatus) GetConditions() []metav1.Condition {
+	return s.Status.Conditions
+}
+
+// SetConditions will set the given conditions on an UIPluginStatus object.
+func (s *UIPluginStatus) SetConditions(conditions []metav1.Condition) {
+	s.Status.Conditions = conditions
+}
+
+//+kubebuilder:object:root=true
+
+// UIPluginStatusList contains a list of UIPluginStatuses.
+type UIPluginStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []UIPluginStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&UIPluginStatus{}, &UIPluginStatusList{})
+}
@@ -42,6 +42,11 @@ const (
 	// into CAPI-specific static identity objects (`AWSClusterStaticIdentity`).
 	// NOTE: currently this feature is only available for CAPA and `AWSClusterStaticIdentity`.
 	RancherCCTranslation featuregate.Feature = "rancher-credential-translation"
+
+	// EtcdSnapshots if enabled Turtles will mirror the etcd snapshots of CAPI RKE2 workload clusters
+	// into EtcdSnapshotInventory objects on the management cluster, and take snapshots requested with
+	// EtcdSnapshot and EtcdSnapshotSchedule objects.
+	EtcdSnapshots featuregate.Feature = "etcd-snapshots"
 )
 
 func init() {
@@ -56,4 +61,5 @@ var DefaultGates = map[featuregate.Feature]featuregate.FeatureSpec{
 	UseRancherDefaultRegistry: {Default: true, PreRelease: featuregate.Beta},
 	UseCAAPF:                  {Default: false, PreRelease: featuregate.Alpha},
 	RancherCCTranslation:      {Default: false, PreRelease: featuregate.Alpha},
+	EtcdSnapshots:             {Default: false, PreRelease: featuregate.Alpha},
 }
@@ -0,0 +1,128 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feature
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"k8s.io/component-base/featuregate"
+)
+
+var (
+	overridesLock sync.Mutex
+	// commandLineGates holds the Turtles feature gates set by defaults and the `--feature-gates` flag.
+	commandLineGates map[string]bool
+	// overrides holds the Turtles feature gates last set through SetOverrides.
+	overrides map[string]bool
+)
+
+// SnapshotCommandLine records the Turtles feature gates set by defaults and the `--feature-gates` flag.
+// Overrides are always applied on top of this state, so removing an override restores the command line value.
+// It must be called after the flags are parsed.
+func SnapshotCommandLine() {
+	overridesLock.Lock()
+	defer overridesLock.Unlock()
+
+	commandLineGates = currentGates()
+}
+
+// ParseOverrides parses feature gate overrides from ConfigMap data, where each key is a Turtles feature gate name
+// and each value is a boolean.
+func ParseOverrides(data map[string]string) (map[string]bool, error) {
+	parsed := map[string]bool{}
+
+	for name, value := range data {
+		if _, ok := DefaultGates[featuregate.Feature(name)]; !ok {
+			return nil, fmt.Errorf("unknown feature gate %q", name)
+		}
+
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for feature gate %q: %w", value, name, err)
+		}
+
+		parsed[name] = enabled
+	}
+
+	return parsed, nil
+}
+
+// SetOverrides sets the Turtles feature gates to the command line values, overridden by the given values.
+// On error the feature gates are left unchanged.
+func SetOverrides(values map[string]bool) error {
+	overridesLock.Lock()
+	defer overridesLock.Unlock()
+
+	if commandLineGates == nil {
+		commandLineGates = currentGates()
+	}
+
+	gates := maps.Clone(commandLineGates)
+	maps.Copy(gates, values)
+
+	if err := MutableGates.SetFromMap(gates); err != nil {
+		return fmt.Errorf("setting feature gates: %w", err)
+	}
+
+	overrides = maps.Clone(values)
+
+	return nil
+}
+
+// Status is the effective state of the Turtles feature gates.
+type Status struct {
+	// Gates holds the effective value of each Turtles feature gate.
+	Gates map[string]bool `json:"gates"`
+	// Overrides holds the values overriding the command line, set at runtime.
+	Overrides map[string]bool `json:"overrides,omitempty"`
+}
+
+// CurrentStatus returns the effective state of the Turtles feature gates.
+func CurrentStatus() Status {
+	overridesLock.Lock()
+	defer overridesLock.Unlock()
+
+	return Status{
+		Gates:     currentGates(),
+		Overrides: maps.Clone(overrides),
+	}
+}
+
+// StatusHandler serves the effective state of the Turtles feature gates in the JSON format.
+func StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(CurrentStatus()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func currentGates() map[string]bool {
+	gates := map[string]bool{}
+	for name := range DefaultGates {
+		gates[string(name)] = Gates.Enabled(name)
+	}
+
+	return gates
+}
@@ -0,0 +1,58 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feature
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseOverrides(t *testing.T) {
+	g := NewWithT(t)
+
+	overrides, err := ParseOverrides(map[string]string{string(UIPlugin): "true"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(overrides).To(Equal(map[string]bool{string(UIPlugin): true}))
+
+	_, err = ParseOverrides(map[string]string{"unknown": "true"})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = ParseOverrides(map[string]string{string(UIPlugin): "maybe"})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestSetOverrides(t *testing.T) {
+	g := NewWithT(t)
+
+	SnapshotCommandLine()
+	defaultValue := Gates.Enabled(UIPlugin)
+
+	g.Expect(SetOverrides(map[string]bool{string(UIPlugin): !defaultValue})).To(Succeed())
+	g.Expect(Gates.Enabled(UIPlugin)).To(Equal(!defaultValue))
+	g.Expect(CurrentStatus().Overrides).To(HaveKeyWithValue(string(UIPlugin), !defaultValue))
+
+	recorder := httptest.NewRecorder()
+	StatusHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/feature-gates", nil))
+	g.Expect(recorder.Body.String()).To(ContainSubstring(`"ui-plugin":`))
+
+	// Removing the override restores the command line value.
+	g.Expect(SetOverrides(nil)).To(Succeed())
+	g.Expect(Gates.Enabled(UIPlugin)).To(Equal(defaultValue))
+	g.Expect(CurrentStatus().Overrides).To(BeEmpty())
+}
@@ -19,56 +19,312 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	capiannotations "sigs.k8s.io/cluster-api/util/annotations"
 
 	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
 )
 
 // CAPICleanupReconciler is a reconciler for cleanup of managementv3 clusters.
 type CAPICleanupReconciler struct {
 	Client client.Client
 	Scheme *runtime.Scheme
+	// RancherClient is the client for the Rancher Manager cluster, holding Rancher clusters.
+	// Defaults to Client, when Turtles runs in the Rancher Manager cluster.
+	RancherClient client.Client
+	// RancherCache is the cache for the Rancher Manager cluster, used to watch Rancher clusters.
+	// Defaults to the manager cache.
+	RancherCache cache.Cache
+
+	// RancherTargets are the Rancher Manager clusters of the RancherTargets. A cleanup controller is started
+	// for the Rancher clusters of each target.
+	// +optional
+	RancherTargets *RancherTargets
+
+	// OrphanCleanup enables deletion of Rancher clusters, whose owning CAPI cluster no longer exists.
+	OrphanCleanup bool
+	// OrphanGracePeriod is the time a Rancher cluster has to stay orphaned before it is deleted.
+	OrphanGracePeriod time.Duration
+
+	recorder events.EventRecorder
+	cache    cache.Cache
+	options  controller.Options
 }
 
 // SetupWithManager sets up reconciler with manager.
-func (r *CAPICleanupReconciler) SetupWithManager(_ context.Context, mgr ctrl.Manager, options controller.Options) error {
+func (r *CAPICleanupReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
+	if r.RancherCache == nil {
+		r.RancherCache = mgr.GetCache()
+	}
+
 	if err := ctrl.NewControllerManagedBy(mgr).
 		Named("cleanup").
-		For(&managementv3.Cluster{}).
+		WatchesRawSource(source.Kind(r.RancherCache, &managementv3.Cluster{},
+			&handler.TypedEnqueueRequestForObject[*managementv3.Cluster]{},
+			ownedRancherClusterPredicate(),
+		)).
+		Watches(&clusterv1.Cluster{},
+			handler.EnqueueRequestsFromMapFunc(r.capiClusterToRancherClusters(ctx)),
+			builder.WithPredicates(deletedCAPIClusterPredicate()),
+		).
 		WithOptions(options).
-		WithEventFilter(predicate.NewPredicateFuncs(func(object client.Object) bool {
-			_, exist := object.GetLabels()[ownedLabelName]
-			return exist
-		})).
-		Complete(reconcile.AsReconciler(r.Client, r)); err != nil {
+		Complete(reconcile.AsReconciler(r.rancherClient(), r)); err != nil {
 		return fmt.Errorf("creating new downgrade controller: %w", err)
 	}
 
+	r.recorder = mgr.GetEventRecorder("rancher-turtles-cleanup")
+	r.cache = mgr.GetCache()
+	r.options = options
+
+	if r.RancherTargets != nil {
+		r.RancherTargets.Watch(r.watchRancherTarget)
+	}
+
+	return nil
+}
+
+// watchRancherTarget starts a cleanup controller for the Rancher clusters of a RancherTarget,
+// running until the target is stopped.
+func (r *CAPICleanupReconciler) watchRancherTarget(ctx context.Context, name string, target cluster.Cluster) error {
+	targetReconciler := &CAPICleanupReconciler{
+		Client:            r.Client,
+		Scheme:            r.Scheme,
+		RancherClient:     target.GetClient(),
+		RancherCache:      target.GetCache(),
+		OrphanCleanup:     r.OrphanCleanup,
+		OrphanGracePeriod: r.OrphanGracePeriod,
+		recorder:          r.recorder,
+	}
+
+	c, err := controller.NewTypedUnmanaged("cleanup-"+name, controller.TypedOptions[reconcile.Request]{
+		Reconciler:              reconcile.AsReconciler(targetReconciler.rancherClient(), targetReconciler),
+		MaxConcurrentReconciles: r.options.MaxConcurrentReconciles,
+		Logger:                  log.FromContext(ctx),
+		// The controller is created again each time the target changes.
+		SkipNameValidation: ptr.To(true),
+	})
+	if err != nil {
+		return fmt.Errorf("creating cleanup controller: %w", err)
+	}
+
+	if err := c.Watch(source.Kind(target.GetCache(), &managementv3.Cluster{},
+		&handler.TypedEnqueueRequestForObject[*managementv3.Cluster]{},
+		ownedRancherClusterPredicate(),
+	)); err != nil {
+		return fmt.Errorf("adding watch for Rancher clusters: %w", err)
+	}
+
+	if err := c.Watch(source.Kind[client.Object](r.cache, &clusterv1.Cluster{},
+		handler.EnqueueRequestsFromMapFunc(targetReconciler.capiClusterToRancherClusters(ctx)),
+		deletedCAPIClusterPredicate(),
+	)); err != nil {
+		return fmt.Errorf("adding watch for CAPI clusters: %w", err)
+	}
+
+	go func() {
+		if err := c.Start(ctx); err != nil {
+			log.FromContext(ctx).Error(err, "Rancher target cleanup controller stopped with error")
+		}
+	}()
+
 	return nil
 }
 
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+
 // Reconcile performs check for clusters and removes finalizer on the clusters in deleteion
-// still containing the turtles finalizer.
+// still containing the turtles finalizer. Rancher clusters, whose owning CAPI cluster no longer exists,
+// are reported as orphaned and deleted after the grace period, if orphan cleanup is enabled.
 func (r *CAPICleanupReconciler) Reconcile(ctx context.Context, cluster *managementv3.Cluster) (res ctrl.Result, err error) {
 	log := log.FromContext(ctx)
 
 	patchBase := client.MergeFromWithOptions(cluster.DeepCopy(), client.MergeFromWithOptimisticLock{})
 
-	if cluster.DeletionTimestamp.IsZero() || !controllerutil.RemoveFinalizer(cluster, managementv3.CapiClusterFinalizer) {
+	if cluster.DeletionTimestamp.IsZero() {
+		return r.reconcileOrphan(ctx, cluster)
+	}
+
+	if !controllerutil.RemoveFinalizer(cluster, managementv3.CapiClusterFinalizer) {
 		return
 	}
 
-	if err = r.Client.Patch(ctx, cluster, patchBase); err != nil {
+	if err = r.rancherClient().Patch(ctx, cluster, patchBase); err != nil {
 		log.Error(err, "Unable to remove turtles finalizer from cluster"+cluster.GetName())
 	}
 
 	return
 }
+
+// reconcileOrphan checks if the CAPI cluster owning the Rancher cluster still exists. Orphaned clusters
+// are marked with the orphaned-since annotation, and deleted once the grace period expired.
+func (r *CAPICleanupReconciler) reconcileOrphan(ctx context.Context, cluster *managementv3.Cluster) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	labels := cluster.GetLabels()
+
+	ownerName, ownerNamespace := labels[capiClusterOwner], labels[capiClusterOwnerNamespace]
+	if ownerName == "" || ownerNamespace == "" {
+		return ctrl.Result{}, nil
+	}
+
+	capiCluster := &clusterv1.Cluster{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: ownerNamespace, Name: ownerName}, capiCluster)
+
+	if client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("error getting owner CAPI cluster: %w", err)
+	}
+
+	if err == nil && capiannotations.IsPaused(capiCluster, capiCluster) {
+		log.V(4).Info("Owner CAPI cluster is paused, skipping")
+		return ctrl.Result{}, nil
+	}
+
+	if apierrors.IsNotFound(err) {
+		movedAway, idErr := managedByOtherCluster(ctx, r.Client, cluster)
+		if idErr != nil {
+			return ctrl.Result{}, idErr
+		}
+
+		if movedAway {
+			log.V(4).Info("Rancher cluster is managed by another CAPI management cluster, skipping")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	patchBase := client.MergeFromWithOptions(cluster.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	annotations := cluster.GetAnnotations()
+
+	if err == nil {
+		if _, found := annotations[turtlesannotations.OrphanedSinceAnnotation]; !found {
+			return ctrl.Result{}, nil
+		}
+
+		log.Info("Owner CAPI cluster exists again, removing orphaned annotation")
+
+		delete(annotations, turtlesannotations.OrphanedSinceAnnotation)
+		cluster.SetAnnotations(annotations)
+
+		return ctrl.Result{}, r.rancherClient().Patch(ctx, cluster, patchBase)
+	}
+
+	orphanedSince, parseErr := time.Parse(time.RFC3339, annotations[turtlesannotations.OrphanedSinceAnnotation])
+	if parseErr != nil {
+		log.Info("Owner CAPI cluster not found, marking Rancher cluster as orphaned",
+			"capiCluster", client.ObjectKey{Namespace: ownerNamespace, Name: ownerName})
+
+		orphanedSince = time.Now().UTC()
+
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		annotations[turtlesannotations.OrphanedSinceAnnotation] = orphanedSince.Format(time.RFC3339)
+		cluster.SetAnnotations(annotations)
+
+		if err := r.rancherClient().Patch(ctx, cluster, patchBase); err != nil {
+			return ctrl.Result{}, fmt.Errorf("error marking Rancher cluster as orphaned: %w", err)
+		}
+
+		r.recorder.Eventf(cluster, nil, corev1.EventTypeWarning, "Orphaned", "OrphanDetected",
+			"Owner CAPI cluster %s/%s no longer exists", ownerNamespace, ownerName)
+	}
+
+	if !r.OrphanCleanup {
+		return ctrl.Result{}, nil
+	}
+
+	if remaining := time.Until(orphanedSince.Add(r.OrphanGracePeriod)); remaining > 0 {
+		log.V(2).Info("Rancher cluster is orphaned, waiting for the grace period", "remaining", remaining)
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	log.Info("Deleting orphaned Rancher cluster", "orphanedSince", orphanedSince)
+
+	if err := r.rancherClient().Delete(ctx, cluster); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("error deleting orphaned Rancher cluster: %w", err)
+	}
+
+	r.recorder.Eventf(cluster, nil, corev1.EventTypeNormal, "OrphanDeleted", "OrphanCleanup",
+		"Orphaned cluster deleted after grace period %s", r.OrphanGracePeriod)
+
+	return ctrl.Result{}, nil
+}
+
+// ownedRancherClusterPredicate selects the Rancher clusters owned by Turtles.
+func ownedRancherClusterPredicate() predicate.TypedPredicate[*managementv3.Cluster] {
+	return predicate.NewTypedPredicateFuncs(func(cluster *managementv3.Cluster) bool {
+		_, exist := cluster.GetLabels()[ownedLabelName]
+
+		return exist
+	})
+}
+
+// deletedCAPIClusterPredicate selects deleted CAPI clusters, which were not moved away.
+func deletedCAPIClusterPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool { return false },
+		UpdateFunc: func(event.UpdateEvent) bool { return false },
+		// Paused clusters are deleted from the source management cluster by `clusterctl move`.
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			cluster, ok := e.Object.(*clusterv1.Cluster)
+
+			return !ok || !capiannotations.IsPaused(cluster, cluster)
+		},
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
+
+// rancherClient returns the client for the Rancher Manager cluster.
+func (r *CAPICleanupReconciler) rancherClient() client.Client {
+	if r.RancherClient != nil {
+		return r.RancherClient
+	}
+
+	return r.Client
+}
+
+// capiClusterToRancherClusters maps CAPI cluster events to the Rancher clusters owned by it.
+func (r *CAPICleanupReconciler) capiClusterToRancherClusters(ctx context.Context) handler.MapFunc {
+	log := log.FromContext(ctx)
+
+	return func(_ context.Context, o client.Object) []ctrl.Request {
+		rancherClusters := &managementv3.ClusterList{}
+		if err := r.rancherClient().List(ctx, rancherClusters, client.MatchingLabels{
+			capiClusterOwner:          o.GetName(),
+			capiClusterOwnerNamespace: o.GetNamespace(),
+			ownedLabelName:            "",
+		}); err != nil {
+			log.Error(err, "getting rancher clusters")
+			return nil
+		}
+
+		reqs := []ctrl.Request{}
+		for _, cluster := range rancherClusters.Items {
+			reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&cluster)})
+		}
+
+		return reqs
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+var _ = Describe("CAPICleanupReconciler orphaned clusters", func() {
+	var (
+		ctx            context.Context
+		cleanupScheme  *runtime.Scheme
+		rancherCluster *managementv3.Cluster
+		r              *CAPICleanupReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		cleanupScheme = runtime.NewScheme()
+		Expect(managementv3.AddToScheme(cleanupScheme)).To(Succeed())
+		Expect(clusterv1.AddToScheme(cleanupScheme)).To(Succeed())
+		Expect(corev1.AddToScheme(cleanupScheme)).To(Succeed())
+
+		rancherCluster = &managementv3.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "c-orphan",
+				Namespace: "default",
+				Labels: map[string]string{
+					capiClusterOwner:          "capi-cluster",
+					capiClusterOwnerNamespace: "default",
+					ownedLabelName:            "",
+				},
+			},
+		}
+
+		r = &CAPICleanupReconciler{
+			OrphanGracePeriod: time.Hour,
+			recorder:          events.NewFakeRecorder(10),
+		}
+	})
+
+	It("should mark the Rancher cluster as orphaned without deleting it", func() {
+		r.Client = fake.NewClientBuilder().WithScheme(cleanupScheme).WithObjects(rancherCluster).Build()
+
+		_, err := r.Reconcile(ctx, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(r.Client.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).To(HaveKey(turtlesannotations.OrphanedSinceAnnotation))
+	})
+
+	It("should requeue until the grace period expires when cleanup is enabled", func() {
+		r.OrphanCleanup = true
+		r.Client = fake.NewClientBuilder().WithScheme(cleanupScheme).WithObjects(rancherCluster).Build()
+
+		res, err := r.Reconcile(ctx, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+		Expect(r.Client.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)).To(Succeed())
+	})
+
+	It("should delete the Rancher cluster after the grace period when cleanup is enabled", func() {
+		r.OrphanCleanup = true
+		rancherCluster.Annotations = map[string]string{
+			turtlesannotations.OrphanedSinceAnnotation: time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+		}
+		r.Client = fake.NewClientBuilder().WithScheme(cleanupScheme).WithObjects(rancherCluster).Build()
+
+		_, err := r.Reconcile(ctx, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = r.Client.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)
+		Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should remove the orphaned annotation when the CAPI cluster exists", func() {
+		r.OrphanCleanup = true
+		rancherCluster.Annotations = map[string]string{
+			turtlesannotations.OrphanedSinceAnnotation: time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+		}
+		capiCluster := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "capi-cluster",
+				Namespace: "default",
+			},
+		}
+		r.Client = fake.NewClientBuilder().WithScheme(cleanupScheme).WithObjects(rancherCluster, capiCluster).Build()
+
+		_, err := r.Reconcile(ctx, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(r.Client.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).NotTo(HaveKey(turtlesannotations.OrphanedSinceAnnotation))
+	})
+
+	It("should skip the Rancher cluster while the CAPI cluster is paused", func() {
+		r.OrphanCleanup = true
+		rancherCluster.Annotations = map[string]string{
+			turtlesannotations.OrphanedSinceAnnotation: time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+		}
+		capiCluster := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "capi-cluster",
+				Namespace: "default",
+			},
+			Spec: clusterv1.ClusterSpec{Paused: ptr.To(true)},
+		}
+		r.Client = fake.NewClientBuilder().WithScheme(cleanupScheme).WithObjects(rancherCluster, capiCluster).Build()
+
+		_, err := r.Reconcile(ctx, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(r.Client.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).To(HaveKey(turtlesannotations.OrphanedSinceAnnotation))
+	})
+
+	It("should not mark a Rancher cluster managed by another management cluster as orphaned", func() {
+		r.OrphanCleanup = true
+		rancherCluster.Annotations = map[string]string{
+			turtlesannotations.ManagementClusterAnnotation: "other",
+		}
+		kubeSystem := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceSystem, UID: "current"}}
+		r.Client = fake.NewClientBuilder().WithScheme(cleanupScheme).WithObjects(rancherCluster, kubeSystem).Build()
+
+		res, err := r.Reconcile(ctx, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(BeZero())
+
+		Expect(r.Client.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).NotTo(HaveKey(turtlesannotations.OrphanedSinceAnnotation))
+	})
+
+	It("should read the Rancher cluster from the Rancher client and the CAPI cluster from the client", func() {
+		r.OrphanCleanup = true
+		rancherCluster.Annotations = map[string]string{
+			turtlesannotations.OrphanedSinceAnnotation: time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+		}
+		capiCluster := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "capi-cluster",
+				Namespace: "default",
+			},
+		}
+		r.Client = fake.NewClientBuilder().WithScheme(cleanupScheme).WithObjects(capiCluster).Build()
+		r.RancherClient = fake.NewClientBuilder().WithScheme(cleanupScheme).WithObjects(rancherCluster).Build()
+
+		_, err := r.Reconcile(ctx, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(r.RancherClient.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).NotTo(HaveKey(turtlesannotations.OrphanedSinceAnnotation))
+	})
+})
@@ -0,0 +1,165 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/cluster-api/controllers/external"
+
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+const (
+	defaultClusterDescription = "CAPI cluster imported to Rancher"
+
+	displayNameTemplateKey = "displayName"
+	descriptionTemplateKey = "description"
+)
+
+// ClusterTemplates defines the Go templates used to render the display name and the description
+// of Rancher clusters created for CAPI clusters.
+type ClusterTemplates struct {
+	// DisplayName is the template of the Rancher cluster display name. Defaults to the CAPI cluster name.
+	DisplayName string
+	// Description is the template of the Rancher cluster description. The description annotation on
+	// the CAPI cluster takes precedence.
+	Description string
+	// ConfigMap references a ConfigMap holding the templates in the displayName and description keys.
+	// Templates in the ConfigMap override the ones above.
+	ConfigMap client.ObjectKey
+}
+
+// IsSet returns true if any template is configured.
+func (t ClusterTemplates) IsSet() bool {
+	return t.DisplayName != "" || t.Description != "" || t.ConfigMap.Name != ""
+}
+
+// clusterTemplateData is the data the cluster templates are executed with.
+type clusterTemplateData struct {
+	Name              string
+	Namespace         string
+	Labels            map[string]string
+	ClusterClass      string
+	KubernetesVersion string
+}
+
+// renderClusterTemplates returns the display name and the description of the Rancher cluster for the CAPI cluster.
+// The templates ConfigMap is read on each call, so changes are picked up without restarting the controller.
+func renderClusterTemplates(ctx context.Context, cl client.Client, templates ClusterTemplates,
+	capiCluster *clusterv1.Cluster,
+) (string, string, error) {
+	if templates.ConfigMap.Name != "" {
+		configMap := &corev1.ConfigMap{}
+		if err := cl.Get(ctx, templates.ConfigMap, configMap); err != nil {
+			return "", "", fmt.Errorf("error getting cluster templates config map %s: %w", templates.ConfigMap, err)
+		}
+
+		if displayName, ok := configMap.Data[displayNameTemplateKey]; ok {
+			templates.DisplayName = displayName
+		}
+
+		if description, ok := configMap.Data[descriptionTemplateKey]; ok {
+			templates.Description = description
+		}
+	}
+
+	data := clusterTemplateData{
+		Name:              capiCluster.Name,
+		Namespace:         capiCluster.Namespace,
+		Labels:            capiCluster.Labels,
+		ClusterClass:      capiCluster.Spec.Topology.ClassRef.Name,
+		KubernetesVersion: capiCluster.Spec.Topology.Version,
+	}
+
+	if data.KubernetesVersion == "" && (templates.DisplayName != "" || templates.Description != "") {
+		data.KubernetesVersion = controlPlaneVersion(ctx, cl, capiCluster)
+	}
+
+	displayName, err := renderClusterTemplate(displayNameTemplateKey, templates.DisplayName, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	description := capiCluster.Annotations[turtlesannotations.ClusterDescriptionAnnotation]
+	if description == "" {
+		description, err = renderClusterTemplate(descriptionTemplateKey, templates.Description, data)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if displayName == "" {
+		displayName = capiCluster.Name
+	}
+
+	if description == "" {
+		description = defaultClusterDescription
+	}
+
+	return displayName, description, nil
+}
+
+// controlPlaneVersion returns the Kubernetes version of the control plane referenced by a cluster not using
+// a ClusterClass. The desired version is preferred over the version reported in the control plane status.
+// The version is left empty while the control plane can't be read, so the cluster is imported regardless.
+func controlPlaneVersion(ctx context.Context, cl client.Client, capiCluster *clusterv1.Cluster) string {
+	if !capiCluster.Spec.ControlPlaneRef.IsDefined() {
+		return ""
+	}
+
+	controlPlane, err := external.GetObjectFromContractVersionedRef(ctx, cl, capiCluster.Spec.ControlPlaneRef, capiCluster.Namespace)
+	if err != nil {
+		log.FromContext(ctx).V(4).Info("Unable to get the control plane version", "error", err.Error())
+		return ""
+	}
+
+	if version, _, _ := unstructured.NestedString(controlPlane.Object, "spec", "version"); version != "" {
+		return version
+	}
+
+	version, _, _ := unstructured.NestedString(controlPlane.Object, "status", "version")
+
+	return version
+}
+
+func renderClusterTemplate(name, text string, data clusterTemplateData) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing %s template: %w", name, err)
+	}
+
+	out := &strings.Builder{}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", fmt.Errorf("executing %s template: %w", name, err)
+	}
+
+	return strings.TrimSpace(out.String()), nil
+}
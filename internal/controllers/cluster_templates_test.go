@@ -0,0 +1,170 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+func newClusterTemplatesClient(g Gomega, objects ...client.Object) client.Client {
+	templatesScheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(templatesScheme)).To(Succeed())
+	g.Expect(apiextensionsv1.AddToScheme(templatesScheme)).To(Succeed())
+
+	return fake.NewClientBuilder().WithScheme(templatesScheme).WithObjects(objects...).Build()
+}
+
+func newTemplatedCluster() *clusterv1.Cluster {
+	return &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster1",
+			Namespace: "team-a",
+			Labels:    map[string]string{"env": "prod"},
+		},
+		Spec: clusterv1.ClusterSpec{
+			Topology: clusterv1.Topology{
+				ClassRef: clusterv1.ClusterClassRef{Name: "docker-kubeadm"},
+				Version:  "v1.33.0",
+			},
+		},
+	}
+}
+
+func TestRenderClusterTemplates(t *testing.T) {
+	tests := []struct {
+		name            string
+		templates       ClusterTemplates
+		annotations     map[string]string
+		wantDisplayName string
+		wantDescription string
+	}{
+		{
+			name:            "should default to the cluster name and the default description",
+			wantDisplayName: "cluster1",
+			wantDescription: defaultClusterDescription,
+		},
+		{
+			name: "should render the templates with the cluster fields",
+			templates: ClusterTemplates{
+				DisplayName: "{{ .Namespace }}/{{ .Name }} ({{ .Labels.env }})",
+				Description: "{{ .ClusterClass }} {{ .KubernetesVersion }}",
+			},
+			wantDisplayName: "team-a/cluster1 (prod)",
+			wantDescription: "docker-kubeadm v1.33.0",
+		},
+		{
+			name:            "should prefer the description annotation",
+			templates:       ClusterTemplates{Description: "{{ .Name }}"},
+			annotations:     map[string]string{turtlesannotations.ClusterDescriptionAnnotation: "my cluster"},
+			wantDisplayName: "cluster1",
+			wantDescription: "my cluster",
+		},
+		{
+			name:            "should fall back to the defaults when a template renders empty",
+			templates:       ClusterTemplates{DisplayName: "{{ .Labels.missing }}"},
+			wantDisplayName: "cluster1",
+			wantDescription: defaultClusterDescription,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			capiCluster := newTemplatedCluster()
+			capiCluster.Annotations = tt.annotations
+
+			displayName, description, err := renderClusterTemplates(context.TODO(), newClusterTemplatesClient(g), tt.templates, capiCluster)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(displayName).To(Equal(tt.wantDisplayName))
+			g.Expect(description).To(Equal(tt.wantDescription))
+		})
+	}
+
+	t.Run("should override the templates with the config map", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cl := newClusterTemplatesClient(g, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-templates", Namespace: "default"},
+			Data:       map[string]string{displayNameTemplateKey: "{{ .Name }}-{{ .Labels.env }}"},
+		})
+
+		displayName, description, err := renderClusterTemplates(context.TODO(), cl, ClusterTemplates{
+			DisplayName: "{{ .Namespace }}",
+			Description: "{{ .Namespace }}",
+			ConfigMap:   client.ObjectKey{Namespace: "default", Name: "cluster-templates"},
+		}, newTemplatedCluster())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(displayName).To(Equal("cluster1-prod"))
+		g.Expect(description).To(Equal("team-a"))
+	})
+
+	t.Run("should use the control plane version for clusters without a ClusterClass", func(t *testing.T) {
+		g := NewWithT(t)
+
+		crd := &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{
+			Name:   "kubeadmcontrolplanes.controlplane.cluster.x-k8s.io",
+			Labels: map[string]string{clusterv1.GroupVersion.String(): "v1beta2"},
+		}}
+		controlPlane := &unstructured.Unstructured{}
+		controlPlane.SetAPIVersion("controlplane.cluster.x-k8s.io/v1beta2")
+		controlPlane.SetKind("KubeadmControlPlane")
+		controlPlane.SetName("cluster1-control-plane")
+		controlPlane.SetNamespace("team-a")
+		g.Expect(unstructured.SetNestedField(controlPlane.Object, "v1.32.4", "spec", "version")).To(Succeed())
+
+		capiCluster := newTemplatedCluster()
+		capiCluster.Spec.Topology = clusterv1.Topology{}
+		capiCluster.Spec.ControlPlaneRef = clusterv1.ContractVersionedObjectReference{
+			APIGroup: "controlplane.cluster.x-k8s.io",
+			Kind:     "KubeadmControlPlane",
+			Name:     "cluster1-control-plane",
+		}
+
+		templates := ClusterTemplates{Description: "{{ .ClusterClass }}{{ .KubernetesVersion }}"}
+
+		_, description, err := renderClusterTemplates(context.TODO(), newClusterTemplatesClient(g, crd, controlPlane), templates, capiCluster)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(description).To(Equal("v1.32.4"))
+
+		_, description, err = renderClusterTemplates(context.TODO(), newClusterTemplatesClient(g), templates, capiCluster)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(description).To(Equal(defaultClusterDescription))
+	})
+
+	t.Run("should fail on invalid templates", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, _, err := renderClusterTemplates(context.TODO(), newClusterTemplatesClient(g), ClusterTemplates{DisplayName: "{{ .Name"}, newTemplatedCluster())
+		g.Expect(err).To(HaveOccurred())
+	})
+}
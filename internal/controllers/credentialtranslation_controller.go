@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -32,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
 	turtlesannotations "github.com/rancher/turtles/util/annotations"
@@ -61,6 +63,12 @@ type CredentialTranslator interface {
 // Rancher Cloud Credentials when provisioning.
 type RancherCredentialReconciler struct {
 	Client client.Client
+	// RancherClient is the client for the Rancher Manager cluster, holding the cloud credentials.
+	// Defaults to Client, when Turtles runs in the Rancher Manager cluster.
+	RancherClient client.Client
+	// RancherCache is the cache for the Rancher Manager cluster, used to watch the cloud credentials.
+	// Defaults to the manager cache.
+	RancherCache cache.Cache
 	// Translators holds provider-specific logic referenced by driver name (e.g. "aws" -> AWSTranslator)
 	Translators map[string]CredentialTranslator
 }
@@ -71,7 +79,11 @@ func (r *RancherCredentialReconciler) SetupWithManager(ctx context.Context, mgr
 
 	log.Info("Watching Rancher Cloud Credential secrets")
 
-	secretPredicate := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+	if r.RancherCache == nil {
+		r.RancherCache = mgr.GetCache()
+	}
+
+	secretPredicate := predicate.NewTypedPredicateFuncs(func(obj *corev1.Secret) bool {
 		if obj.GetNamespace() != rancherCredentialsNamespace {
 			return false
 		}
@@ -114,7 +126,8 @@ func (r *RancherCredentialReconciler) SetupWithManager(ctx context.Context, mgr
 	if err := ctrl.NewControllerManagedBy(mgr).
 		Named("rancher-credential-translation").
 		WithOptions(options).
-		For(&corev1.Secret{}, builder.WithPredicates(secretPredicate)).
+		WatchesRawSource(source.Kind(r.RancherCache, &corev1.Secret{},
+			&handler.TypedEnqueueRequestForObject[*corev1.Secret]{}, secretPredicate)).
 		Watches(
 			&turtlesv1.CAPIProvider{},
 			handler.EnqueueRequestsFromMapFunc(r.enqueueAllMatchingSecrets),
@@ -135,7 +148,7 @@ func (r *RancherCredentialReconciler) Reconcile(ctx context.Context, req ctrl.Re
 	log := log.FromContext(ctx)
 
 	credential := &corev1.Secret{}
-	if err := r.Client.Get(ctx, req.NamespacedName, credential); err != nil {
+	if err := r.rancherClient().Get(ctx, req.NamespacedName, credential); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
@@ -186,7 +199,7 @@ func (r *RancherCredentialReconciler) reconcileNormal(ctx context.Context, crede
 		patch := client.MergeFrom(credential.DeepCopy())
 		controllerutil.AddFinalizer(credential, t.Finalizer())
 
-		if err := r.Client.Patch(ctx, credential, patch); err != nil {
+		if err := r.rancherClient().Patch(ctx, credential, patch); err != nil {
 			return ctrl.Result{}, fmt.Errorf("adding finalizer to credential %s: %w", client.ObjectKeyFromObject(credential), err)
 		}
 	}
@@ -212,7 +225,7 @@ func (r *RancherCredentialReconciler) reconcileNormal(ctx context.Context, crede
 		annotations[turtlesannotations.CAPIIdentityRefAnnotation] = identityName
 		credential.SetAnnotations(annotations)
 
-		if err := r.Client.Patch(ctx, credential, patch); err != nil {
+		if err := r.rancherClient().Patch(ctx, credential, patch); err != nil {
 			return ctrl.Result{}, fmt.Errorf("annotating credential %s with identity reference: %w", client.ObjectKeyFromObject(credential), err)
 		}
 	}
@@ -236,13 +249,22 @@ func (r *RancherCredentialReconciler) reconcileDelete(ctx context.Context, crede
 	patch := client.MergeFrom(credential.DeepCopy())
 	controllerutil.RemoveFinalizer(credential, t.Finalizer())
 
-	if err := r.Client.Patch(ctx, credential, patch); err != nil {
+	if err := r.rancherClient().Patch(ctx, credential, patch); err != nil {
 		return ctrl.Result{}, fmt.Errorf("removing finalizer from credential %s: %w", client.ObjectKeyFromObject(credential), err)
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// rancherClient returns the client for the Rancher Manager cluster.
+func (r *RancherCredentialReconciler) rancherClient() client.Client {
+	if r.RancherClient != nil {
+		return r.RancherClient
+	}
+
+	return r.Client
+}
+
 // enqueueAllMatchingSecrets runs when `CAPIProvider` is installed and ready.
 func (r *RancherCredentialReconciler) enqueueAllMatchingSecrets(ctx context.Context, obj client.Object) []ctrl.Request {
 	provider, ok := obj.(*turtlesv1.CAPIProvider)
@@ -266,7 +288,7 @@ func (r *RancherCredentialReconciler) enqueueAllMatchingSecrets(ctx context.Cont
 	// List and enqueue only secrets for this specific provider
 	var secretList corev1.SecretList
 
-	err := r.Client.List(ctx, &secretList, client.InNamespace(rancherCredentialsNamespace))
+	err := r.rancherClient().List(ctx, &secretList, client.InNamespace(rancherCredentialsNamespace))
 	if err != nil {
 		return nil
 	}
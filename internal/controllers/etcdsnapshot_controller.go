@@ -0,0 +1,393 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+
+	k3sv1 "github.com/rancher/turtles/api/rancher/k3s/v1"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+const (
+	etcdSnapshotJobNamespace = "kube-system"
+	etcdSnapshotJobPrefix    = "turtles-etcd-snapshot-"
+	etcdNodeRoleLabel        = "node-role.kubernetes.io/etcd"
+
+	// DefaultEtcdSnapshotJobImage is the default image of the Job taking the snapshot on the workload cluster.
+	// The image only needs chroot, as rke2 is run from the host.
+	DefaultEtcdSnapshotJobImage = "registry.suse.com/bci/bci-busybox:15.7"
+
+	etcdSnapshotRequeueDuration = 10 * time.Second
+
+	// DefaultEtcdSnapshotRemoteCleanupTimeout is the default time after which a deleted EtcdSnapshot is removed,
+	// when its snapshot files can't be deleted on the workload cluster.
+	DefaultEtcdSnapshotRemoteCleanupTimeout = 10 * time.Minute
+)
+
+// EtcdSnapshotReconciler takes the etcd snapshots requested with EtcdSnapshot objects on CAPI RKE2 workload clusters,
+// and tracks the resulting ETCDSnapshotFiles.
+type EtcdSnapshotReconciler struct {
+	Client client.Client
+	// JobImage is the image of the Job running `rke2 etcd-snapshot save` on an etcd node of the workload cluster.
+	JobImage string
+	// RemoteCleanupTimeout is the time after which a deleted EtcdSnapshot is removed, when its snapshot files
+	// can't be deleted on the workload cluster.
+	RemoteCleanupTimeout time.Duration
+
+	remoteClientGetter remote.ClusterClientGetter
+}
+
+// SetupWithManager sets up reconciler with manager.
+func (r *EtcdSnapshotReconciler) SetupWithManager(_ context.Context, mgr ctrl.Manager, options controller.Options) error {
+	if r.remoteClientGetter == nil {
+		r.remoteClientGetter = remote.NewClusterClient
+	}
+
+	if r.JobImage == "" {
+		r.JobImage = DefaultEtcdSnapshotJobImage
+	}
+
+	if r.RemoteCleanupTimeout == 0 {
+		r.RemoteCleanupTimeout = DefaultEtcdSnapshotRemoteCleanupTimeout
+	}
+
+	if err := ctrl.NewControllerManagedBy(mgr).
+		Named("etcdsnapshot").
+		For(&turtlesv1.EtcdSnapshot{}).
+		WithOptions(options).
+		Complete(r); err != nil {
+		return fmt.Errorf("creating etcd snapshot controller: %w", err)
+	}
+
+	return nil
+}
+
+// +kubebuilder:rbac:groups=turtles-capi.cattle.io,resources=etcdsnapshots;etcdsnapshots/status;etcdsnapshots/finalizers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
+//
+//nolint:lll
+
+// Reconcile takes the etcd snapshot on the workload cluster and updates its phase.
+func (r *EtcdSnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	snapshot := &turtlesv1.EtcdSnapshot{}
+	if err := r.Client.Get(ctx, req.NamespacedName, snapshot); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	patchHelper, err := patch.NewHelper(snapshot, r.Client)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to create patch helper: %w", err)
+	}
+
+	defer func() {
+		if err := patchHelper.Patch(ctx, snapshot); err != nil {
+			reterr = errors.Join(reterr, fmt.Errorf("failed to patch etcd snapshot: %w", err))
+		}
+	}()
+
+	if !snapshot.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.reconcileDelete(ctx, snapshot)
+	}
+
+	controllerutil.AddFinalizer(snapshot, turtlesv1.EtcdSnapshotFinalizer)
+
+	if snapshot.IsFinished() {
+		return ctrl.Result{}, nil
+	}
+
+	return r.reconcileNormal(ctx, snapshot)
+}
+
+func (r *EtcdSnapshotReconciler) reconcileNormal(ctx context.Context, snapshot *turtlesv1.EtcdSnapshot) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if snapshot.Status.Phase == "" {
+		snapshot.Status.Phase = turtlesv1.EtcdSnapshotPhasePending
+	}
+
+	if snapshot.Status.SnapshotName == "" {
+		snapshot.Status.SnapshotName = etcdSnapshotName(snapshot)
+	}
+
+	capiCluster := &clusterv1.Cluster{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: snapshot.Namespace, Name: snapshot.Spec.ClusterName}, capiCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			setEtcdSnapshotFailed(snapshot, fmt.Sprintf("cluster %s not found", snapshot.Spec.ClusterName))
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, fmt.Errorf("getting cluster %s: %w", snapshot.Spec.ClusterName, err)
+	}
+
+	if capiCluster.Spec.ControlPlaneRef.Kind != rke2ControlPlaneKind {
+		setEtcdSnapshotFailed(snapshot, fmt.Sprintf("cluster %s does not use an %s", capiCluster.Name, rke2ControlPlaneKind))
+		return ctrl.Result{}, nil
+	}
+
+	remoteClient, err := r.remoteClientGetter(ctx, capiCluster.Name, r.Client, client.ObjectKeyFromObject(capiCluster))
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("getting remote cluster client: %w", err)
+	}
+
+	files, err := listEtcdSnapshotFiles(ctx, remoteClient, snapshot.Status.SnapshotName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(files) > 0 {
+		updateEtcdSnapshotFiles(snapshot, files)
+		return ctrl.Result{RequeueAfter: etcdSnapshotRequeueDuration}, nil
+	}
+
+	job := &batchv1.Job{}
+
+	err = remoteClient.Get(ctx, client.ObjectKey{Namespace: etcdSnapshotJobNamespace, Name: etcdSnapshotJobName(snapshot)}, job)
+	switch {
+	case apierrors.IsNotFound(err):
+		log.Info("Requesting etcd snapshot on the workload cluster", "snapshot", snapshot.Status.SnapshotName)
+
+		if err := remoteClient.Create(ctx, r.etcdSnapshotJob(snapshot)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("creating etcd snapshot job: %w", err)
+		}
+	case err != nil:
+		return ctrl.Result{}, fmt.Errorf("getting etcd snapshot job: %w", err)
+	case jobFailed(job):
+		setEtcdSnapshotFailed(snapshot, fmt.Sprintf("etcd snapshot job %s/%s failed", job.Namespace, job.Name))
+		return ctrl.Result{}, nil
+	}
+
+	// The ETCDSnapshotFiles are created by RKE2 once the snapshot is saved.
+	snapshot.Status.Phase = turtlesv1.EtcdSnapshotPhaseRunning
+	conditions.Set(snapshot, metav1.Condition{
+		Type:    turtlesv1.EtcdSnapshotCompletedCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  turtlesv1.EtcdSnapshotInProgressReason,
+		Message: "Waiting for the snapshot to be saved on the workload cluster",
+	})
+
+	return ctrl.Result{RequeueAfter: etcdSnapshotRequeueDuration}, nil
+}
+
+// reconcileDelete deletes the ETCDSnapshotFiles of the snapshot, which makes RKE2 delete the snapshot files.
+// Nothing is deleted when the cluster no longer exists, or the snapshot has the skip-remote-cleanup annotation.
+// When the workload cluster can't be cleaned up, the snapshot is removed anyway after the remote cleanup timeout,
+// leaving its snapshot files on the workload cluster.
+func (r *EtcdSnapshotReconciler) reconcileDelete(ctx context.Context, snapshot *turtlesv1.EtcdSnapshot) error {
+	log := log.FromContext(ctx)
+
+	capiCluster := &clusterv1.Cluster{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: snapshot.Namespace, Name: snapshot.Spec.ClusterName}, capiCluster)
+
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return fmt.Errorf("getting cluster %s: %w", snapshot.Spec.ClusterName, err)
+	case turtlesannotations.IsSkipRemoteCleanup(snapshot):
+		log.Info("Skipping the deletion of the etcd snapshot files on the workload cluster")
+	case capiCluster.DeletionTimestamp.IsZero() && snapshot.Status.SnapshotName != "":
+		if err := r.deleteEtcdSnapshotFiles(ctx, capiCluster, snapshot); err != nil {
+			if time.Since(snapshot.DeletionTimestamp.Time) < r.RemoteCleanupTimeout {
+				return err
+			}
+
+			log.Error(err, "Unable to delete the etcd snapshot files on the workload cluster, removing the snapshot after timeout",
+				"timeout", r.RemoteCleanupTimeout)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(snapshot, turtlesv1.EtcdSnapshotFinalizer)
+
+	return nil
+}
+
+// deleteEtcdSnapshotFiles deletes the ETCDSnapshotFiles of the snapshot on the workload cluster.
+func (r *EtcdSnapshotReconciler) deleteEtcdSnapshotFiles(ctx context.Context, capiCluster *clusterv1.Cluster,
+	snapshot *turtlesv1.EtcdSnapshot,
+) error {
+	log := log.FromContext(ctx)
+
+	remoteClient, err := r.remoteClientGetter(ctx, capiCluster.Name, r.Client, client.ObjectKeyFromObject(capiCluster))
+	if err != nil {
+		return fmt.Errorf("getting remote cluster client: %w", err)
+	}
+
+	files, err := listEtcdSnapshotFiles(ctx, remoteClient, snapshot.Status.SnapshotName)
+	if err != nil {
+		return err
+	}
+
+	for i := range files {
+		log.Info("Deleting etcd snapshot file", "file", files[i].Name)
+
+		if err := remoteClient.Delete(ctx, &files[i]); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting etcd snapshot file %s: %w", files[i].Name, err)
+		}
+	}
+
+	return nil
+}
+
+// etcdSnapshotJob returns the Job saving the snapshot with rke2 on an etcd node. rke2 runs in the host root,
+// using the snapshot configuration of the node.
+func (r *EtcdSnapshotReconciler) etcdSnapshotJob(snapshot *turtlesv1.EtcdSnapshot) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      etcdSnapshotJobName(snapshot),
+			Namespace: etcdSnapshotJobNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "rancher-turtles",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To[int32](2),
+			TTLSecondsAfterFinished: ptr.To[int32](3600),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					HostNetwork:   true,
+					NodeSelector:  map[string]string{etcdNodeRoleLabel: "true"},
+					Tolerations:   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+					Containers: []corev1.Container{{
+						Name:    "etcd-snapshot",
+						Image:   r.JobImage,
+						Command: []string{"chroot", "/host", "rke2", "etcd-snapshot", "save", "--name", snapshot.Status.SnapshotName},
+						Env: []corev1.EnvVar{{
+							Name:  "PATH",
+							Value: "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin:/opt/rke2/bin",
+						}},
+						SecurityContext: &corev1.SecurityContext{Privileged: ptr.To(true)},
+						VolumeMounts:    []corev1.VolumeMount{{Name: "host", MountPath: "/host"}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name:         "host",
+						VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/"}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// listEtcdSnapshotFiles returns the ETCDSnapshotFiles of the snapshot. RKE2 names the snapshot files
+// <name>-<node>-<timestamp>.
+func listEtcdSnapshotFiles(ctx context.Context, remoteClient client.Client, snapshotName string) ([]k3sv1.ETCDSnapshotFile, error) {
+	files := &k3sv1.ETCDSnapshotFileList{}
+	if err := remoteClient.List(ctx, files); err != nil {
+		return nil, fmt.Errorf("listing etcd snapshot files: %w", err)
+	}
+
+	snapshotFiles := []k3sv1.ETCDSnapshotFile{}
+
+	for _, file := range files.Items {
+		if strings.HasPrefix(file.Spec.SnapshotName, snapshotName+"-") {
+			snapshotFiles = append(snapshotFiles, file)
+		}
+	}
+
+	return snapshotFiles, nil
+}
+
+// updateEtcdSnapshotFiles records the snapshot files, and completes the snapshot when all files are ready
+// or one of them failed.
+func updateEtcdSnapshotFiles(snapshot *turtlesv1.EtcdSnapshot, files []k3sv1.ETCDSnapshotFile) {
+	snapshot.Status.Files = make([]turtlesv1.EtcdSnapshotInfo, 0, len(files))
+	ready := true
+
+	for i := range files {
+		info := etcdSnapshotInfo(&files[i])
+		snapshot.Status.Files = append(snapshot.Status.Files, info)
+
+		if info.Error != "" {
+			setEtcdSnapshotFailed(snapshot, fmt.Sprintf("snapshot file %s failed: %s", info.Name, info.Error))
+			return
+		}
+
+		ready = ready && info.ReadyToUse
+	}
+
+	if !ready {
+		snapshot.Status.Phase = turtlesv1.EtcdSnapshotPhaseRunning
+		conditions.Set(snapshot, metav1.Condition{
+			Type:    turtlesv1.EtcdSnapshotCompletedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  turtlesv1.EtcdSnapshotInProgressReason,
+			Message: "Waiting for the snapshot files to be ready to use",
+		})
+
+		return
+	}
+
+	snapshot.Status.Phase = turtlesv1.EtcdSnapshotPhaseDone
+	conditions.Set(snapshot, metav1.Condition{
+		Type:   turtlesv1.EtcdSnapshotCompletedCondition,
+		Status: metav1.ConditionTrue,
+		Reason: turtlesv1.EtcdSnapshotCompletedReason,
+	})
+}
+
+func setEtcdSnapshotFailed(snapshot *turtlesv1.EtcdSnapshot, message string) {
+	snapshot.Status.Phase = turtlesv1.EtcdSnapshotPhaseFailed
+	conditions.Set(snapshot, metav1.Condition{
+		Type:    turtlesv1.EtcdSnapshotCompletedCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  turtlesv1.EtcdSnapshotFailedReason,
+		Message: message,
+	})
+}
+
+func jobFailed(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// etcdSnapshotName returns the name the snapshot is saved with, unique for the EtcdSnapshot object.
+func etcdSnapshotName(snapshot *turtlesv1.EtcdSnapshot) string {
+	return fmt.Sprintf("%s-%s", snapshot.Name, string(snapshot.UID)[:min(8, len(snapshot.UID))])
+}
+
+func etcdSnapshotJobName(snapshot *turtlesv1.EtcdSnapshot) string {
+	name := etcdSnapshotJobPrefix + snapshot.Status.SnapshotName
+
+	return strings.TrimSuffix(name[:min(len(name), 63)], "-")
+}
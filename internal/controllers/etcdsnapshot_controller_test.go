@@ -0,0 +1,272 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	k3sv1 "github.com/rancher/turtles/api/rancher/k3s/v1"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+var _ = Describe("EtcdSnapshotReconciler", func() {
+	var (
+		ctx            context.Context
+		snapshotScheme *runtime.Scheme
+		capiCluster    *clusterv1.Cluster
+		snapshot       *turtlesv1.EtcdSnapshot
+		remoteObjects  []client.Object
+		remoteClient   client.Client
+		remoteErr      error
+		r              *EtcdSnapshotReconciler
+	)
+
+	localSnapshotFile := func(name string, ready bool, errMessage string) *k3sv1.ETCDSnapshotFile {
+		file := &k3sv1.ETCDSnapshotFile{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: k3sv1.ETCDSnapshotSpec{
+				SnapshotName: name,
+				NodeName:     "cp-0",
+				Location:     "file:///var/lib/rancher/rke2/server/db/snapshots/" + name,
+			},
+			Status: k3sv1.ETCDSnapshotStatus{
+				CreationTime: &metav1.Time{Time: time.Now()},
+				ReadyToUse:   ptr.To(ready),
+			},
+		}
+
+		if errMessage != "" {
+			file.Status.Error = &k3sv1.ETCDSnapshotError{Message: ptr.To(errMessage)}
+		}
+
+		return file
+	}
+
+	// reconcile builds the management and workload cluster clients with the objects set in the spec,
+	// and reconciles the snapshot.
+	reconcile := func() (ctrl.Result, error) {
+		remoteClient = fake.NewClientBuilder().WithScheme(snapshotScheme).WithObjects(remoteObjects...).Build()
+
+		r = &EtcdSnapshotReconciler{
+			Client: fake.NewClientBuilder().WithScheme(snapshotScheme).
+				WithObjects(capiCluster, snapshot).
+				WithStatusSubresource(&turtlesv1.EtcdSnapshot{}).
+				Build(),
+			JobImage:             DefaultEtcdSnapshotJobImage,
+			RemoteCleanupTimeout: DefaultEtcdSnapshotRemoteCleanupTimeout,
+			remoteClientGetter: func(context.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+				return remoteClient, remoteErr
+			},
+		}
+
+		return r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(snapshot)})
+	}
+
+	getSnapshot := func() *turtlesv1.EtcdSnapshot {
+		current := &turtlesv1.EtcdSnapshot{}
+		Expect(r.Client.Get(ctx, client.ObjectKeyFromObject(snapshot), current)).To(Succeed())
+
+		return current
+	}
+
+	// deleteSnapshot marks the snapshot as deleted since the given time, after it was taken.
+	deleteSnapshot := func(since time.Time) {
+		snapshot.Finalizers = []string{turtlesv1.EtcdSnapshotFinalizer}
+		snapshot.DeletionTimestamp = &metav1.Time{Time: since}
+		snapshot.Status.SnapshotName = "backup-01234567"
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		snapshotScheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(snapshotScheme)).To(Succeed())
+		Expect(clusterv1.AddToScheme(snapshotScheme)).To(Succeed())
+		Expect(turtlesv1.AddToScheme(snapshotScheme)).To(Succeed())
+		Expect(k3sv1.AddToScheme(snapshotScheme)).To(Succeed())
+
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2", Namespace: "default", UID: "cluster-uid"},
+			Spec: clusterv1.ClusterSpec{
+				ControlPlaneRef: clusterv1.ContractVersionedObjectReference{
+					APIGroup: "controlplane.cluster.x-k8s.io",
+					Kind:     rke2ControlPlaneKind,
+					Name:     "rke2-control-plane",
+				},
+			},
+			Status: clusterv1.ClusterStatus{
+				Conditions: []metav1.Condition{{
+					Type:   clusterv1.ClusterControlPlaneAvailableCondition,
+					Status: metav1.ConditionTrue,
+					Reason: "Available",
+				}},
+			},
+		}
+		snapshot = &turtlesv1.EtcdSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default", UID: "0123456789abcdef"},
+			Spec:       turtlesv1.EtcdSnapshotSpec{ClusterName: "rke2"},
+		}
+		remoteObjects = nil
+		remoteErr = nil
+	})
+
+	It("should run the snapshot job on an etcd node of the workload cluster", func() {
+		res, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(etcdSnapshotRequeueDuration))
+
+		current := getSnapshot()
+		Expect(current.Finalizers).To(ContainElement(turtlesv1.EtcdSnapshotFinalizer))
+		Expect(current.Status.Phase).To(Equal(turtlesv1.EtcdSnapshotPhaseRunning))
+		Expect(current.Status.SnapshotName).To(Equal("backup-01234567"))
+		Expect(conditions.GetReason(current, turtlesv1.EtcdSnapshotCompletedCondition)).To(Equal(turtlesv1.EtcdSnapshotInProgressReason))
+
+		job := &batchv1.Job{}
+		Expect(remoteClient.Get(ctx,
+			client.ObjectKey{Namespace: "kube-system", Name: "turtles-etcd-snapshot-backup-01234567"}, job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue(etcdNodeRoleLabel, "true"))
+		Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal(DefaultEtcdSnapshotJobImage))
+		Expect(job.Spec.Template.Spec.Containers[0].Command).To(ContainElements("save", "--name", "backup-01234567"))
+	})
+
+	It("should complete the snapshot when the snapshot files are ready", func() {
+		remoteObjects = []client.Object{
+			localSnapshotFile("backup-01234567-cp-0-1700000000", true, ""),
+			localSnapshotFile("other-cp-0-1700000000", true, ""),
+		}
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		current := getSnapshot()
+		Expect(current.Status.Phase).To(Equal(turtlesv1.EtcdSnapshotPhaseDone))
+		Expect(current.Status.Files).To(HaveLen(1))
+		Expect(current.Status.Files[0].Name).To(Equal("backup-01234567-cp-0-1700000000"))
+		Expect(conditions.IsTrue(current, turtlesv1.EtcdSnapshotCompletedCondition)).To(BeTrue())
+	})
+
+	It("should fail the snapshot when a snapshot file failed", func() {
+		remoteObjects = []client.Object{localSnapshotFile("backup-01234567-cp-0-1700000000", false, "disk full")}
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		current := getSnapshot()
+		Expect(current.Status.Phase).To(Equal(turtlesv1.EtcdSnapshotPhaseFailed))
+		Expect(conditions.GetMessage(current, turtlesv1.EtcdSnapshotCompletedCondition)).To(ContainSubstring("disk full"))
+	})
+
+	It("should fail the snapshot when the snapshot job failed", func() {
+		remoteObjects = []client.Object{&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "turtles-etcd-snapshot-backup-01234567"},
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+			},
+		}}
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getSnapshot().Status.Phase).To(Equal(turtlesv1.EtcdSnapshotPhaseFailed))
+	})
+
+	It("should fail the snapshot when the cluster does not use an RKE2 control plane", func() {
+		capiCluster.Spec.ControlPlaneRef.Kind = "KubeadmControlPlane"
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		current := getSnapshot()
+		Expect(current.Status.Phase).To(Equal(turtlesv1.EtcdSnapshotPhaseFailed))
+		Expect(conditions.GetReason(current, turtlesv1.EtcdSnapshotCompletedCondition)).To(Equal(turtlesv1.EtcdSnapshotFailedReason))
+	})
+
+	It("should delete the snapshot files on the workload cluster when the snapshot is deleted", func() {
+		deleteSnapshot(time.Now())
+		remoteObjects = []client.Object{
+			localSnapshotFile("backup-01234567-cp-0-1700000000", true, ""),
+			localSnapshotFile("backup-01234567-s3-1700000000", true, ""),
+			localSnapshotFile("other-cp-0-1700000000", true, ""),
+		}
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		files := &k3sv1.ETCDSnapshotFileList{}
+		Expect(remoteClient.List(ctx, files)).To(Succeed())
+		Expect(files.Items).To(HaveLen(1))
+		Expect(files.Items[0].Name).To(Equal("other-cp-0-1700000000"))
+
+		err = r.Client.Get(ctx, client.ObjectKeyFromObject(snapshot), &turtlesv1.EtcdSnapshot{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should keep a deleted snapshot while the workload cluster can't be reached", func() {
+		deleteSnapshot(time.Now())
+		remoteErr = errors.New("cluster unreachable")
+
+		_, err := reconcile()
+		Expect(err).To(MatchError(ContainSubstring("cluster unreachable")))
+		Expect(getSnapshot().Finalizers).To(ContainElement(turtlesv1.EtcdSnapshotFinalizer))
+	})
+
+	It("should remove a deleted snapshot after the remote cleanup timeout", func() {
+		deleteSnapshot(time.Now().Add(-DefaultEtcdSnapshotRemoteCleanupTimeout))
+		remoteErr = errors.New("cluster unreachable")
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		err = r.Client.Get(ctx, client.ObjectKeyFromObject(snapshot), &turtlesv1.EtcdSnapshot{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should remove a deleted snapshot without cleaning up the workload cluster when requested", func() {
+		deleteSnapshot(time.Now())
+		snapshot.Annotations = map[string]string{turtlesannotations.SkipRemoteCleanupAnnotation: "true"}
+		remoteErr = errors.New("cluster unreachable")
+		remoteObjects = []client.Object{localSnapshotFile("backup-01234567-cp-0-1700000000", true, "")}
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		files := &k3sv1.ETCDSnapshotFileList{}
+		Expect(remoteClient.List(ctx, files)).To(Succeed())
+		Expect(files.Items).To(HaveLen(1))
+
+		err = r.Client.Get(ctx, client.ObjectKeyFromObject(snapshot), &turtlesv1.EtcdSnapshot{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,235 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	capiannotations "sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/predicates"
+
+	k3sv1 "github.com/rancher/turtles/api/rancher/k3s/v1"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlespredicates "github.com/rancher/turtles/util/predicates"
+)
+
+const (
+	rke2ControlPlaneKind = "RKE2ControlPlane"
+
+	defaultEtcdSnapshotSyncInterval = 5 * time.Minute
+)
+
+// EtcdSnapshotInventoryReconciler mirrors the ETCDSnapshotFiles of CAPI RKE2 workload clusters into
+// an EtcdSnapshotInventory next to the CAPI cluster, so snapshots can be listed on the management cluster.
+type EtcdSnapshotInventoryReconciler struct {
+	Client           client.Client
+	WatchFilterValue string
+	// SyncInterval is the interval at which the snapshots are read from the workload cluster.
+	SyncInterval time.Duration
+
+	remoteClientGetter remote.ClusterClientGetter
+}
+
+// SetupWithManager sets up reconciler with manager.
+func (r *EtcdSnapshotInventoryReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
+	log := log.FromContext(ctx)
+
+	if r.remoteClientGetter == nil {
+		r.remoteClientGetter = remote.NewClusterClient
+	}
+
+	if r.SyncInterval == 0 {
+		r.SyncInterval = defaultEtcdSnapshotSyncInterval
+	}
+
+	if err := ctrl.NewControllerManagedBy(mgr).
+		Named("etcdsnapshotinventory").
+		For(&clusterv1.Cluster{}, builder.WithPredicates(
+			predicates.ResourceHasFilterLabel(mgr.GetScheme(), log, r.WatchFilterValue),
+			turtlespredicates.ClusterWithReadyControlPlane(log),
+		)).
+		// Status updates of the inventory are ignored, as each sync records the sync time.
+		Owns(&turtlesv1.EtcdSnapshotInventory{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(options).
+		Complete(r); err != nil {
+		return fmt.Errorf("creating etcd snapshot inventory controller: %w", err)
+	}
+
+	return nil
+}
+
+// +kubebuilder:rbac:groups=turtles-capi.cattle.io,resources=etcdsnapshotinventories;etcdsnapshotinventories/status,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
+//
+//nolint:lll
+
+// Reconcile reads the etcd snapshots of a CAPI RKE2 cluster and records them in its EtcdSnapshotInventory.
+func (r *EtcdSnapshotInventoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	capiCluster := &clusterv1.Cluster{}
+	if err := r.Client.Get(ctx, req.NamespacedName, capiCluster); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if capiCluster.Spec.ControlPlaneRef.Kind != rke2ControlPlaneKind || !capiCluster.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if capiannotations.IsPaused(capiCluster, capiCluster) {
+		log.Info("Reconciliation is paused for this cluster")
+		return ctrl.Result{}, nil
+	}
+
+	if !conditions.IsTrue(capiCluster, clusterv1.ClusterControlPlaneAvailableCondition) {
+		log.V(4).Info("Control plane is not available, skipping etcd snapshot sync")
+		return ctrl.Result{}, nil
+	}
+
+	snapshots, syncErr := r.listSnapshots(ctx, capiCluster)
+
+	inventory := &turtlesv1.EtcdSnapshotInventory{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      capiCluster.Name,
+			Namespace: capiCluster.Namespace,
+		},
+	}
+
+	if _, err := controllerutil.CreateOrPatch(ctx, r.Client, inventory, func() error {
+		if inventory.Labels == nil {
+			inventory.Labels = map[string]string{}
+		}
+
+		inventory.Labels[clusterv1.ClusterNameLabel] = capiCluster.Name
+		inventory.Spec.ClusterName = capiCluster.Name
+
+		// The last known snapshots are kept when the workload cluster can't be reached.
+		if syncErr != nil {
+			conditions.Set(inventory, metav1.Condition{
+				Type:    turtlesv1.EtcdSnapshotsSyncedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  turtlesv1.EtcdSnapshotsSyncFailedReason,
+				Message: syncErr.Error(),
+			})
+		} else {
+			inventory.Status.Snapshots = snapshots
+			inventory.Status.SnapshotCount = int32(len(snapshots)) //nolint:gosec // snapshot count is small
+			inventory.Status.LastSyncTime = ptr.To(metav1.Now())
+
+			conditions.Set(inventory, metav1.Condition{
+				Type:   turtlesv1.EtcdSnapshotsSyncedCondition,
+				Status: metav1.ConditionTrue,
+				Reason: turtlesv1.EtcdSnapshotsSyncedReason,
+			})
+		}
+
+		return controllerutil.SetControllerReference(capiCluster, inventory, r.Client.Scheme())
+	}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("updating etcd snapshot inventory: %w", err)
+	}
+
+	if syncErr != nil {
+		return ctrl.Result{}, syncErr
+	}
+
+	return ctrl.Result{RequeueAfter: r.SyncInterval}, nil
+}
+
+// listSnapshots returns the etcd snapshots of the workload cluster, the newest first.
+func (r *EtcdSnapshotInventoryReconciler) listSnapshots(ctx context.Context, capiCluster *clusterv1.Cluster,
+) ([]turtlesv1.EtcdSnapshotInfo, error) {
+	remoteClient, err := r.remoteClientGetter(ctx, capiCluster.Name, r.Client, client.ObjectKeyFromObject(capiCluster))
+	if err != nil {
+		return nil, fmt.Errorf("getting remote cluster client: %w", err)
+	}
+
+	files := &k3sv1.ETCDSnapshotFileList{}
+	if err := remoteClient.List(ctx, files); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("ETCDSnapshotFile API is not available on the workload cluster: %w", err)
+		}
+
+		return nil, fmt.Errorf("listing etcd snapshot files: %w", err)
+	}
+
+	snapshots := make([]turtlesv1.EtcdSnapshotInfo, 0, len(files.Items))
+	for i := range files.Items {
+		snapshots = append(snapshots, etcdSnapshotInfo(&files.Items[i]))
+	}
+
+	slices.SortStableFunc(snapshots, func(a, b turtlesv1.EtcdSnapshotInfo) int {
+		if c := snapshotTime(b).Compare(snapshotTime(a)); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return snapshots, nil
+}
+
+// etcdSnapshotInfo converts the ETCDSnapshotFile into its inventory entry. S3 credentials and CA are not copied.
+func etcdSnapshotInfo(file *k3sv1.ETCDSnapshotFile) turtlesv1.EtcdSnapshotInfo {
+	info := turtlesv1.EtcdSnapshotInfo{
+		Name:         file.Name,
+		SnapshotName: file.Spec.SnapshotName,
+		NodeName:     file.Spec.NodeName,
+		Location:     file.Spec.Location,
+		CreationTime: file.Status.CreationTime,
+		ReadyToUse:   ptr.Deref(file.Status.ReadyToUse, false),
+	}
+
+	if s3 := file.Spec.S3; s3 != nil {
+		info.S3 = &turtlesv1.EtcdSnapshotS3Location{
+			Endpoint: s3.Endpoint,
+			Bucket:   s3.Bucket,
+			Region:   s3.Region,
+		}
+	}
+
+	if file.Status.Error != nil {
+		info.Error = ptr.Deref(file.Status.Error.Message, "")
+	}
+
+	return info
+}
+
+func snapshotTime(info turtlesv1.EtcdSnapshotInfo) time.Time {
+	if info.CreationTime == nil {
+		return time.Time{}
+	}
+
+	return info.CreationTime.Time
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	k3sv1 "github.com/rancher/turtles/api/rancher/k3s/v1"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+)
+
+var _ = Describe("EtcdSnapshotInventoryReconciler", func() {
+	var (
+		ctx            context.Context
+		snapshotScheme *runtime.Scheme
+		capiCluster    *clusterv1.Cluster
+		cl             client.Client
+		remoteClient   client.Client
+		remoteErr      error
+		r              *EtcdSnapshotInventoryReconciler
+	)
+
+	snapshotFile := func(name string, created time.Time, ready bool) *k3sv1.ETCDSnapshotFile {
+		return &k3sv1.ETCDSnapshotFile{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: k3sv1.ETCDSnapshotSpec{
+				SnapshotName: name,
+				NodeName:     "cp-0",
+				Location:     "s3://backups/" + name,
+				S3:           &k3sv1.ETCDSnapshotS3{Bucket: "backups", Region: "eu-west-1", EndpointCA: "secret-ca"},
+			},
+			Status: k3sv1.ETCDSnapshotStatus{
+				CreationTime: &metav1.Time{Time: created},
+				ReadyToUse:   ptr.To(ready),
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		snapshotScheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(snapshotScheme)).To(Succeed())
+		Expect(clusterv1.AddToScheme(snapshotScheme)).To(Succeed())
+		Expect(turtlesv1.AddToScheme(snapshotScheme)).To(Succeed())
+		Expect(k3sv1.AddToScheme(snapshotScheme)).To(Succeed())
+
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2", Namespace: "default", UID: "cluster-uid"},
+			Spec: clusterv1.ClusterSpec{
+				ControlPlaneRef: clusterv1.ContractVersionedObjectReference{
+					APIGroup: "controlplane.cluster.x-k8s.io",
+					Kind:     rke2ControlPlaneKind,
+					Name:     "rke2-control-plane",
+				},
+			},
+			Status: clusterv1.ClusterStatus{
+				Conditions: []metav1.Condition{{
+					Type:   clusterv1.ClusterControlPlaneAvailableCondition,
+					Status: metav1.ConditionTrue,
+					Reason: "Available",
+				}},
+			},
+		}
+
+		remoteErr = nil
+		r = &EtcdSnapshotInventoryReconciler{
+			SyncInterval: time.Minute,
+			remoteClientGetter: func(context.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+				return remoteClient, remoteErr
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		cl = fake.NewClientBuilder().WithScheme(snapshotScheme).
+			WithObjects(capiCluster).
+			WithStatusSubresource(&turtlesv1.EtcdSnapshotInventory{}).
+			Build()
+		r.Client = cl
+	})
+
+	It("should mirror the snapshots of the workload cluster, the newest first", func() {
+		now := time.Now().Truncate(time.Second)
+		remoteClient = fake.NewClientBuilder().WithScheme(snapshotScheme).WithObjects(
+			snapshotFile("etcd-snapshot-old", now.Add(-time.Hour), true),
+			snapshotFile("etcd-snapshot-new", now, false),
+		).Build()
+
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(capiCluster)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(time.Minute))
+
+		inventory := &turtlesv1.EtcdSnapshotInventory{}
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(capiCluster), inventory)).To(Succeed())
+		Expect(inventory.Spec.ClusterName).To(Equal("rke2"))
+		Expect(inventory.OwnerReferences).To(HaveLen(1))
+		Expect(inventory.Status.SnapshotCount).To(BeEquivalentTo(2))
+		Expect(inventory.Status.Snapshots[0].Name).To(Equal("etcd-snapshot-new"))
+		Expect(inventory.Status.Snapshots[0].ReadyToUse).To(BeFalse())
+		Expect(inventory.Status.Snapshots[1].ReadyToUse).To(BeTrue())
+		Expect(inventory.Status.Snapshots[1].S3).To(Equal(&turtlesv1.EtcdSnapshotS3Location{Bucket: "backups", Region: "eu-west-1"}))
+		Expect(conditions.IsTrue(inventory, turtlesv1.EtcdSnapshotsSyncedCondition)).To(BeTrue())
+	})
+
+	It("should keep the last known snapshots when the workload cluster is unreachable", func() {
+		remoteClient = fake.NewClientBuilder().WithScheme(snapshotScheme).WithObjects(
+			snapshotFile("etcd-snapshot-1", time.Now(), true),
+		).Build()
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(capiCluster)})
+		Expect(err).NotTo(HaveOccurred())
+
+		remoteErr = errors.New("connection refused")
+
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(capiCluster)})
+		Expect(err).To(HaveOccurred())
+
+		inventory := &turtlesv1.EtcdSnapshotInventory{}
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(capiCluster), inventory)).To(Succeed())
+		Expect(inventory.Status.Snapshots).To(HaveLen(1))
+		Expect(conditions.GetReason(inventory, turtlesv1.EtcdSnapshotsSyncedCondition)).To(Equal(turtlesv1.EtcdSnapshotsSyncFailedReason))
+	})
+
+	Context("when the cluster does not use an RKE2 control plane", func() {
+		BeforeEach(func() {
+			capiCluster.Spec.ControlPlaneRef.Kind = "KubeadmControlPlane"
+		})
+
+		It("should not create an inventory", func() {
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(capiCluster)})
+			Expect(err).NotTo(HaveOccurred())
+
+			inventories := &turtlesv1.EtcdSnapshotInventoryList{}
+			Expect(cl.List(ctx, inventories)).To(Succeed())
+			Expect(inventories.Items).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,206 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+// etcdSnapshotFailedRetention is the number of failed snapshots of a schedule, which are kept to report the failures.
+const etcdSnapshotFailedRetention = 3
+
+// EtcdSnapshotScheduleReconciler creates the periodic EtcdSnapshots of an EtcdSnapshotSchedule,
+// and prunes the snapshots exceeding its retention.
+type EtcdSnapshotScheduleReconciler struct {
+	Client client.Client
+
+	now func() time.Time
+}
+
+// SetupWithManager sets up reconciler with manager.
+func (r *EtcdSnapshotScheduleReconciler) SetupWithManager(_ context.Context, mgr ctrl.Manager, options controller.Options) error {
+	if r.now == nil {
+		r.now = time.Now
+	}
+
+	// Snapshots are not owned by the schedule, so they are kept when the schedule is deleted.
+	if err := ctrl.NewControllerManagedBy(mgr).
+		Named("etcdsnapshotschedule").
+		For(&turtlesv1.EtcdSnapshotSchedule{}).
+		Watches(&turtlesv1.EtcdSnapshot{}, handler.EnqueueRequestsFromMapFunc(etcdSnapshotToSchedule)).
+		WithOptions(options).
+		Complete(r); err != nil {
+		return fmt.Errorf("creating etcd snapshot schedule controller: %w", err)
+	}
+
+	return nil
+}
+
+// +kubebuilder:rbac:groups=turtles-capi.cattle.io,resources=etcdsnapshotschedules;etcdsnapshotschedules/status,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=turtles-capi.cattle.io,resources=etcdsnapshots,verbs=get;list;watch;create;delete
+//
+//nolint:lll
+
+// Reconcile creates an EtcdSnapshot when the next snapshot of the schedule is due, and prunes old snapshots.
+func (r *EtcdSnapshotScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	schedule := &turtlesv1.EtcdSnapshotSchedule{}
+	if err := r.Client.Get(ctx, req.NamespacedName, schedule); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !schedule.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.pruneSnapshots(ctx, schedule); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if schedule.Spec.Suspend || schedule.Spec.Interval.Duration <= 0 {
+		return ctrl.Result{}, nil
+	}
+
+	now := r.now()
+	interval := schedule.Spec.Interval.Duration
+
+	// Snapshots are scheduled at a fixed interval from the last scheduled time, so the schedule does not drift
+	// with the reconcile delay. Missed snapshots are not taken again, only the latest due one is.
+	scheduled := now
+
+	if last := schedule.Status.LastSnapshotTime; last != nil {
+		next := last.Add(interval)
+		if now.Before(next) {
+			return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+		}
+
+		scheduled = last.Add(now.Sub(last.Time) / interval * interval)
+	}
+
+	snapshot := &turtlesv1.EtcdSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", schedule.Name, now.Unix()),
+			Namespace: schedule.Namespace,
+			Labels: map[string]string{
+				turtlesv1.EtcdSnapshotScheduleLabel: schedule.Name,
+			},
+			Annotations: map[string]string{
+				turtlesannotations.EtcdAutomaticSnapshot: "true",
+			},
+		},
+		Spec: turtlesv1.EtcdSnapshotSpec{
+			ClusterName: schedule.Spec.ClusterName,
+		},
+	}
+
+	log.Info("Creating scheduled etcd snapshot", "snapshot", snapshot.Name)
+
+	if err := r.Client.Create(ctx, snapshot); client.IgnoreAlreadyExists(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("creating etcd snapshot %s: %w", snapshot.Name, err)
+	}
+
+	patchBase := client.MergeFrom(schedule.DeepCopy())
+	schedule.Status.LastSnapshotTime = ptr.To(metav1.NewTime(scheduled))
+	schedule.Status.LastSnapshotName = snapshot.Name
+
+	if err := r.Client.Status().Patch(ctx, schedule, patchBase); err != nil {
+		return ctrl.Result{}, fmt.Errorf("updating etcd snapshot schedule status: %w", err)
+	}
+
+	return ctrl.Result{RequeueAfter: scheduled.Add(interval).Sub(now)}, nil
+}
+
+// pruneSnapshots deletes the done snapshots of the schedule, which are older than the newest retention done snapshots,
+// and the failed snapshots older than the newest etcdSnapshotFailedRetention failed snapshots.
+// Failed snapshots do not count against the retention, and snapshots in progress are never deleted.
+func (r *EtcdSnapshotScheduleReconciler) pruneSnapshots(ctx context.Context, schedule *turtlesv1.EtcdSnapshotSchedule) error {
+	log := log.FromContext(ctx)
+
+	snapshots := &turtlesv1.EtcdSnapshotList{}
+	if err := r.Client.List(ctx, snapshots, client.InNamespace(schedule.Namespace),
+		client.MatchingLabels{turtlesv1.EtcdSnapshotScheduleLabel: schedule.Name}); err != nil {
+		return fmt.Errorf("listing etcd snapshots: %w", err)
+	}
+
+	slices.SortStableFunc(snapshots.Items, func(a, b turtlesv1.EtcdSnapshot) int {
+		if c := b.CreationTimestamp.Compare(a.CreationTimestamp.Time); c != 0 {
+			return c
+		}
+
+		return strings.Compare(b.Name, a.Name)
+	})
+
+	retention := max(int(schedule.Spec.Retention), 1)
+	done, failed := 0, 0
+
+	for i := range snapshots.Items {
+		snapshot := &snapshots.Items[i]
+
+		switch snapshot.Status.Phase {
+		case turtlesv1.EtcdSnapshotPhaseDone:
+			done++
+			if done <= retention {
+				continue
+			}
+		case turtlesv1.EtcdSnapshotPhaseFailed:
+			failed++
+			if failed <= etcdSnapshotFailedRetention {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if !snapshot.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		log.Info("Pruning etcd snapshot", "snapshot", snapshot.Name)
+
+		if err := r.Client.Delete(ctx, snapshot); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting etcd snapshot %s: %w", snapshot.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func etcdSnapshotToSchedule(_ context.Context, o client.Object) []reconcile.Request {
+	name, ok := o.GetLabels()[turtlesv1.EtcdSnapshotScheduleLabel]
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: o.GetNamespace(), Name: name}}}
+}
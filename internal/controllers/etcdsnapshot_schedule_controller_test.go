@@ -0,0 +1,199 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+var _ = Describe("EtcdSnapshotScheduleReconciler", func() {
+	var (
+		ctx       context.Context
+		now       time.Time
+		schedule  *turtlesv1.EtcdSnapshotSchedule
+		snapshots []client.Object
+		r         *EtcdSnapshotScheduleReconciler
+	)
+
+	hoursAgo := func(hours int) time.Time { return now.Add(-time.Duration(hours) * time.Hour) }
+
+	scheduledSnapshot := func(name string, created time.Time, phase turtlesv1.EtcdSnapshotPhase) *turtlesv1.EtcdSnapshot {
+		return &turtlesv1.EtcdSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(created),
+				Labels:            map[string]string{turtlesv1.EtcdSnapshotScheduleLabel: "hourly"},
+			},
+			Spec:   turtlesv1.EtcdSnapshotSpec{ClusterName: "rke2"},
+			Status: turtlesv1.EtcdSnapshotStatus{Phase: phase},
+		}
+	}
+
+	// reconcile builds the client with the schedule and snapshots set in the spec, and reconciles the schedule.
+	reconcile := func() ctrl.Result {
+		snapshotScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(snapshotScheme)).To(Succeed())
+		Expect(turtlesv1.AddToScheme(snapshotScheme)).To(Succeed())
+
+		r = &EtcdSnapshotScheduleReconciler{
+			Client: fake.NewClientBuilder().WithScheme(snapshotScheme).
+				WithObjects(append(snapshots, schedule)...).
+				WithStatusSubresource(&turtlesv1.EtcdSnapshotSchedule{}).
+				Build(),
+			now: func() time.Time { return now },
+		}
+
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(schedule)})
+		Expect(err).NotTo(HaveOccurred())
+
+		return res
+	}
+
+	listSnapshots := func() []turtlesv1.EtcdSnapshot {
+		list := &turtlesv1.EtcdSnapshotList{}
+		Expect(r.Client.List(ctx, list)).To(Succeed())
+
+		return list.Items
+	}
+
+	listSnapshotNames := func() []string {
+		names := []string{}
+		for _, snapshot := range listSnapshots() {
+			names = append(names, snapshot.Name)
+		}
+
+		return names
+	}
+
+	getSchedule := func() *turtlesv1.EtcdSnapshotSchedule {
+		current := &turtlesv1.EtcdSnapshotSchedule{}
+		Expect(r.Client.Get(ctx, client.ObjectKeyFromObject(schedule), current)).To(Succeed())
+
+		return current
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		now = time.Now().Truncate(time.Second)
+		snapshots = nil
+
+		schedule = &turtlesv1.EtcdSnapshotSchedule{
+			ObjectMeta: metav1.ObjectMeta{Name: "hourly", Namespace: "default"},
+			Spec: turtlesv1.EtcdSnapshotScheduleSpec{
+				ClusterName: "rke2",
+				Interval:    metav1.Duration{Duration: time.Hour},
+				Retention:   2,
+			},
+		}
+	})
+
+	It("should create an automatic snapshot when the schedule has no snapshot yet", func() {
+		res := reconcile()
+		Expect(res.RequeueAfter).To(Equal(time.Hour))
+
+		items := listSnapshots()
+		Expect(items).To(HaveLen(1))
+		Expect(items[0].Spec.ClusterName).To(Equal("rke2"))
+		Expect(items[0].Labels).To(HaveKeyWithValue(turtlesv1.EtcdSnapshotScheduleLabel, "hourly"))
+		Expect(items[0].Annotations).To(HaveKeyWithValue(turtlesannotations.EtcdAutomaticSnapshot, "true"))
+
+		current := getSchedule()
+		Expect(current.Status.LastSnapshotName).To(Equal(items[0].Name))
+		Expect(current.Status.LastSnapshotTime.Time).To(Equal(now))
+	})
+
+	It("should requeue until the next snapshot is due", func() {
+		schedule.Status.LastSnapshotTime = &metav1.Time{Time: now.Add(-20 * time.Minute)}
+
+		res := reconcile()
+		Expect(res.RequeueAfter).To(Equal(40 * time.Minute))
+		Expect(listSnapshotNames()).To(BeEmpty())
+	})
+
+	It("should schedule the next snapshot one interval after the last scheduled time", func() {
+		schedule.Status.LastSnapshotTime = &metav1.Time{Time: now.Add(-65 * time.Minute)}
+
+		res := reconcile()
+		Expect(res.RequeueAfter).To(Equal(55 * time.Minute))
+		Expect(listSnapshotNames()).To(HaveLen(1))
+		Expect(getSchedule().Status.LastSnapshotTime.Time).To(Equal(now.Add(-5 * time.Minute)))
+	})
+
+	It("should only take the latest missed snapshot", func() {
+		schedule.Status.LastSnapshotTime = &metav1.Time{Time: now.Add(-190 * time.Minute)}
+
+		res := reconcile()
+		Expect(res.RequeueAfter).To(Equal(50 * time.Minute))
+		Expect(listSnapshotNames()).To(HaveLen(1))
+		Expect(getSchedule().Status.LastSnapshotTime.Time).To(Equal(now.Add(-10 * time.Minute)))
+	})
+
+	It("should not create a snapshot when the schedule is suspended", func() {
+		schedule.Spec.Suspend = true
+
+		reconcile()
+		Expect(listSnapshotNames()).To(BeEmpty())
+	})
+
+	It("should prune the oldest done snapshots of the schedule beyond the retention", func() {
+		manual := scheduledSnapshot("manual", hoursAgo(10), turtlesv1.EtcdSnapshotPhaseDone)
+		delete(manual.Labels, turtlesv1.EtcdSnapshotScheduleLabel)
+
+		schedule.Spec.Suspend = true
+		snapshots = []client.Object{
+			scheduledSnapshot("hourly-0", hoursAgo(5), turtlesv1.EtcdSnapshotPhaseDone),
+			scheduledSnapshot("hourly-1", hoursAgo(4), turtlesv1.EtcdSnapshotPhaseDone),
+			scheduledSnapshot("hourly-2", hoursAgo(3), turtlesv1.EtcdSnapshotPhaseRunning),
+			scheduledSnapshot("hourly-3", hoursAgo(2), turtlesv1.EtcdSnapshotPhaseFailed),
+			scheduledSnapshot("hourly-4", hoursAgo(1), turtlesv1.EtcdSnapshotPhaseDone),
+			manual,
+		}
+
+		reconcile()
+		Expect(listSnapshotNames()).To(ConsistOf("hourly-1", "hourly-2", "hourly-3", "hourly-4", "manual"))
+	})
+
+	It("should keep the retention of done snapshots when the newest snapshots failed", func() {
+		schedule.Spec.Suspend = true
+		snapshots = []client.Object{
+			scheduledSnapshot("hourly-0", hoursAgo(7), turtlesv1.EtcdSnapshotPhaseDone),
+			scheduledSnapshot("hourly-1", hoursAgo(6), turtlesv1.EtcdSnapshotPhaseDone),
+			scheduledSnapshot("hourly-2", hoursAgo(5), turtlesv1.EtcdSnapshotPhaseDone),
+			scheduledSnapshot("hourly-3", hoursAgo(4), turtlesv1.EtcdSnapshotPhaseFailed),
+			scheduledSnapshot("hourly-4", hoursAgo(3), turtlesv1.EtcdSnapshotPhaseFailed),
+			scheduledSnapshot("hourly-5", hoursAgo(2), turtlesv1.EtcdSnapshotPhaseFailed),
+			scheduledSnapshot("hourly-6", hoursAgo(1), turtlesv1.EtcdSnapshotPhaseFailed),
+		}
+
+		reconcile()
+		Expect(listSnapshotNames()).To(ConsistOf("hourly-1", "hourly-2", "hourly-4", "hourly-5", "hourly-6"))
+	})
+})
@@ -0,0 +1,245 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/component-base/featuregate"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/rancher/turtles/feature"
+)
+
+// GatedController is a controller depending on a feature gate, which is started or stopped at runtime
+// when the feature gate changes.
+type GatedController struct {
+	// Name of the controller, used for logging.
+	Name string
+	// Gate is the feature gate enabling the controller.
+	Gate featuregate.Feature
+	// Setup creates the controller with the manager. Controllers and runnables added to the manager are
+	// not managed by the main manager, they are started when the gate is enabled and stopped when it is disabled.
+	Setup func(ctx context.Context, mgr ctrl.Manager) error
+	// Cleanup is called when the feature gate changes from enabled to disabled, to remove resources
+	// managed by the controller. It is not retried on failure.
+	// +optional
+	Cleanup func(ctx context.Context) error
+}
+
+// FeatureGatesRunner reloads Turtles feature gates from a ConfigMap and runs the gated controllers with the
+// cache and clients of the main manager. Each gated controller is unmanaged and started with its own context,
+// so it can be stopped when its feature gate is disabled.
+// Values in the ConfigMap override the `--feature-gates` flag. Without a ConfigMap, the gated controllers
+// are started according to the command line feature gates only.
+type FeatureGatesRunner struct {
+	// Client is used to read the ConfigMap. An uncached reader is expected.
+	Client client.Reader
+	// ConfigMap references the ConfigMap holding the feature gate overrides.
+	// +optional
+	ConfigMap client.ObjectKey
+	// Interval is the interval at which the ConfigMap is read, and stopped controllers are restarted.
+	Interval time.Duration
+	// Manager is the main manager, whose cache and clients are used by the gated controllers.
+	Manager ctrl.Manager
+	// Controllers are the gated controllers.
+	Controllers []GatedController
+
+	mu      sync.Mutex
+	running map[string]*runningController
+	enabled map[string]bool
+}
+
+// runningController holds a started gated controller, identifying it when its runnables stop.
+type runningController struct {
+	cancel context.CancelFunc
+}
+
+// gatedManager is the manager passed to the Setup of a gated controller. It shares the main manager,
+// but keeps the added controllers and runnables unmanaged, so the runner can start and stop them.
+// Gated controllers are created again each time their feature gate is enabled, so their name is not validated.
+type gatedManager struct {
+	ctrl.Manager
+
+	runnables []manager.Runnable
+}
+
+// Add records the runnable, instead of adding it to the main manager.
+func (m *gatedManager) Add(runnable manager.Runnable) error {
+	m.runnables = append(m.runnables, runnable)
+
+	return nil
+}
+
+// GetControllerOptions returns the controller options of the main manager, without name validation.
+func (m *gatedManager) GetControllerOptions() config.Controller {
+	options := m.Manager.GetControllerOptions()
+	options.SkipNameValidation = ptr.To(true)
+
+	return options
+}
+
+// Start runs the gated controllers until the context is done.
+func (r *FeatureGatesRunner) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("feature-gates")
+	ctx = ctrl.LoggerInto(ctx, log)
+
+	// Controllers are reconciled on every tick, even without a ConfigMap, to restart the stopped ones.
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if r.ConfigMap.Name != "" {
+			if err := r.reloadGates(ctx); err != nil {
+				log.Error(err, "Unable to reload feature gates, keeping the current values", "configMap", r.ConfigMap)
+			}
+		}
+
+		r.reconcileControllers(ctx)
+	}, r.Interval)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, running := range r.running {
+		running.cancel()
+		delete(r.running, name)
+	}
+
+	return nil
+}
+
+// reloadGates applies the feature gate overrides from the ConfigMap. A missing ConfigMap removes all overrides.
+func (r *FeatureGatesRunner) reloadGates(ctx context.Context) error {
+	configMap := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, r.ConfigMap, configMap); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("getting feature gates config map: %w", err)
+	}
+
+	overrides, err := feature.ParseOverrides(configMap.Data)
+	if err != nil {
+		return err
+	}
+
+	return feature.SetOverrides(overrides)
+}
+
+// reconcileControllers starts the controllers with an enabled feature gate, and stops the others.
+// Resources of a controller are only cleaned up when its feature gate changes from enabled to disabled.
+func (r *FeatureGatesRunner) reconcileControllers(ctx context.Context) {
+	log := log.FromContext(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running == nil {
+		r.running = map[string]*runningController{}
+		r.enabled = map[string]bool{}
+	}
+
+	for _, c := range r.Controllers {
+		enabled := feature.Gates.Enabled(c.Gate)
+		previous, known := r.enabled[c.Name]
+
+		if known && previous == enabled {
+			continue
+		}
+
+		if enabled {
+			if err := r.startController(ctx, c); err != nil {
+				log.Error(err, "Unable to start gated controller", "controller", c.Name, "gate", c.Gate)
+				continue
+			}
+
+			log.Info("Started gated controller", "controller", c.Name, "gate", c.Gate)
+		} else {
+			if running, ok := r.running[c.Name]; ok {
+				running.cancel()
+				delete(r.running, c.Name)
+				log.Info("Stopped gated controller", "controller", c.Name, "gate", c.Gate)
+			}
+
+			// The CRDs of the cleaned up resources may not be installed, there is nothing to remove then.
+			if known && previous && c.Cleanup != nil {
+				if err := c.Cleanup(ctx); err != nil && !meta.IsNoMatchError(err) {
+					log.Error(err, "Unable to clean up gated controller", "controller", c.Name, "gate", c.Gate)
+				}
+			}
+		}
+
+		r.enabled[c.Name] = enabled
+	}
+}
+
+// startController creates the gated controller and starts its runnables with a dedicated context.
+// When a runnable stops on its own, the controller is stopped and forgotten, so the next reconciliation
+// starts it again.
+func (r *FeatureGatesRunner) startController(ctx context.Context, c GatedController) error {
+	controllerCtx, cancel := context.WithCancel(ctx)
+
+	mgr := &gatedManager{Manager: r.Manager}
+	if err := c.Setup(controllerCtx, mgr); err != nil {
+		cancel()
+
+		return fmt.Errorf("setting up controller: %w", err)
+	}
+
+	running := &runningController{cancel: cancel}
+
+	go func() {
+		errs := make(chan error, len(mgr.runnables))
+		for _, runnable := range mgr.runnables {
+			go func() {
+				errs <- runnable.Start(controllerCtx)
+			}()
+		}
+
+		select {
+		case err := <-errs:
+			if err != nil {
+				log.FromContext(ctx).Error(err, "Gated controller stopped with error", "controller", c.Name)
+			}
+
+			cancel()
+		case <-controllerCtx.Done():
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		// The controller was stopped or restarted by a feature gate change in the meantime.
+		if r.running[c.Name] != running {
+			return
+		}
+
+		delete(r.running, c.Name)
+		delete(r.enabled, c.Name)
+	}()
+
+	r.running[c.Name] = running
+
+	return nil
+}
@@ -0,0 +1,207 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/rancher/turtles/feature"
+)
+
+var _ = Describe("FeatureGatesRunner", func() {
+	var (
+		ctx        context.Context
+		runner     *FeatureGatesRunner
+		configMap  *corev1.ConfigMap
+		fakeClient client.Client
+		setup      func(ctrl.Manager) error
+		cleanupErr error
+		started    atomic.Int32
+		cleanedUp  atomic.Int32
+	)
+
+	isRunning := func() bool {
+		runner.mu.Lock()
+		defer runner.mu.Unlock()
+
+		_, ok := runner.running["ui-plugin"]
+
+		return ok
+	}
+
+	setGate := func(value string) {
+		configMap.Data[string(feature.UIPlugin)] = value
+		Expect(fakeClient.Update(ctx, configMap)).To(Succeed())
+
+		Expect(runner.reloadGates(ctx)).To(Succeed())
+		runner.reconcileControllers(ctx)
+	}
+
+	BeforeEach(func() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.TODO())
+		DeferCleanup(cancel)
+
+		feature.SnapshotCommandLine()
+		DeferCleanup(func() { _ = feature.SetOverrides(nil) })
+
+		setup = nil
+		cleanupErr = nil
+		started.Store(0)
+		cleanedUp.Store(0)
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "feature-gates", Namespace: "default"},
+			Data:       map[string]string{string(feature.UIPlugin): "true"},
+		}
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(configMap).Build()
+
+		mgr, err := ctrl.NewManager(&rest.Config{Host: "https://127.0.0.1:0"}, ctrl.Options{
+			Metrics:                server.Options{BindAddress: "0"},
+			HealthProbeBindAddress: "0",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		runner = &FeatureGatesRunner{
+			Client:    fakeClient,
+			ConfigMap: client.ObjectKeyFromObject(configMap),
+			Manager:   mgr,
+			Controllers: []GatedController{{
+				Name: "ui-plugin",
+				Gate: feature.UIPlugin,
+				Setup: func(_ context.Context, mgr ctrl.Manager) error {
+					started.Add(1)
+
+					if setup != nil {
+						return setup(mgr)
+					}
+
+					return nil
+				},
+				Cleanup: func(_ context.Context) error {
+					cleanedUp.Add(1)
+					return cleanupErr
+				},
+			}},
+		}
+	})
+
+	It("should start and stop gated controllers when the ConfigMap changes", func() {
+		setGate("true")
+		Expect(feature.Gates.Enabled(feature.UIPlugin)).To(BeTrue())
+		Expect(started.Load()).To(BeEquivalentTo(1))
+		Expect(isRunning()).To(BeTrue())
+
+		// Unchanged gates do not restart the controller.
+		runner.reconcileControllers(ctx)
+		Expect(started.Load()).To(BeEquivalentTo(1))
+
+		setGate("false")
+		Expect(feature.Gates.Enabled(feature.UIPlugin)).To(BeFalse())
+		Expect(isRunning()).To(BeFalse())
+		Expect(cleanedUp.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should keep the controllers added by the gated controller out of the main manager", func() {
+		var runnableStarted atomic.Bool
+		setup = func(mgr ctrl.Manager) error {
+			Expect(mgr.GetControllerOptions().SkipNameValidation).To(HaveValue(BeTrue()))
+
+			return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+				runnableStarted.Store(true)
+				<-ctx.Done()
+
+				return nil
+			}))
+		}
+
+		setGate("true")
+		Eventually(runnableStarted.Load, 5*time.Second).Should(BeTrue())
+		Expect(runner.Manager.GetControllerOptions().SkipNameValidation).To(BeNil())
+
+		setGate("false")
+		Expect(isRunning()).To(BeFalse())
+	})
+
+	It("should not clean up a gated controller disabled from the start", func() {
+		setGate("false")
+		Expect(started.Load()).To(BeZero())
+		Expect(cleanedUp.Load()).To(BeZero())
+	})
+
+	It("should not retry a failed clean up on every reconciliation", func() {
+		cleanupErr = errors.New("cleanup failed")
+
+		setGate("true")
+		setGate("false")
+		Expect(cleanedUp.Load()).To(BeEquivalentTo(1))
+
+		runner.reconcileControllers(ctx)
+		Expect(cleanedUp.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should consider the clean up of resources without an installed CRD as done", func() {
+		cleanupErr = &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "catalog.cattle.io", Kind: "UIPlugin"}}
+
+		setGate("true")
+		setGate("false")
+		Expect(cleanedUp.Load()).To(BeEquivalentTo(1))
+		Expect(isRunning()).To(BeFalse())
+	})
+
+	It("should restart a gated controller after it stopped", func() {
+		setup = func(mgr ctrl.Manager) error {
+			return mgr.Add(manager.RunnableFunc(func(context.Context) error {
+				return errors.New("controller failed")
+			}))
+		}
+
+		setGate("true")
+		Expect(started.Load()).To(BeEquivalentTo(1))
+
+		Eventually(isRunning, 5*time.Second).Should(BeFalse())
+
+		runner.reconcileControllers(ctx)
+		Expect(started.Load()).To(BeEquivalentTo(2))
+		Expect(cleanedUp.Load()).To(BeZero())
+	})
+
+	It("should keep the current feature gates when the ConfigMap is invalid", func() {
+		configMap.Data = map[string]string{"unknown": "true"}
+		Expect(fakeClient.Update(ctx, configMap)).To(Succeed())
+
+		Expect(runner.reloadGates(ctx)).NotTo(Succeed())
+		Expect(feature.CurrentStatus().Overrides).To(BeEmpty())
+	})
+})
@@ -0,0 +1,183 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+)
+
+const (
+	legacyFleetAgentNamespace = "fleet-addon-agent"
+	fleetAgentNamespace       = "cattle-fleet-system"
+)
+
+// fleetMigrationState is a state of the fleet agent namespace migration on the workload cluster.
+type fleetMigrationState string
+
+const (
+	// fleetMigrationDeletingLegacyNamespace waits for the legacy fleet agent namespace to be deleted.
+	fleetMigrationDeletingLegacyNamespace fleetMigrationState = "DeletingLegacyNamespace"
+	// fleetMigrationWaitingForAgent waits for Rancher to install the fleet agent in the new namespace.
+	fleetMigrationWaitingForAgent fleetMigrationState = "WaitingForFleetAgent"
+	// fleetMigrationCompleted means the fleet agent runs in the new namespace.
+	fleetMigrationCompleted fleetMigrationState = "Completed"
+)
+
+var fleetMigrationStuck = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "turtles_fleet_namespace_migration_stuck",
+	Help: "Whether the fleet agent namespace migration of a CAPI cluster did not complete within the migration timeout.",
+}, []string{"namespace", "cluster"})
+
+func init() {
+	metrics.Registry.MustRegister(fleetMigrationStuck)
+}
+
+// migrateFleetNamespace performs the next step of the fleet agent namespace migration on the workload cluster,
+// and returns the resulting state. The state only depends on the namespaces present on the workload cluster,
+// so an interrupted migration is resumed from where it stopped.
+func migrateFleetNamespace(ctx context.Context, remoteClient client.Client) (fleetMigrationState, error) {
+	legacy := &corev1.Namespace{}
+
+	err := remoteClient.Get(ctx, client.ObjectKey{Name: legacyFleetAgentNamespace}, legacy)
+	if client.IgnoreNotFound(err) != nil {
+		return "", fmt.Errorf("unable to check fleet agent namespace on downstream cluster: %w", err)
+	}
+
+	if err == nil {
+		if legacy.DeletionTimestamp.IsZero() {
+			if err := remoteClient.Delete(ctx, legacy); client.IgnoreNotFound(err) != nil {
+				return "", fmt.Errorf("unable to remove old fleet agent namespace on downstream cluster: %w", err)
+			}
+		}
+
+		return fleetMigrationDeletingLegacyNamespace, nil
+	}
+
+	if err := remoteClient.Get(ctx, client.ObjectKey{Name: fleetAgentNamespace}, &corev1.Namespace{}); apierrors.IsNotFound(err) {
+		return fleetMigrationWaitingForAgent, nil
+	} else if err != nil {
+		return "", fmt.Errorf("unable to check %s namespace on downstream cluster: %w", fleetAgentNamespace, err)
+	}
+
+	return fleetMigrationCompleted, nil
+}
+
+// reconcileFleetMigration migrates the fleet agent namespace on the workload cluster, and tracks the progress with
+// the FleetNamespaceMigrated condition on the CAPI cluster. A migration not completed within the migration timeout
+// is reported as stuck, but still retried. Completion is recorded on the Rancher cluster with the
+// fleet-namespace-migrated annotation, so the workload cluster is not checked again.
+func (r *CAPIImportReconciler) reconcileFleetMigration(ctx context.Context, capiCluster *clusterv1.Cluster,
+	rancherCluster *managementv3.Cluster,
+) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	remoteClient, err := r.remoteClientGetter(ctx, capiCluster.Name, r.Client, client.ObjectKeyFromObject(capiCluster))
+	if err != nil {
+		err = fmt.Errorf("getting remote cluster client: %w", err)
+	}
+
+	var state fleetMigrationState
+	if err == nil {
+		state, err = migrateFleetNamespace(ctx, remoteClient)
+	}
+
+	if err != nil {
+		conditions.Set(capiCluster, metav1.Condition{
+			Type:    turtlesv1.FleetNamespaceMigratedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  turtlesv1.FleetMigrationErrorReason,
+			Message: err.Error(),
+		})
+
+		return ctrl.Result{}, fmt.Errorf("cleaning up fleet namespace: %w", err)
+	}
+
+	if state == fleetMigrationCompleted {
+		log.Info("fleet agent namespace is migrated")
+
+		annotations := rancherCluster.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		annotations[fleetNamespaceMigrated] = fleetAgentNamespace
+		rancherCluster.SetAnnotations(annotations)
+
+		conditions.Set(capiCluster, metav1.Condition{
+			Type:    turtlesv1.FleetNamespaceMigratedCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  turtlesv1.FleetMigrationCompletedReason,
+			Message: fmt.Sprintf("Fleet agent runs in the %s namespace", fleetAgentNamespace),
+		})
+		fleetMigrationStuck.DeleteLabelValues(capiCluster.Namespace, capiCluster.Name)
+
+		return ctrl.Result{}, nil
+	}
+
+	reason, message := turtlesv1.FleetMigrationWaitingForAgentReason,
+		fmt.Sprintf("Waiting for the fleet agent in the %s namespace", fleetAgentNamespace)
+	if state == fleetMigrationDeletingLegacyNamespace {
+		reason, message = turtlesv1.FleetMigrationDeletingLegacyNamespaceReason,
+			fmt.Sprintf("Deleting the legacy %s namespace", legacyFleetAgentNamespace)
+	}
+
+	// The condition keeps its transition time while it stays False, so it marks the start of the migration.
+	started := time.Now()
+	if condition := conditions.Get(capiCluster, turtlesv1.FleetNamespaceMigratedCondition); condition != nil &&
+		condition.Status == metav1.ConditionFalse {
+		started = condition.LastTransitionTime.Time
+	}
+
+	stuck := 0.0
+	if r.FleetMigrationTimeout > 0 && time.Since(started) > r.FleetMigrationTimeout {
+		log.Info("fleet agent namespace migration is stuck", "state", state, "since", started)
+
+		stuck = 1
+		reason, message = turtlesv1.FleetMigrationStuckReason,
+			fmt.Sprintf("Migration did not complete within %s: %s", r.FleetMigrationTimeout, message)
+	} else {
+		log.Info("fleet agent namespace is not migrated yet", "state", state)
+	}
+
+	fleetMigrationStuck.WithLabelValues(capiCluster.Namespace, capiCluster.Name).Set(stuck)
+
+	conditions.Set(capiCluster, metav1.Condition{
+		Type:    turtlesv1.FleetNamespaceMigratedCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+
+	return ctrl.Result{RequeueAfter: defaultRequeueDuration}, nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+)
+
+var _ = Describe("Fleet agent namespace migration", func() {
+	var (
+		ctx            context.Context
+		remoteClient   client.Client
+		capiCluster    *clusterv1.Cluster
+		rancherCluster *managementv3.Cluster
+		r              *CAPIImportReconciler
+	)
+
+	legacyNamespace := func() *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: legacyFleetAgentNamespace}}
+	}
+
+	agentNamespace := func() *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fleetAgentNamespace}}
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		capiCluster = &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"}}
+		rancherCluster = &managementv3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-cluster", Namespace: "default"}}
+
+		r = &CAPIImportReconciler{
+			FleetMigrationTimeout: time.Minute,
+			remoteClientGetter: func(context.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+				return remoteClient, nil
+			},
+		}
+	})
+
+	It("should delete the legacy namespace and resume until the agent namespace exists", func() {
+		remoteClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(legacyNamespace()).Build()
+
+		res, err := r.reconcileFleetMigration(ctx, capiCluster, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(defaultRequeueDuration))
+		Expect(conditions.GetReason(capiCluster, turtlesv1.FleetNamespaceMigratedCondition)).
+			To(Equal(turtlesv1.FleetMigrationDeletingLegacyNamespaceReason))
+
+		res, err = r.reconcileFleetMigration(ctx, capiCluster, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(defaultRequeueDuration))
+		Expect(conditions.GetReason(capiCluster, turtlesv1.FleetNamespaceMigratedCondition)).
+			To(Equal(turtlesv1.FleetMigrationWaitingForAgentReason))
+
+		Expect(remoteClient.Create(ctx, agentNamespace())).To(Succeed())
+
+		res, err = r.reconcileFleetMigration(ctx, capiCluster, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(BeZero())
+		Expect(conditions.IsTrue(capiCluster, turtlesv1.FleetNamespaceMigratedCondition)).To(BeTrue())
+		Expect(rancherCluster.Annotations).To(HaveKeyWithValue(fleetNamespaceMigrated, fleetAgentNamespace))
+	})
+
+	It("should report the migration as stuck after the timeout", func() {
+		remoteClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+		conditions.Set(capiCluster, metav1.Condition{
+			Type:               turtlesv1.FleetNamespaceMigratedCondition,
+			Status:             metav1.ConditionFalse,
+			Reason:             turtlesv1.FleetMigrationWaitingForAgentReason,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+		})
+
+		_, err := r.reconcileFleetMigration(ctx, capiCluster, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conditions.GetReason(capiCluster, turtlesv1.FleetNamespaceMigratedCondition)).
+			To(Equal(turtlesv1.FleetMigrationStuckReason))
+		Expect(rancherCluster.Annotations).NotTo(HaveKey(fleetNamespaceMigrated))
+	})
+})
@@ -0,0 +1,229 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	capiannotations "sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/predicates"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+	turtlespredicates "github.com/rancher/turtles/util/predicates"
+)
+
+const healthyClusterSummary = "Healthy"
+
+// healthConditions are the CAPI cluster conditions summarized in the health annotation, with their healthy status.
+var healthConditions = []struct {
+	conditionType string
+	healthy       metav1.ConditionStatus
+}{
+	{clusterv1.ClusterControlPlaneAvailableCondition, metav1.ConditionTrue},
+	{clusterv1.ClusterWorkersAvailableCondition, metav1.ConditionTrue},
+	{clusterv1.ClusterRemediatingCondition, metav1.ConditionFalse},
+	{clusterv1.ClusterTopologyReconciledCondition, metav1.ConditionTrue},
+}
+
+// clusterHealthSummary returns the summarized status of the CAPI cluster, mirrored as annotations on the Rancher cluster.
+// Empty values remove the annotation.
+func clusterHealthSummary(capiCluster *clusterv1.Cluster) map[string]string {
+	var ready, desired int32
+
+	if controlPlane := capiCluster.Status.ControlPlane; controlPlane != nil {
+		ready += ptr.Deref(controlPlane.ReadyReplicas, 0)
+		desired += ptr.Deref(controlPlane.DesiredReplicas, 0)
+	}
+
+	if workers := capiCluster.Status.Workers; workers != nil {
+		ready += ptr.Deref(workers.ReadyReplicas, 0)
+		desired += ptr.Deref(workers.DesiredReplicas, 0)
+	}
+
+	unhealthy := []string{}
+
+	for _, c := range healthConditions {
+		condition := conditions.Get(capiCluster, c.conditionType)
+		if condition == nil || condition.Status == c.healthy {
+			continue
+		}
+
+		unhealthy = append(unhealthy, fmt.Sprintf("%s=%s (%s)", condition.Type, condition.Status, condition.Reason))
+	}
+
+	health := healthyClusterSummary
+	if len(unhealthy) > 0 {
+		health = strings.Join(unhealthy, ", ")
+	}
+
+	return map[string]string{
+		turtlesannotations.CAPIHealthAnnotation: health,
+		turtlesannotations.CAPIControlPlaneReadyAnnotation: strconv.FormatBool(
+			conditions.IsTrue(capiCluster, clusterv1.ClusterControlPlaneAvailableCondition)),
+		turtlesannotations.CAPIMachinesAnnotation:        fmt.Sprintf("%d/%d", ready, desired),
+		turtlesannotations.CAPITopologyVersionAnnotation: capiCluster.Spec.Topology.Version,
+		turtlesannotations.CAPIPausedAnnotation:          strconv.FormatBool(capiannotations.IsPaused(capiCluster, capiCluster)),
+	}
+}
+
+// ClusterHealthReconciler mirrors the health summary of imported CAPI clusters as annotations on their Rancher clusters.
+// The summary is kept out of the import, so it is mirrored for all imported clusters.
+type ClusterHealthReconciler struct {
+	Client client.Client
+	// RancherClient is the client for the Rancher Manager cluster, holding Rancher clusters.
+	// Defaults to Client, when Turtles runs in the Rancher Manager cluster.
+	RancherClient client.Client
+	// RancherTargets are the Rancher Manager clusters of the RancherTargets, where CAPI clusters may be imported.
+	RancherTargets *RancherTargets
+
+	WatchFilterValue string
+}
+
+// SetupWithManager sets up reconciler with manager.
+func (r *ClusterHealthReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
+	log := log.FromContext(ctx)
+
+	if err := ctrl.NewControllerManagedBy(mgr).
+		Named("clusterhealth").
+		For(&clusterv1.Cluster{}).
+		WithOptions(options).
+		WithEventFilter(predicates.All(mgr.GetScheme(), log,
+			predicates.ResourceHasFilterLabel(mgr.GetScheme(), log, r.WatchFilterValue),
+			turtlespredicates.ClusterWithRancherCluster(log),
+		)).
+		Complete(r); err != nil {
+		return fmt.Errorf("creating health summary controller: %w", err)
+	}
+
+	return nil
+}
+
+// Reconcile mirrors the health summary of an imported CAPI cluster. The summary is informational,
+// so errors are logged and the sync is retried later, without failing the reconcile.
+func (r *ClusterHealthReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	capiCluster := &clusterv1.Cluster{}
+	if err := r.Client.Get(ctx, req.NamespacedName, capiCluster); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if err := r.syncHealthSummary(ctx, capiCluster); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to sync the health summary to the Rancher cluster")
+		return ctrl.Result{RequeueAfter: defaultRequeueDuration}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// syncHealthSummary mirrors the summarized status of the CAPI cluster to the Rancher cluster it was imported as,
+// so CAPI side problems are visible in Rancher. Clusters which were not imported yet are skipped.
+// Only the paused state is mirrored for paused clusters, which are otherwise left untouched.
+func (r *ClusterHealthReconciler) syncHealthSummary(ctx context.Context, capiCluster *clusterv1.Cluster) error {
+	name := capiCluster.GetAnnotations()[turtlesannotations.RancherClusterAnnotation]
+	if name == "" || !capiCluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	rancherClient, err := r.rancherClientFor(ctx, capiCluster)
+	if err != nil {
+		return fmt.Errorf("error getting client for Rancher target: %w", err)
+	}
+
+	rancherCluster := &managementv3.Cluster{}
+	if err := rancherClient.Get(ctx, client.ObjectKey{Namespace: capiCluster.Namespace, Name: name}, rancherCluster); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if _, owned := rancherCluster.GetLabels()[ownedLabelName]; !owned || !rancherCluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	patchBase := client.MergeFrom(rancherCluster.DeepCopy())
+
+	annotations := rancherCluster.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	summary := clusterHealthSummary(capiCluster)
+	if capiannotations.IsPaused(capiCluster, capiCluster) {
+		summary = map[string]string{turtlesannotations.CAPIPausedAnnotation: summary[turtlesannotations.CAPIPausedAnnotation]}
+	}
+
+	changed := false
+
+	for key, value := range summary {
+		current, found := annotations[key]
+
+		switch {
+		case value == "" && found:
+			delete(annotations, key)
+		case value != "" && current != value:
+			annotations[key] = value
+		default:
+			continue
+		}
+
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	rancherCluster.SetAnnotations(annotations)
+
+	if err := rancherClient.Patch(ctx, rancherCluster, patchBase); err != nil {
+		return fmt.Errorf("error patching Rancher cluster %s health summary: %w", name, err)
+	}
+
+	return nil
+}
+
+// rancherClientFor returns the client for the Rancher Manager cluster, where the CAPI cluster is imported.
+func (r *ClusterHealthReconciler) rancherClientFor(ctx context.Context, capiCluster *clusterv1.Cluster) (client.Client, error) {
+	target, err := rancherTargetName(ctx, r.Client, capiCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if target == "" {
+		if r.RancherClient != nil {
+			return r.RancherClient, nil
+		}
+
+		return r.Client, nil
+	}
+
+	if r.RancherTargets == nil {
+		return nil, fmt.Errorf("getting client for Rancher target %s: Rancher targets are not configured", target)
+	}
+
+	return r.RancherTargets.client(ctx, r.Client, target)
+}
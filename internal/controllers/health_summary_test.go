@@ -0,0 +1,187 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+var _ = Describe("ClusterHealthReconciler", func() {
+	var (
+		ctx            context.Context
+		healthScheme   *runtime.Scheme
+		capiCluster    *clusterv1.Cluster
+		rancherCluster *managementv3.Cluster
+	)
+
+	// newReconciler returns a reconciler with the CAPI cluster and its namespace in the management cluster,
+	// and the Rancher cluster in the Rancher client.
+	newReconciler := func() (*ClusterHealthReconciler, client.Client) {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+		rancherClient := fake.NewClientBuilder().WithScheme(healthScheme).WithObjects(rancherCluster).Build()
+
+		return &ClusterHealthReconciler{
+			Client:         fake.NewClientBuilder().WithScheme(healthScheme).WithObjects(namespace, capiCluster).Build(),
+			RancherClient:  rancherClient,
+			RancherTargets: NewRancherTargets(healthScheme),
+		}, rancherClient
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		healthScheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(healthScheme)).To(Succeed())
+		Expect(clusterv1.AddToScheme(healthScheme)).To(Succeed())
+		Expect(managementv3.AddToScheme(healthScheme)).To(Succeed())
+		Expect(turtlesv1.AddToScheme(healthScheme)).To(Succeed())
+
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "cluster1",
+				Namespace: "default",
+				Annotations: map[string]string{
+					turtlesannotations.RancherClusterAnnotation: "c-cluster1",
+				},
+			},
+			Spec: clusterv1.ClusterSpec{
+				Topology: clusterv1.Topology{Version: "v1.33.0"},
+			},
+			Status: clusterv1.ClusterStatus{
+				Conditions: []metav1.Condition{
+					{Type: clusterv1.ClusterControlPlaneAvailableCondition, Status: metav1.ConditionTrue, Reason: "Available"},
+					{Type: clusterv1.ClusterWorkersAvailableCondition, Status: metav1.ConditionTrue, Reason: "Available"},
+					{Type: clusterv1.ClusterRemediatingCondition, Status: metav1.ConditionFalse, Reason: "NotRemediating"},
+				},
+				ControlPlane: &clusterv1.ClusterControlPlaneStatus{DesiredReplicas: ptr.To[int32](3), ReadyReplicas: ptr.To[int32](3)},
+				Workers:      &clusterv1.WorkersStatus{DesiredReplicas: ptr.To[int32](2), ReadyReplicas: ptr.To[int32](1)},
+			},
+		}
+		rancherCluster = &managementv3.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "c-cluster1",
+				Namespace: "default",
+				Labels:    map[string]string{ownedLabelName: ""},
+			},
+		}
+	})
+
+	It("should summarize a healthy cluster", func() {
+		Expect(clusterHealthSummary(capiCluster)).To(Equal(map[string]string{
+			turtlesannotations.CAPIHealthAnnotation:            healthyClusterSummary,
+			turtlesannotations.CAPIControlPlaneReadyAnnotation: "true",
+			turtlesannotations.CAPIMachinesAnnotation:          "4/5",
+			turtlesannotations.CAPITopologyVersionAnnotation:   "v1.33.0",
+			turtlesannotations.CAPIPausedAnnotation:            "false",
+		}))
+	})
+
+	It("should list the unhealthy conditions", func() {
+		capiCluster.Status.Conditions[1].Status = metav1.ConditionFalse
+		capiCluster.Status.Conditions[1].Reason = "NotAvailable"
+		capiCluster.Status.Conditions[2].Status = metav1.ConditionTrue
+		capiCluster.Status.Conditions[2].Reason = "Remediating"
+		capiCluster.Spec.Paused = ptr.To(true)
+
+		summary := clusterHealthSummary(capiCluster)
+		Expect(summary).To(HaveKeyWithValue(turtlesannotations.CAPIHealthAnnotation,
+			"WorkersAvailable=False (NotAvailable), Remediating=True (Remediating)"))
+		Expect(summary).To(HaveKeyWithValue(turtlesannotations.CAPIPausedAnnotation, "true"))
+	})
+
+	It("should mirror the summary to the Rancher cluster", func() {
+		capiCluster.Spec.Topology = clusterv1.Topology{}
+		rancherCluster.Annotations = map[string]string{turtlesannotations.CAPITopologyVersionAnnotation: "v1.32.0"}
+		r, rancherClient := newReconciler()
+
+		Expect(r.syncHealthSummary(ctx, capiCluster)).To(Succeed())
+
+		Expect(rancherClient.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).To(HaveKeyWithValue(turtlesannotations.CAPIHealthAnnotation, healthyClusterSummary))
+		Expect(rancherCluster.Annotations).To(HaveKeyWithValue(turtlesannotations.CAPIMachinesAnnotation, "4/5"))
+		Expect(rancherCluster.Annotations).NotTo(HaveKey(turtlesannotations.CAPITopologyVersionAnnotation))
+	})
+
+	It("should only mirror the paused state of a paused cluster", func() {
+		capiCluster.Spec.Paused = ptr.To(true)
+		rancherCluster.Annotations = map[string]string{turtlesannotations.CAPIHealthAnnotation: "WorkersAvailable=False (NotAvailable)"}
+		r, rancherClient := newReconciler()
+
+		Expect(r.syncHealthSummary(ctx, capiCluster)).To(Succeed())
+
+		Expect(rancherClient.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).To(Equal(map[string]string{
+			turtlesannotations.CAPIHealthAnnotation: "WorkersAvailable=False (NotAvailable)",
+			turtlesannotations.CAPIPausedAnnotation: "true",
+		}))
+	})
+
+	It("should skip Rancher clusters not created by Turtles", func() {
+		rancherCluster.Labels = nil
+		r, rancherClient := newReconciler()
+
+		Expect(r.syncHealthSummary(ctx, capiCluster)).To(Succeed())
+
+		Expect(rancherClient.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).To(BeEmpty())
+	})
+
+	It("should mirror the summary of the reconciled cluster", func() {
+		r, rancherClient := newReconciler()
+
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(capiCluster)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(BeZero())
+
+		Expect(rancherClient.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).To(HaveKeyWithValue(turtlesannotations.CAPIHealthAnnotation, healthyClusterSummary))
+	})
+
+	It("should retry without failing when the Rancher target can't be resolved", func() {
+		capiCluster.Annotations[turtlesannotations.RancherTargetAnnotation] = "eu"
+		r, _ := newReconciler()
+
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(capiCluster)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(defaultRequeueDuration))
+	})
+
+	It("should ignore deleted clusters", func() {
+		r, _ := newReconciler()
+
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "default", Name: "deleted"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(BeZero())
+	})
+})
@@ -19,19 +19,24 @@ package controllers
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	yamlDecoder "k8s.io/apimachinery/pkg/util/yaml"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -44,7 +49,9 @@ import (
 	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
 
 	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
 	"github.com/rancher/turtles/util"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
 )
 
 const (
@@ -66,10 +73,12 @@ const (
 	trueValue              = "true"
 
 	tokenPlaceholder = "{token}"
+
+	importFieldOwner = "rancher-turtles"
 )
 
 func getClusterRegistrationManifest(ctx context.Context, clusterName, namespace string, cl client.Client,
-	caCert []byte, insecureSkipVerify bool,
+	caCert, caBundle []byte, insecureSkipVerify bool, cache *manifestCache,
 ) (string, error) {
 	log := log.FromContext(ctx)
 
@@ -105,7 +114,7 @@ func getClusterRegistrationManifest(ctx context.Context, clusterName, namespace
 		return "", nil
 	}
 
-	manifestData, err := downloadManifest(manifestURL, caCert, insecureSkipVerify)
+	manifestData, err := cache.download(manifestURL, caCert, caBundle, insecureSkipVerify)
 	if err != nil {
 		log.Error(err, "failed downloading import manifest")
 		return "", err
@@ -155,8 +164,17 @@ func namespaceToCapiClusters(ctx context.Context, clusterPredicate predicate.Fun
 		}
 
 		if _, autoImport := util.ShouldImport(ns, importLabelName); !autoImport {
-			log.V(2).Info("Namespace doesn't have import annotation label with a true value, skipping")
-			return nil
+			// Import policies may still select the clusters in the namespace.
+			policies := &turtlesv1.ImportPolicyList{}
+			if err := cl.List(ctx, policies, client.Limit(1)); err != nil {
+				log.Error(err, "listing import policies")
+				return nil
+			}
+
+			if len(policies.Items) == 0 {
+				log.V(2).Info("Namespace doesn't have import annotation label with a true value, skipping")
+				return nil
+			}
 		}
 
 		capiClusters := &clusterv1.ClusterList{}
@@ -189,7 +207,39 @@ func namespaceToCapiClusters(ctx context.Context, clusterPredicate predicate.Fun
 	}
 }
 
-func downloadManifest(url string, caCert []byte, insecureSkipVerify bool) (string, error) {
+// importPolicyToCapiClusters maps an import policy change to all CAPI clusters accepted by the cluster predicate.
+func importPolicyToCapiClusters(ctx context.Context, clusterPredicate predicate.Funcs, cl client.Client) handler.MapFunc {
+	log := log.FromContext(ctx)
+
+	return func(_ context.Context, o client.Object) []ctrl.Request {
+		if _, ok := o.(*turtlesv1.ImportPolicy); !ok {
+			log.Error(nil, fmt.Sprintf("Expected an ImportPolicy but got a %T", o))
+			return nil
+		}
+
+		capiClusters := &clusterv1.ClusterList{}
+		if err := cl.List(ctx, capiClusters); err != nil {
+			log.Error(err, "getting capi clusters")
+			return nil
+		}
+
+		reqs := []ctrl.Request{}
+
+		for _, cluster := range capiClusters.Items {
+			if !clusterPredicate.Generic(event.GenericEvent{Object: &cluster}) {
+				continue
+			}
+
+			reqs = append(reqs, ctrl.Request{
+				NamespacedName: client.ObjectKeyFromObject(&cluster),
+			})
+		}
+
+		return reqs
+	}
+}
+
+func downloadManifest(url string, caCert, caBundle []byte, insecureSkipVerify bool) (string, error) {
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: insecureSkipVerify, //nolint:gosec
 	}
@@ -204,6 +254,22 @@ func downloadManifest(url string, caCert []byte, insecureSkipVerify bool) (strin
 		tlsConfig.RootCAs = caCertPool
 	}
 
+	// The CA bundle extends the trusted CA certificate, or the system store if it is not provided
+	if caBundle != nil {
+		if tlsConfig.RootCAs == nil {
+			systemPool, err := x509.SystemCertPool()
+			if err != nil {
+				systemPool = x509.NewCertPool()
+			}
+
+			tlsConfig.RootCAs = systemPool
+		}
+
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caBundle) {
+			return "", errors.New("failed to append CA bundle")
+		}
+	}
+
 	client := &http.Client{Transport: &http.Transport{
 		TLSClientConfig: tlsConfig,
 	}}
@@ -222,45 +288,166 @@ func downloadManifest(url string, caCert []byte, insecureSkipVerify bool) (strin
 	return string(data), err
 }
 
-// removeFleetNamespace cleans up previous namespace of the deployed agent on the downstream cluster.
-func removeFleetNamespace(ctx context.Context, cl client.Client, cluster *managementv3.Cluster) (bool, error) {
-	log := log.FromContext(ctx)
-	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
-		Name: "fleet-addon-agent",
-	}}
+// manifestObjectRef identifies an object of the import manifest on the downstream cluster.
+type manifestObjectRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// applyImportManifest server-side applies all objects of the import manifest on the downstream cluster
+// and returns references to the applied objects.
+func applyImportManifest(ctx context.Context, remoteClient client.Client, in io.Reader) ([]manifestObjectRef, error) {
+	reader := yamlDecoder.NewYAMLReader(bufio.NewReaderSize(in, 4096))
+	applied := []manifestObjectRef{}
+
+	for {
+		raw, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return applied, err
+		}
+
+		refs, err := applyRawManifest(ctx, remoteClient, raw)
+		applied = append(applied, refs...)
 
-	if err := cl.Get(ctx, client.ObjectKeyFromObject(ns), ns); client.IgnoreNotFound(err) != nil {
-		return true, fmt.Errorf("unable to check fleet agent namespace on downstream cluster: %w", err)
-	} else if err == nil {
-		if err := cl.Delete(ctx, ns); err != nil {
-			return true, fmt.Errorf("unable to remove old fleet agent namespace on downstream cluster: %w", err)
+		if err != nil {
+			return applied, err
 		}
 	}
 
-	if err := cl.Get(ctx, client.ObjectKeyFromObject(ns), ns); apierrors.IsNotFound(err) {
-		ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
-			Name: "cattle-fleet-system",
-		}}
+	return applied, nil
+}
+
+// pruneImportManifest deletes objects from the previous import inventory, which are no longer part of the manifest.
+func pruneImportManifest(ctx context.Context, remoteClient client.Client, previous, current []manifestObjectRef) error {
+	log := log.FromContext(ctx)
 
-		if err := cl.Get(ctx, client.ObjectKeyFromObject(ns), ns); err != nil {
-			return true, fmt.Errorf("cattle-fleet-system namespace is not present yet: %w", err)
+	for _, ref := range previous {
+		if slices.Contains(current, ref) {
+			continue
 		}
 
-		log.Info("fleet agent namespace is migrated")
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(ref.APIVersion)
+		obj.SetKind(ref.Kind)
+		obj.SetNamespace(ref.Namespace)
+		obj.SetName(ref.Name)
 
-		annotations := cluster.GetAnnotations()
-		annotations[fleetNamespaceMigrated] = ns.Name
-		cluster.SetAnnotations(annotations)
+		err := remoteClient.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			continue
+		}
 
-		return false, nil
-	} else if err == nil {
-		log.Info("fleet agent namespace is not migrated yet")
+		if err != nil {
+			return fmt.Errorf("pruning object %s %s in remote cluster: %w", ref.Kind, client.ObjectKeyFromObject(obj), err)
+		}
+
+		log.V(4).Info("object was pruned", "gvk", obj.GroupVersionKind(), "name", ref.Name, "namespace", ref.Namespace)
 	}
 
-	return true, nil
+	return nil
+}
+
+// importInventory is the content of the import inventory annotation of the Rancher cluster.
+type importInventory struct {
+	// ManifestHash is the hash of the last applied import manifest.
+	ManifestHash string `json:"manifestHash,omitempty"`
+	// Objects are the objects applied on the workload cluster from the import manifest.
+	Objects []manifestObjectRef `json:"objects"`
 }
 
-func createImportManifest(ctx context.Context, remoteClient client.Client, in io.Reader) error {
+// getImportInventory returns the import inventory recorded in the annotation of the Rancher cluster.
+// Inventories written as a plain list of objects are still accepted, without a manifest hash.
+func getImportInventory(rancherCluster *managementv3.Cluster) (importInventory, error) {
+	inventory := importInventory{Objects: []manifestObjectRef{}}
+
+	value, ok := rancherCluster.GetAnnotations()[turtlesannotations.ImportInventoryAnnotation]
+	if !ok {
+		return inventory, nil
+	}
+
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(value), "[") {
+		err = json.Unmarshal([]byte(value), &inventory.Objects)
+	} else {
+		err = json.Unmarshal([]byte(value), &inventory)
+	}
+
+	if err != nil {
+		return importInventory{}, fmt.Errorf("parsing import inventory of Rancher cluster %s: %w", rancherCluster.Name, err)
+	}
+
+	return inventory, nil
+}
+
+// setImportInventory records the import inventory in the annotation of the Rancher cluster.
+func setImportInventory(rancherCluster *managementv3.Cluster, inventory importInventory) error {
+	value, err := json.Marshal(inventory)
+	if err != nil {
+		return fmt.Errorf("serializing import inventory: %w", err)
+	}
+
+	annotations := rancherCluster.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[turtlesannotations.ImportInventoryAnnotation] = string(value)
+	rancherCluster.SetAnnotations(annotations)
+
+	return nil
+}
+
+// importManifestHash returns the hash of the import manifest recorded in the import inventory.
+func importManifestHash(manifest string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(manifest)))
+}
+
+// importManifestDiff summarizes the changes applying an import manifest would make on the downstream cluster.
+type importManifestDiff struct {
+	Create    []string
+	Update    []string
+	Unchanged []string
+	Deleting  []string
+}
+
+// String renders the diff with one object per line, prefixed with the planned action.
+func (d importManifestDiff) String() string {
+	var sb strings.Builder
+
+	for _, group := range []struct {
+		action string
+		items  []string
+	}{
+		{"create", d.Create},
+		{"update", d.Update},
+		{"deleting", d.Deleting},
+		{"unchanged", d.Unchanged},
+	} {
+		for _, item := range group.items {
+			fmt.Fprintf(&sb, "%s %s\n", group.action, item)
+		}
+	}
+
+	return sb.String()
+}
+
+// Summary returns a short description of the diff, suitable for a condition message.
+func (d importManifestDiff) Summary() string {
+	return fmt.Sprintf("%d objects to create, %d to update, %d being deleted, %d unchanged",
+		len(d.Create), len(d.Update), len(d.Deleting), len(d.Unchanged))
+}
+
+// diffImportManifest compares the objects of the import manifest with their state in the downstream cluster.
+// Existing objects are considered unchanged when all fields set in the manifest match the live object.
+func diffImportManifest(ctx context.Context, remoteClient client.Client, in io.Reader) (importManifestDiff, error) {
+	diff := importManifestDiff{}
+
 	reader := yamlDecoder.NewYAMLReader(bufio.NewReaderSize(in, 4096))
 
 	for {
@@ -270,15 +457,103 @@ func createImportManifest(ctx context.Context, remoteClient client.Client, in io
 		}
 
 		if err != nil {
-			return err
+			return diff, err
 		}
 
-		if err := createRawManifest(ctx, remoteClient, raw); err != nil {
-			return err
+		items, err := utilyaml.ToUnstructured(raw)
+		if err != nil {
+			return diff, fmt.Errorf("error unmarshalling bytes or empty object passed: %w", err)
+		}
+
+		for _, desired := range items {
+			ref := fmt.Sprintf("%s %s", desired.GroupVersionKind().String(), client.ObjectKeyFromObject(&desired))
+
+			live := &unstructured.Unstructured{}
+			live.SetGroupVersionKind(desired.GroupVersionKind())
+
+			err := remoteClient.Get(ctx, client.ObjectKeyFromObject(&desired), live)
+
+			switch {
+			case apierrors.IsNotFound(err):
+				diff.Create = append(diff.Create, ref)
+			case err != nil:
+				return diff, fmt.Errorf("checking object in remote cluster: %w", err)
+			case live.GetDeletionTimestamp() != nil:
+				diff.Deleting = append(diff.Deleting, ref)
+			case manifestObjectChanged(&desired, live):
+				diff.Update = append(diff.Update, ref)
+			default:
+				diff.Unchanged = append(diff.Unchanged, ref)
+			}
 		}
 	}
 
-	return nil
+	return diff, nil
+}
+
+// manifestObjectChanged returns true if any of the fields set in the desired object differs from the live object.
+// Server managed metadata and the status are ignored.
+func manifestObjectChanged(desired, live *unstructured.Unstructured) bool {
+	for key, value := range desired.Object {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			if !isSubset(desired.GetLabels(), live.GetLabels()) || !isSubset(desired.GetAnnotations(), live.GetAnnotations()) {
+				return true
+			}
+		default:
+			if !isSubset(value, live.Object[key]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isSubset returns true if all values set in desired are present in live. Fields defaulted
+// by the API server on the live object are not taken into account.
+func isSubset(desired, live any) bool {
+	switch d := desired.(type) {
+	case map[string]any:
+		l, ok := live.(map[string]any)
+		if !ok {
+			return len(d) == 0 && live == nil
+		}
+
+		for key, value := range d {
+			if !isSubset(value, l[key]) {
+				return false
+			}
+		}
+
+		return true
+	case map[string]string:
+		l, _ := live.(map[string]string)
+		for key, value := range d {
+			if l[key] != value {
+				return false
+			}
+		}
+
+		return true
+	case []any:
+		l, ok := live.([]any)
+		if !ok || len(l) != len(d) {
+			return len(d) == 0 && live == nil
+		}
+
+		for i := range d {
+			if !isSubset(d[i], l[i]) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return fmt.Sprint(desired) == fmt.Sprint(live)
+	}
 }
 
 func validateImportReadiness(ctx context.Context, remoteClient client.Client, in io.Reader) (bool, error) {
@@ -316,19 +591,28 @@ func validateImportReadiness(ctx context.Context, remoteClient client.Client, in
 	return false, nil
 }
 
-func createRawManifest(ctx context.Context, remoteClient client.Client, bytes []byte) error {
+func applyRawManifest(ctx context.Context, remoteClient client.Client, bytes []byte) ([]manifestObjectRef, error) {
 	items, err := utilyaml.ToUnstructured(bytes)
 	if err != nil {
-		return fmt.Errorf("error unmarshalling bytes or empty object passed: %w", err)
+		return nil, fmt.Errorf("error unmarshalling bytes or empty object passed: %w", err)
 	}
 
+	applied := []manifestObjectRef{}
+
 	for _, obj := range items {
-		if err := createObject(ctx, remoteClient, obj.DeepCopy()); err != nil {
-			return err
+		if err := applyObject(ctx, remoteClient, obj.DeepCopy()); err != nil {
+			return applied, err
 		}
+
+		applied = append(applied, manifestObjectRef{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+		})
 	}
 
-	return nil
+	return applied, nil
 }
 
 func verifyRawManifest(ctx context.Context, remoteClient client.Client, bytes []byte) (bool, error) {
@@ -366,21 +650,18 @@ func checkDeletion(ctx context.Context, c client.Client, obj client.Object) (boo
 	return false, nil
 }
 
-func createObject(ctx context.Context, c client.Client, obj client.Object) error {
+func applyObject(ctx context.Context, c client.Client, obj *unstructured.Unstructured) error {
 	log := log.FromContext(ctx)
-	gvk := obj.GetObjectKind().GroupVersionKind()
+	gvk := obj.GroupVersionKind()
 
-	err := c.Create(ctx, obj)
-	if apierrors.IsAlreadyExists(err) {
-		log.V(4).Info("object already exists in remote cluster", "gvk", gvk, "name", obj.GetName(), "namespace", obj.GetNamespace())
-		return nil
+	if err := c.Apply(ctx, client.ApplyConfigurationFromUnstructured(obj),
+		client.ForceOwnership,
+		client.FieldOwner(importFieldOwner),
+	); err != nil {
+		return fmt.Errorf("applying object in remote cluster: %w", err)
 	}
 
-	if err != nil {
-		return fmt.Errorf("creating object in remote cluster: %w", err)
-	}
-
-	log.V(4).Info("object was created", "gvk", gvk, "name", obj.GetName(), "namespace", obj.GetNamespace())
+	log.V(4).Info("object was applied", "gvk", gvk, "name", obj.GetName(), "namespace", obj.GetNamespace())
 
 	return nil
 }
@@ -431,6 +712,60 @@ func getTrustedCAcert(ctx context.Context, cl client.Client, agentTLSModeFeature
 	}
 }
 
+// CABundleRef references a Secret or ConfigMap key holding PEM encoded CA certificates, which are trusted
+// when downloading the import manifest from the Rancher server URL.
+type CABundleRef struct {
+	// Kind is either Secret or ConfigMap.
+	Kind      string
+	Namespace string
+	Name      string
+	Key       string
+}
+
+// IsSet returns true if the CA bundle source is configured.
+func (r CABundleRef) IsSet() bool {
+	return r.Name != ""
+}
+
+// getCABundle reads the CA bundle from the referenced Secret or ConfigMap. The objects are read on each call,
+// so changes to the bundle are picked up without restarting the controller.
+func getCABundle(ctx context.Context, cl client.Client, ref CABundleRef) ([]byte, error) {
+	if !ref.IsSet() {
+		return nil, nil
+	}
+
+	key := client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}
+
+	var bundle []byte
+
+	switch ref.Kind {
+	case "Secret":
+		secret := &corev1.Secret{}
+		if err := cl.Get(ctx, key, secret); err != nil {
+			return nil, fmt.Errorf("error getting CA bundle secret %s: %w", key, err)
+		}
+
+		bundle = secret.Data[ref.Key]
+	case "ConfigMap":
+		configMap := &corev1.ConfigMap{}
+		if err := cl.Get(ctx, key, configMap); err != nil {
+			return nil, fmt.Errorf("error getting CA bundle config map %s: %w", key, err)
+		}
+
+		bundle = []byte(configMap.Data[ref.Key])
+	default:
+		return nil, fmt.Errorf("invalid CA bundle kind: %s", ref.Kind)
+	}
+
+	if len(bundle) == 0 {
+		return nil, fmt.Errorf("CA bundle key %s is empty in %s %s", ref.Key, ref.Kind, key)
+	}
+
+	log.FromContext(ctx).V(4).Info("using CA bundle for the Rancher server", "kind", ref.Kind, "name", key)
+
+	return bundle, nil
+}
+
 func verifySecretOwnership(obj client.Object, sourceSecret *corev1.Secret) error {
 	if obj.GetResourceVersion() != "" {
 		annots := obj.GetAnnotations()
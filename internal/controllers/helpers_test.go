@@ -22,6 +22,8 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -122,3 +124,96 @@ var _ = Describe("getTrustedCAcert", func() {
 		Expect(result).To(BeNil())
 	})
 })
+
+var _ = Describe("getImportInventory", func() {
+	var rancherCluster *managementv3.Cluster
+
+	BeforeEach(func() {
+		rancherCluster = &managementv3.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "c-test",
+				Annotations: map[string]string{},
+			},
+		}
+	})
+
+	It("should return an empty inventory when the annotation is missing", func() {
+		inventory, err := getImportInventory(rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inventory.ManifestHash).To(BeEmpty())
+		Expect(inventory.Objects).To(BeEmpty())
+	})
+
+	It("should read an inventory written as a list of objects", func() {
+		rancherCluster.Annotations[turtlesannotations.ImportInventoryAnnotation] =
+			`[{"apiVersion":"v1","kind":"ConfigMap","namespace":"default","name":"agent"}]`
+
+		inventory, err := getImportInventory(rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inventory.ManifestHash).To(BeEmpty())
+		Expect(inventory.Objects).To(ConsistOf(manifestObjectRef{
+			APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "agent",
+		}))
+	})
+
+	It("should round-trip the manifest hash and the applied objects", func() {
+		want := importInventory{
+			ManifestHash: importManifestHash("manifest"),
+			Objects:      []manifestObjectRef{{APIVersion: "v1", Kind: "Namespace", Name: "cattle-system"}},
+		}
+		Expect(setImportInventory(rancherCluster, want)).To(Succeed())
+
+		inventory, err := getImportInventory(rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inventory).To(Equal(want))
+	})
+})
+
+var _ = Describe("getCABundle", func() {
+	var (
+		ctx        context.Context
+		fakeClient client.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	})
+
+	It("should return nil when CA bundle is not configured", func() {
+		result, err := getCABundle(ctx, fakeClient, CABundleRef{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(BeNil())
+	})
+
+	It("should read the CA bundle from a secret", func() {
+		Expect(fakeClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "rancher-ca", Namespace: "default"},
+			Data:       map[string][]byte{"ca.crt": []byte("bundle")},
+		})).To(Succeed())
+
+		result, err := getCABundle(ctx, fakeClient, CABundleRef{Kind: "Secret", Namespace: "default", Name: "rancher-ca", Key: "ca.crt"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]byte("bundle")))
+	})
+
+	It("should read the CA bundle from a config map", func() {
+		Expect(fakeClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "rancher-ca", Namespace: "default"},
+			Data:       map[string]string{"ca.crt": "bundle"},
+		})).To(Succeed())
+
+		result, err := getCABundle(ctx, fakeClient, CABundleRef{Kind: "ConfigMap", Namespace: "default", Name: "rancher-ca", Key: "ca.crt"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]byte("bundle")))
+	})
+
+	It("should return error when the key is missing", func() {
+		Expect(fakeClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "rancher-ca", Namespace: "default"},
+		})).To(Succeed())
+
+		_, err := getCABundle(ctx, fakeClient, CABundleRef{Kind: "ConfigMap", Namespace: "default", Name: "rancher-ca", Key: "ca.crt"})
+		Expect(err).To(HaveOccurred())
+	})
+})
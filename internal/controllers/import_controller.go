@@ -19,9 +19,12 @@ package controllers
 import (
 	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,7 +33,9 @@ import (
 	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -42,10 +47,13 @@ import (
 	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
 	"sigs.k8s.io/cluster-api/controllers/external"
 	"sigs.k8s.io/cluster-api/controllers/remote"
+	capiannotations "sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/cluster-api/util/predicates"
 
 	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
 	"github.com/rancher/turtles/feature"
 	"github.com/rancher/turtles/util"
 	turtlesannotations "github.com/rancher/turtles/util/annotations"
@@ -56,20 +64,52 @@ const (
 	missingLabelMsg = "missing label"
 	// FleetAddonFinalizer is the finalizer added by CAAPF to guard cleanup.
 	FleetAddonFinalizer = "fleet.addons.cluster.x-k8s.io"
+
+	reimportRequeueDuration = 10 * time.Second
+
+	importDryRunSecretSuffix = "-import-dry-run"
+	importDryRunManifestKey  = "manifest.yaml"
+	importDryRunDiffKey      = "diff.txt"
 )
 
 // CAPIImportReconciler represents a reconciler for importing CAPI clusters in Rancher.
 type CAPIImportReconciler struct {
-	Client             client.Client
-	UncachedClient     client.Client
+	Client         client.Client
+	UncachedClient client.Client
+	// RancherClient is the client for the Rancher Manager cluster, holding Rancher clusters, registration tokens
+	// and settings. Defaults to Client, when Turtles runs in the Rancher Manager cluster.
+	RancherClient client.Client
+	// RancherCache is the cache for the Rancher Manager cluster, used to watch Rancher clusters.
+	// Defaults to the manager cache.
+	RancherCache cache.Cache
+	// RancherTargets are the Rancher Manager clusters of the RancherTargets, whose Rancher clusters are watched.
+	// A RancherTargets is created and added to the manager, when not set.
+	RancherTargets *RancherTargets
+
 	recorder           events.EventRecorder
 	WatchFilterValue   string
 	Scheme             *runtime.Scheme
 	InsecureSkipVerify bool
+	ImportDryRun       bool
+	ImportPrune        bool
+	ManifestCacheTTL   time.Duration
+	ImportRateLimit    float64
+	ImportBurst        int
+	CABundle           CABundleRef
+	ReadinessGates     ImportReadinessGates
+	// ClusterTemplates are the templates of the Rancher cluster display name and description.
+	ClusterTemplates ClusterTemplates
+	// FleetMigrationTimeout is the time after which an incomplete fleet agent namespace migration is reported as stuck.
+	FleetMigrationTimeout time.Duration
+	// DetectSelfManaged enables the detection of the CAPI cluster representing the management cluster itself,
+	// in addition to the self-managed annotation.
+	DetectSelfManaged bool
 
 	controller         controller.Controller
 	externalTracker    external.ObjectTracker
 	remoteClientGetter remote.ClusterClientGetter
+	manifestCache      *manifestCache
+	importLimiter      *rate.Limiter
 }
 
 // SetupWithManager sets up reconciler with manager.
@@ -80,15 +120,38 @@ func (r *CAPIImportReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Ma
 		r.remoteClientGetter = remote.NewClusterClient
 	}
 
+	if r.RancherCache == nil {
+		r.RancherCache = mgr.GetCache()
+	}
+
+	r.manifestCache = newManifestCache(r.ManifestCacheTTL)
+	if r.RancherTargets == nil {
+		r.RancherTargets = NewRancherTargets(r.Client.Scheme())
+
+		if err := mgr.Add(r.RancherTargets); err != nil {
+			return fmt.Errorf("adding Rancher targets to the manager: %w", err)
+		}
+	}
+
+	if r.ImportRateLimit > 0 {
+		r.importLimiter = rate.NewLimiter(rate.Limit(r.ImportRateLimit), max(r.ImportBurst, 1))
+	}
+
 	capiPredicates := predicates.All(r.Scheme, log,
 		predicates.ResourceHasFilterLabel(r.Scheme, log, r.WatchFilterValue),
-		turtlespredicates.ClusterWithoutImportedAnnotation(log),
 		turtlespredicates.ClusterWithReadyControlPlane(log),
-		turtlespredicates.ClusterOrNamespaceWithImportLabel(ctx, log, r.Client, importLabelName),
+		predicates.Any(r.Scheme, log,
+			turtlespredicates.ClusterWithReimportAnnotation(log),
+			predicates.All(r.Scheme, log,
+				turtlespredicates.ClusterWithoutImportedAnnotation(log),
+				turtlespredicates.ClusterOrNamespaceWithImportLabel(ctx, log, r.Client, importLabelName),
+			),
+		),
 	)
 
 	c, err := ctrl.NewControllerManagedBy(mgr).
-		For(&clusterv1.Cluster{}).
+		Named("cluster").
+		Watches(&clusterv1.Cluster{}, enqueueWithImportPriority(r.Client)).
 		WithOptions(options).
 		WithEventFilter(capiPredicates).
 		Build(r)
@@ -98,12 +161,19 @@ func (r *CAPIImportReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Ma
 
 	// Watch Rancher managementv3 clusters
 	if err := c.Watch(
-		source.Kind[client.Object](mgr.GetCache(), &managementv3.Cluster{},
+		source.Kind[client.Object](r.RancherCache, &managementv3.Cluster{},
 			handler.EnqueueRequestsFromMapFunc(r.rancherV3ClusterToCapiCluster(ctx, capiPredicates)),
 		)); err != nil {
 		return fmt.Errorf("adding watch for Rancher cluster: %w", err)
 	}
 
+	// Watch Rancher managementv3 clusters of the RancherTargets, once the target is started
+	r.RancherTargets.Watch(func(_ context.Context, _ string, target cluster.Cluster) error {
+		return c.Watch(source.Kind[client.Object](target.GetCache(), &managementv3.Cluster{},
+			handler.EnqueueRequestsFromMapFunc(r.rancherV3ClusterToCapiCluster(ctx, capiPredicates)),
+		))
+	})
+
 	ns := &corev1.Namespace{}
 	if err = c.Watch(
 		source.Kind[client.Object](mgr.GetCache(), ns,
@@ -112,6 +182,13 @@ func (r *CAPIImportReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Ma
 		return fmt.Errorf("adding watch for namespaces: %w", err)
 	}
 
+	if err = c.Watch(
+		source.Kind[client.Object](mgr.GetCache(), &turtlesv1.ImportPolicy{},
+			handler.EnqueueRequestsFromMapFunc(importPolicyToCapiClusters(ctx, capiPredicates, r.Client)),
+		)); err != nil {
+		return fmt.Errorf("adding watch for import policies: %w", err)
+	}
+
 	r.recorder = mgr.GetEventRecorder("rancher-turtles")
 	r.controller = c
 	r.externalTracker = external.ObjectTracker{
@@ -126,9 +203,11 @@ func (r *CAPIImportReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Ma
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;create;update
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=*,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=*,verbs=get;list;watch
 // +kubebuilder:rbac:groups=management.cattle.io,resources=clusters;clusters/status;clusterregistrationtokens,verbs=get;list;watch;create;update;delete;deletecollection;patch
 // +kubebuilder:rbac:groups=management.cattle.io,resources=clusterregistrationtokens/status;settings,verbs=get;list;watch
 // +kubebuilder:rbac:groups=provisioning.cattle.io,resources=clusters;clusters/status,verbs=get;list;watch
+// +kubebuilder:rbac:groups=turtles-capi.cattle.io,resources=importpolicies;ranchertargets,verbs=get;list;watch
 //
 //nolint:lll
 
@@ -151,6 +230,12 @@ func (r *CAPIImportReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	log = log.WithValues("cluster", capiCluster.Name)
 
+	// Clusters are paused while they are moved between management clusters with `clusterctl move`.
+	if capiannotations.IsPaused(capiCluster, capiCluster) {
+		log.Info("Reconciliation is paused for this cluster")
+		return ctrl.Result{}, nil
+	}
+
 	if capiCluster.DeletionTimestamp.IsZero() &&
 		!turtlesannotations.HasClusterImportAnnotation(capiCluster) &&
 		!controllerutil.ContainsFinalizer(capiCluster, managementv3.CapiClusterFinalizer) {
@@ -176,22 +261,45 @@ func (r *CAPIImportReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{RequeueAfter: defaultRequeueDuration}, nil
 	}
 
-	if turtlesannotations.HasClusterImportAnnotation(capiCluster) {
+	reimport := turtlesannotations.HasClusterReimportAnnotation(capiCluster)
+
+	if turtlesannotations.HasClusterImportAnnotation(capiCluster) && !reimport {
 		log.Info("cluster was imported already and has imported=true annotation set, skipping re-import")
 		return ctrl.Result{}, nil
 	}
 
+	rancherClient, err := r.rancherClientFor(ctx, capiCluster)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("error getting client for Rancher target: %w", err)
+	}
+
 	// Collect errors as an aggregate to return together after all patches have been performed.
 	var errs []error
 
-	patchBase := client.MergeFromWithOptions(capiCluster.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	patchHelper, err := patch.NewHelper(capiCluster, r.Client)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to create patch helper: %w", err)
+	}
+
+	var result ctrl.Result
+
+	if reimport {
+		result, err = r.reconcileReimport(ctx, rancherClient, capiCluster)
+	} else {
+		result, err = r.reconcile(ctx, rancherClient, capiCluster)
+	}
 
-	result, err := r.reconcile(ctx, capiCluster)
 	if err != nil {
 		errs = append(errs, fmt.Errorf("error reconciling cluster: %w", err))
 	}
 
-	if err := r.Client.Patch(ctx, capiCluster, patchBase); err != nil {
+	if err := patchHelper.Patch(ctx, capiCluster, patch.WithOwnedConditions{Conditions: []string{
+		turtlesv1.RancherClusterReimportedCondition,
+		turtlesv1.RancherImportDryRunCondition,
+		turtlesv1.ImportReadinessGatesCondition,
+		turtlesv1.FleetNamespaceMigratedCondition,
+		turtlesv1.KubernetesVersionManagedCondition,
+	}}); err != nil {
 		errs = append(errs, fmt.Errorf("failed to patch cluster: %w", err))
 	}
 
@@ -199,10 +307,17 @@ func (r *CAPIImportReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, errorutils.NewAggregate(errs)
 	}
 
+	if result.RequeueAfter > 0 {
+		result.Priority = importPriority(ctx, r.Client, capiCluster)
+	}
+
 	return result, nil
 }
 
-func (r *CAPIImportReconciler) reconcile(ctx context.Context, capiCluster *clusterv1.Cluster) (res ctrl.Result, reterr error) {
+// getRancherCluster returns the Rancher cluster owned by the CAPI cluster, or nil if it does not exist.
+func (r *CAPIImportReconciler) getRancherCluster(ctx context.Context, rancherClient client.Client,
+	capiCluster *clusterv1.Cluster,
+) (*managementv3.Cluster, error) {
 	log := log.FromContext(ctx)
 
 	labels := map[string]string{
@@ -211,24 +326,35 @@ func (r *CAPIImportReconciler) reconcile(ctx context.Context, capiCluster *clust
 		ownedLabelName:            "",
 	}
 
-	var rancherCluster *managementv3.Cluster
-
 	rancherClusterList := &managementv3.ClusterList{}
 	selectors := []client.ListOption{
 		client.MatchingLabels(labels),
 	}
 
-	if err := r.Client.List(ctx, rancherClusterList, selectors...); client.IgnoreNotFound(err) != nil {
-		log.Error(err, fmt.Sprintf("Unable to fetch rancher cluster %s", client.ObjectKeyFromObject(rancherCluster)))
-		return ctrl.Result{RequeueAfter: defaultRequeueDuration}, err
+	if err := rancherClient.List(ctx, rancherClusterList, selectors...); client.IgnoreNotFound(err) != nil {
+		log.Error(err, fmt.Sprintf("Unable to fetch rancher cluster for CAPI cluster %s", client.ObjectKeyFromObject(capiCluster)))
+		return nil, err
 	}
 
-	if len(rancherClusterList.Items) != 0 {
-		if len(rancherClusterList.Items) > 1 {
-			log.Info("More than one rancher cluster found. Will default to using the first one.")
-		}
+	if len(rancherClusterList.Items) == 0 {
+		return relinkRancherCluster(ctx, rancherClient, capiCluster)
+	}
 
-		rancherCluster = &rancherClusterList.Items[0]
+	if len(rancherClusterList.Items) > 1 {
+		log.Info("More than one rancher cluster found. Will default to using the first one.")
+	}
+
+	return &rancherClusterList.Items[0], nil
+}
+
+func (r *CAPIImportReconciler) reconcile(ctx context.Context, rancherClient client.Client,
+	capiCluster *clusterv1.Cluster,
+) (res ctrl.Result, reterr error) {
+	log := log.FromContext(ctx)
+
+	rancherCluster, err := r.getRancherCluster(ctx, rancherClient, capiCluster)
+	if err != nil {
+		return ctrl.Result{RequeueAfter: defaultRequeueDuration}, err
 	}
 
 	// Reconcile ManagementV3 Cluster deletion.
@@ -242,7 +368,7 @@ func (r *CAPIImportReconciler) reconcile(ctx context.Context, capiCluster *clust
 		}
 
 		if controllerutil.RemoveFinalizer(rancherCluster, managementv3.CapiClusterFinalizer) {
-			if err := r.Client.Update(ctx, rancherCluster); err != nil {
+			if err := rancherClient.Update(ctx, rancherCluster); err != nil {
 				return ctrl.Result{}, fmt.Errorf("error removing rancher cluster finalizer: %w", err)
 			}
 		}
@@ -252,10 +378,12 @@ func (r *CAPIImportReconciler) reconcile(ctx context.Context, capiCluster *clust
 
 	// Reconcile CAPI Cluster deletion.
 	if !capiCluster.DeletionTimestamp.IsZero() {
-		if err := r.deleteDependentRancherCluster(ctx, capiCluster); err != nil {
+		if err := r.deleteDependentRancherCluster(ctx, rancherClient, capiCluster); err != nil {
 			return ctrl.Result{}, fmt.Errorf("error deleting associated managementv3.Cluster resources: %w", err)
 		}
 
+		fleetMigrationStuck.DeleteLabelValues(capiCluster.Namespace, capiCluster.Name)
+
 		if controllerutil.RemoveFinalizer(capiCluster, managementv3.CapiClusterFinalizer) {
 			if err := r.Client.Update(ctx, capiCluster); err != nil {
 				return ctrl.Result{}, fmt.Errorf("error removing finalizer from CAPI Cluster: %w", err)
@@ -265,6 +393,17 @@ func (r *CAPIImportReconciler) reconcile(ctx context.Context, capiCluster *clust
 		return ctrl.Result{}, nil
 	}
 
+	if rancherCluster == nil {
+		selfManaged, err := r.isSelfManaged(ctx, rancherClient, capiCluster)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("error detecting self-managed cluster: %w", err)
+		}
+
+		if selfManaged {
+			return ctrl.Result{}, r.reconcileSelfManaged(ctx, rancherClient, capiCluster)
+		}
+	}
+
 	patchBase := client.MergeFromWithOptions(rancherCluster.DeepCopy(), client.MergeFromWithOptimisticLock{})
 
 	defer func() {
@@ -274,23 +413,28 @@ func (r *CAPIImportReconciler) reconcile(ctx context.Context, capiCluster *clust
 			return
 		}
 
-		if err := r.Client.Patch(ctx, rancherCluster, patchBase); err != nil {
+		if err := rancherClient.Patch(ctx, rancherCluster, patchBase); err != nil {
 			reterr = fmt.Errorf("failed to patch Rancher cluster: %w", err)
 		}
 	}()
 
-	res, reterr = r.reconcileNormal(ctx, capiCluster, rancherCluster)
+	res, reterr = r.reconcileNormal(ctx, rancherClient, capiCluster, rancherCluster)
 
 	return res, reterr
 }
 
-func (r *CAPIImportReconciler) reconcileNormal(ctx context.Context, capiCluster *clusterv1.Cluster,
+func (r *CAPIImportReconciler) reconcileNormal(ctx context.Context, rancherClient client.Client, capiCluster *clusterv1.Cluster,
 	rancherCluster *managementv3.Cluster,
 ) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
 	clusterMissing := rancherCluster == nil
 
+	displayName, description, err := renderClusterTemplates(ctx, r.Client, r.ClusterTemplates, capiCluster)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("error rendering cluster templates: %w", err)
+	}
+
 	updatedCluster := &managementv3.Cluster{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace:    capiCluster.Namespace,
@@ -301,28 +445,34 @@ func (r *CAPIImportReconciler) reconcileNormal(ctx context.Context, capiCluster
 				ownedLabelName:            "",
 			},
 			Annotations: map[string]string{
-				fleetNamespaceMigrated: "cattle-fleet-system",
-				turtlesannotations.ImportedClusterVersionManagementAnnotation: "false",
+				fleetNamespaceMigrated: fleetAgentNamespace,
 			},
 			Finalizers: []string{
 				managementv3.CapiClusterFinalizer,
 			},
 		},
 		Spec: managementv3.ClusterSpec{
-			DisplayName: capiCluster.Name,
-			Description: func() string {
-				annotation := capiCluster.Annotations[turtlesannotations.ClusterDescriptionAnnotation]
-				if annotation == "" {
-					return "CAPI cluster imported to Rancher"
-				}
-
-				return annotation
-			}(),
+			DisplayName: displayName,
+			Description: description,
 		},
 	}
 
 	rancherCluster = cmp.Or(rancherCluster, updatedCluster)
 
+	// Templates are re-rendered on existing clusters, so changes to the source fields are reflected in Rancher.
+	if r.ClusterTemplates.IsSet() {
+		rancherCluster.Spec.DisplayName = displayName
+		rancherCluster.Spec.Description = description
+	}
+
+	if err := r.recordManagementCluster(ctx, rancherCluster, capiCluster); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcileVersionManagement(ctx, rancherCluster, capiCluster); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error applying version management policy: %w", err)
+	}
+
 	r.optOutOfClusterOwner(ctx, rancherCluster)
 	r.propagateLabels(rancherCluster, capiCluster)
 	r.reconcileExternalFleetManagement(ctx, rancherCluster, capiCluster)
@@ -337,10 +487,12 @@ func (r *CAPIImportReconciler) reconcileNormal(ctx context.Context, capiCluster
 			return ctrl.Result{}, err
 		}
 
-		if err := r.Client.Create(ctx, rancherCluster); err != nil {
+		if err := rancherClient.Create(ctx, rancherCluster); err != nil {
 			return ctrl.Result{}, fmt.Errorf("error creating rancher cluster: %w", err)
 		}
 
+		recordRancherCluster(capiCluster, rancherCluster)
+
 		return ctrl.Result{RequeueAfter: defaultRequeueDuration}, nil
 	}
 
@@ -350,68 +502,401 @@ func (r *CAPIImportReconciler) reconcileNormal(ctx context.Context, capiCluster
 	}
 
 	annotations := rancherCluster.GetAnnotations()
-	fleetMigrated = annotations[fleetNamespaceMigrated] == "cattle-fleet-system" || fleetMigrated
+	fleetMigrated = annotations[fleetNamespaceMigrated] == fleetAgentNamespace || fleetMigrated
 
-	if conditions.IsTrue(rancherCluster, managementv3.ClusterConditionReady) && fleetMigrated {
-		log.Info("agent is ready, no action needed")
+	agentReady := conditions.IsTrue(rancherCluster, managementv3.ClusterConditionReady)
+	if agentReady && !fleetMigrated {
+		return r.reconcileFleetMigration(ctx, capiCluster, rancherCluster)
+	}
 
+	// get the registration manifest
+	manifest, err := r.downloadImportManifest(ctx, rancherClient, rancherCluster, r.manifestCache)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if manifest == "" && agentReady {
+		log.Info("agent is ready, no action needed")
 		return ctrl.Result{}, nil
-	} else if conditions.IsTrue(rancherCluster, managementv3.ClusterConditionReady) {
-		// Delete old agent namespace on the downstream cluster
-		remoteClient, err := r.remoteClientGetter(ctx, capiCluster.Name, r.Client, client.ObjectKeyFromObject(capiCluster))
+	} else if manifest == "" {
+		log.Info("Import manifest URL not set yet, requeue")
+		return ctrl.Result{RequeueAfter: defaultRequeueDuration}, nil
+	}
+
+	if agentReady {
+		inventory, err := getImportInventory(rancherCluster)
 		if err != nil {
-			return ctrl.Result{}, fmt.Errorf("getting remote cluster client: %w", err)
+			return ctrl.Result{}, err
 		}
 
-		if requeue, err := removeFleetNamespace(ctx, remoteClient, rancherCluster); err != nil {
-			return ctrl.Result{}, fmt.Errorf("cleaning up fleet namespace: %w", err)
-		} else if requeue {
-			return ctrl.Result{RequeueAfter: defaultRequeueDuration}, nil
+		if inventory.ManifestHash == importManifestHash(manifest) {
+			log.Info("agent is ready, no action needed")
+			return ctrl.Result{}, nil
 		}
 
-		return ctrl.Result{}, nil
+		log.Info("Import manifest changed since it was last applied, updating the agent")
 	}
 
-	// Get custom CAcert if agentTLSMode feature is enabled
-	caCert, err := getTrustedCAcert(ctx, r.Client, feature.Gates.Enabled(feature.AgentTLSMode))
+	log.Info("Creating import manifest")
+
+	remoteClient, err := r.remoteClientGetter(ctx, capiCluster.Name, r.Client, client.ObjectKeyFromObject(capiCluster))
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("error getting CA cert: %w", err)
+		return ctrl.Result{}, fmt.Errorf("getting remote cluster client: %w", err)
 	}
 
-	// get the registration manifest
-	manifest, err := getClusterRegistrationManifest(ctx, rancherCluster.Name, rancherCluster.Name, r.Client, caCert, r.InsecureSkipVerify)
+	if r.ImportDryRun || turtlesannotations.IsClusterImportDryRun(capiCluster) {
+		return ctrl.Result{}, r.reconcileDryRun(ctx, capiCluster, remoteClient, manifest)
+	}
+
+	if passed, err := r.reconcileReadinessGates(ctx, capiCluster, remoteClient); err != nil {
+		return ctrl.Result{}, err
+	} else if !passed {
+		return ctrl.Result{RequeueAfter: defaultRequeueDuration}, nil
+	}
+
+	if requeue, err := validateImportReadiness(ctx, remoteClient, strings.NewReader(manifest)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("verifying import manifest: %w", err)
+	} else if requeue {
+		log.Info("Import manifests are being deleted, not ready to be applied yet, requeue")
+		return ctrl.Result{RequeueAfter: defaultRequeueDuration}, nil
+	}
+
+	if delay := reserveImport(r.importLimiter); delay > 0 {
+		log.Info("Import rate limit reached, requeue", "after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	if err := r.applyImportManifest(ctx, remoteClient, rancherCluster, manifest); err != nil {
+		return ctrl.Result{}, fmt.Errorf("applying import manifest: %w", err)
+	}
+
+	conditions.Delete(capiCluster, turtlesv1.RancherImportDryRunCondition)
+
+	log.Info("Successfully applied import manifest")
+
+	return ctrl.Result{}, nil
+}
+
+// reconcileDryRun stores the rendered import manifest in a Secret next to the CAPI cluster, together with
+// the list of changes applying it would make on the workload cluster. Nothing is applied downstream.
+func (r *CAPIImportReconciler) reconcileDryRun(ctx context.Context, capiCluster *clusterv1.Cluster,
+	remoteClient client.Client, manifest string,
+) error {
+	log := log.FromContext(ctx)
+	log.Info("Import dry-run is enabled, skipping import manifest apply")
+
+	diff, err := diffImportManifest(ctx, remoteClient, strings.NewReader(manifest))
+	if err != nil {
+		return fmt.Errorf("comparing import manifest with workload cluster: %w", err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      capiCluster.Name + importDryRunSecretSuffix,
+			Namespace: capiCluster.Namespace,
+		},
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+
+		secret.Labels[clusterv1.ClusterNameLabel] = capiCluster.Name
+		secret.Data = map[string][]byte{
+			importDryRunManifestKey: []byte(manifest),
+			importDryRunDiffKey:     []byte(diff.String()),
+		}
+
+		return controllerutil.SetOwnerReference(capiCluster, secret, r.Client.Scheme())
+	}); err != nil {
+		return fmt.Errorf("storing import dry-run result: %w", err)
+	}
+
+	conditions.Set(capiCluster, metav1.Condition{
+		Type:    turtlesv1.RancherImportDryRunCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  turtlesv1.ImportDryRunCompletedReason,
+		Message: fmt.Sprintf("%s, see secret %s", diff.Summary(), secret.Name),
+	})
+
+	log.Info("Stored import dry-run result", "secret", secret.Name, "summary", diff.Summary())
+
+	return nil
+}
+
+// reconcileReimport re-applies the Rancher agent on a CAPI cluster marked with the reimport annotation.
+// The ClusterRegistrationToken is deleted first, so Rancher generates a new one, then the fresh import manifest
+// is downloaded and applied on the workload cluster. The result of the attempt is stored in the
+// RancherClusterReimported condition, and the annotation is removed once the attempt is finished.
+func (r *CAPIImportReconciler) reconcileReimport(ctx context.Context, rancherClient client.Client,
+	capiCluster *clusterv1.Cluster,
+) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling CAPI cluster reimport")
+
+	rancherCluster, err := r.getRancherCluster(ctx, rancherClient, capiCluster)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	if rancherCluster == nil {
+		log.Info("Rancher cluster not found, removing imported annotation to allow a new import")
+
+		annotations := capiCluster.GetAnnotations()
+		delete(annotations, turtlesannotations.ClusterImportedAnnotation)
+		delete(annotations, turtlesannotations.ClusterReimportAnnotation)
+		capiCluster.SetAnnotations(annotations)
+
+		conditions.Set(capiCluster, metav1.Condition{
+			Type:    turtlesv1.RancherClusterReimportedCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  turtlesv1.ReimportRestartedReason,
+			Message: "Rancher cluster not found, the cluster will be imported again if it is marked for auto import",
+		})
+
+		return ctrl.Result{}, nil
+	}
+
+	if !rancherCluster.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.failReimport(capiCluster, errors.New("rancher cluster is being deleted"))
+	}
+
+	// In dry-run mode the registration token is kept and nothing is applied. The reimport annotation is kept as well,
+	// so the agent is re-applied once dry-run is disabled.
+	if r.ImportDryRun || turtlesannotations.IsClusterImportDryRun(capiCluster) {
+		return r.reconcileReimportDryRun(ctx, rancherClient, capiCluster, rancherCluster)
+	}
+
+	token := &managementv3.ClusterRegistrationToken{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      rancherCluster.Name,
+			Namespace: rancherCluster.Name,
+		},
+	}
+
+	if conditions.GetReason(capiCluster, turtlesv1.RancherClusterReimportedCondition) != turtlesv1.ReimportInProgressReason {
+		log.Info("Deleting cluster registration token to regenerate the import manifest")
+
+		if err := rancherClient.Delete(ctx, token); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, r.failReimport(capiCluster, fmt.Errorf("deleting cluster registration token: %w", err))
+		}
+
+		conditions.Set(capiCluster, metav1.Condition{
+			Type:    turtlesv1.RancherClusterReimportedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  turtlesv1.ReimportInProgressReason,
+			Message: "Cluster registration token is being regenerated",
+		})
+
+		return ctrl.Result{RequeueAfter: reimportRequeueDuration}, nil
+	}
+
+	if err := rancherClient.Get(ctx, client.ObjectKeyFromObject(token), token); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, r.failReimport(capiCluster, fmt.Errorf("getting cluster registration token: %w", err))
+	} else if err == nil && !token.DeletionTimestamp.IsZero() {
+		log.Info("Previous cluster registration token is still being deleted, requeue")
+		return ctrl.Result{RequeueAfter: reimportRequeueDuration}, nil
+	}
+
+	// The registration token was regenerated, so the manifest is always downloaded again.
+	manifest, err := r.downloadImportManifest(ctx, rancherClient, rancherCluster, nil)
+	if err != nil {
+		return ctrl.Result{}, r.failReimport(capiCluster, err)
+	}
+
 	if manifest == "" {
 		log.Info("Import manifest URL not set yet, requeue")
-		return ctrl.Result{RequeueAfter: defaultRequeueDuration}, nil
+		return ctrl.Result{RequeueAfter: reimportRequeueDuration}, nil
 	}
 
-	log.Info("Creating import manifest")
-
 	remoteClient, err := r.remoteClientGetter(ctx, capiCluster.Name, r.Client, client.ObjectKeyFromObject(capiCluster))
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("getting remote cluster client: %w", err)
+		return ctrl.Result{}, r.failReimport(capiCluster, fmt.Errorf("getting remote cluster client: %w", err))
+	}
+
+	if passed, err := r.reconcileReadinessGates(ctx, capiCluster, remoteClient); err != nil {
+		return ctrl.Result{}, err
+	} else if !passed {
+		return ctrl.Result{RequeueAfter: reimportRequeueDuration}, nil
 	}
 
 	if requeue, err := validateImportReadiness(ctx, remoteClient, strings.NewReader(manifest)); err != nil {
-		return ctrl.Result{}, fmt.Errorf("verifying import manifest: %w", err)
+		return ctrl.Result{}, r.failReimport(capiCluster, fmt.Errorf("verifying import manifest: %w", err))
 	} else if requeue {
 		log.Info("Import manifests are being deleted, not ready to be applied yet, requeue")
-		return ctrl.Result{RequeueAfter: defaultRequeueDuration}, nil
+		return ctrl.Result{RequeueAfter: reimportRequeueDuration}, nil
 	}
 
-	if err := createImportManifest(ctx, remoteClient, strings.NewReader(manifest)); err != nil {
-		return ctrl.Result{}, fmt.Errorf("creating import manifest: %w", err)
+	if delay := reserveImport(r.importLimiter); delay > 0 {
+		log.Info("Import rate limit reached, requeue", "after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
 	}
 
-	log.Info("Successfully applied import manifest")
+	patchBase := client.MergeFrom(rancherCluster.DeepCopy())
+
+	if err := r.applyImportManifest(ctx, remoteClient, rancherCluster, manifest); err != nil {
+		return ctrl.Result{}, r.failReimport(capiCluster, fmt.Errorf("applying import manifest: %w", err))
+	}
+
+	if err := rancherClient.Patch(ctx, rancherCluster, patchBase); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to patch Rancher cluster: %w", err)
+	}
+
+	conditions.Delete(capiCluster, turtlesv1.RancherImportDryRunCondition)
+
+	log.Info("Successfully re-applied import manifest")
+
+	conditions.Set(capiCluster, metav1.Condition{
+		Type:    turtlesv1.RancherClusterReimportedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  turtlesv1.ReimportSucceededReason,
+		Message: fmt.Sprintf("Import manifest for Rancher cluster %s was re-applied", rancherCluster.Name),
+	})
+
+	annotations := capiCluster.GetAnnotations()
+	delete(annotations, turtlesannotations.ClusterReimportAnnotation)
+	capiCluster.SetAnnotations(annotations)
 
 	return ctrl.Result{}, nil
 }
 
+// reconcileReimportDryRun stores the import manifest, which the reimport would apply with the current registration
+// token, and its diff against the workload cluster.
+func (r *CAPIImportReconciler) reconcileReimportDryRun(ctx context.Context, rancherClient client.Client,
+	capiCluster *clusterv1.Cluster, rancherCluster *managementv3.Cluster,
+) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	manifest, err := r.downloadImportManifest(ctx, rancherClient, rancherCluster, r.manifestCache)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if manifest == "" {
+		log.Info("Import manifest URL not set yet, requeue")
+		return ctrl.Result{RequeueAfter: reimportRequeueDuration}, nil
+	}
+
+	remoteClient, err := r.remoteClientGetter(ctx, capiCluster.Name, r.Client, client.ObjectKeyFromObject(capiCluster))
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("getting remote cluster client: %w", err)
+	}
+
+	return ctrl.Result{}, r.reconcileDryRun(ctx, capiCluster, remoteClient, manifest)
+}
+
+// downloadImportManifest returns the import manifest of the Rancher cluster, trusting the Rancher CA certificate
+// and the configured CA bundle. The manifest is empty when the manifest URL is not set yet.
+func (r *CAPIImportReconciler) downloadImportManifest(ctx context.Context, rancherClient client.Client,
+	rancherCluster *managementv3.Cluster, cache *manifestCache,
+) (string, error) {
+	// Get custom CAcert if agentTLSMode feature is enabled
+	caCert, err := getTrustedCAcert(ctx, rancherClient, feature.Gates.Enabled(feature.AgentTLSMode))
+	if err != nil {
+		return "", fmt.Errorf("error getting CA cert: %w", err)
+	}
+
+	caBundle, err := getCABundle(ctx, r.Client, r.CABundle)
+	if err != nil {
+		return "", err
+	}
+
+	return getClusterRegistrationManifest(ctx, rancherCluster.Name, rancherCluster.Name, rancherClient,
+		caCert, caBundle, r.InsecureSkipVerify, cache)
+}
+
+// reconcileReadinessGates evaluates the import readiness gates on the workload cluster and reports the result
+// in the ImportReadinessGates condition. It returns true when the import manifest can be applied.
+func (r *CAPIImportReconciler) reconcileReadinessGates(ctx context.Context, capiCluster *clusterv1.Cluster,
+	remoteClient client.Client,
+) (bool, error) {
+	log := log.FromContext(ctx)
+
+	if !r.ReadinessGates.IsSet() {
+		return true, nil
+	}
+
+	failures, err := checkReadinessGates(ctx, r.Client, remoteClient, r.ReadinessGates)
+	if err != nil {
+		conditions.Set(capiCluster, metav1.Condition{
+			Type:    turtlesv1.ImportReadinessGatesCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  turtlesv1.ReadinessGatesErrorReason,
+			Message: err.Error(),
+		})
+
+		return false, fmt.Errorf("checking import readiness gates: %w", err)
+	}
+
+	if len(failures) > 0 {
+		log.Info("Workload cluster is not ready for import, requeue", "failures", failures)
+
+		conditions.Set(capiCluster, metav1.Condition{
+			Type:    turtlesv1.ImportReadinessGatesCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  turtlesv1.ReadinessGatesFailedReason,
+			Message: strings.Join(failures, "; "),
+		})
+
+		return false, nil
+	}
+
+	conditions.Set(capiCluster, metav1.Condition{
+		Type:   turtlesv1.ImportReadinessGatesCondition,
+		Status: metav1.ConditionTrue,
+		Reason: turtlesv1.ReadinessGatesPassedReason,
+	})
+
+	return true, nil
+}
+
+// applyImportManifest server-side applies the import manifest on the workload cluster and records the applied
+// objects and the manifest hash in the inventory annotation of the Rancher cluster. When pruning is enabled,
+// objects from the previous inventory which are no longer part of the manifest are deleted from the workload cluster.
+// The caller is responsible for persisting the Rancher cluster changes.
+func (r *CAPIImportReconciler) applyImportManifest(ctx context.Context, remoteClient client.Client,
+	rancherCluster *managementv3.Cluster, manifest string,
+) error {
+	previous, err := getImportInventory(rancherCluster)
+	if err != nil {
+		return err
+	}
+
+	applied, err := applyImportManifest(ctx, remoteClient, strings.NewReader(manifest))
+	if err != nil {
+		return err
+	}
+
+	if r.ImportPrune {
+		if err := pruneImportManifest(ctx, remoteClient, previous.Objects, applied); err != nil {
+			return err
+		}
+	}
+
+	return setImportInventory(rancherCluster, importInventory{
+		ManifestHash: importManifestHash(manifest),
+		Objects:      applied,
+	})
+}
+
+// failReimport records a failed reimport attempt on the CAPI cluster and removes the reimport annotation,
+// so a new attempt has to be requested explicitly.
+func (r *CAPIImportReconciler) failReimport(capiCluster *clusterv1.Cluster, err error) error {
+	conditions.Set(capiCluster, metav1.Condition{
+		Type:    turtlesv1.RancherClusterReimportedCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  turtlesv1.ReimportFailedReason,
+		Message: err.Error(),
+	})
+
+	annotations := capiCluster.GetAnnotations()
+	delete(annotations, turtlesannotations.ClusterReimportAnnotation)
+	capiCluster.SetAnnotations(annotations)
+
+	return fmt.Errorf("reimporting cluster: %w", err)
+}
+
 func (r *CAPIImportReconciler) shouldAutoImportUncached(ctx context.Context, capiCluster *clusterv1.Cluster) (bool, error) {
 	log := log.FromContext(ctx)
 
@@ -471,6 +956,30 @@ func (r *CAPIImportReconciler) rancherV3ClusterToCapiCluster(ctx context.Context
 	}
 }
 
+// rancherClient returns the client for the Rancher Manager cluster.
+func (r *CAPIImportReconciler) rancherClient() client.Client {
+	if r.RancherClient != nil {
+		return r.RancherClient
+	}
+
+	return r.Client
+}
+
+// rancherClientFor returns the client for the Rancher Manager cluster, where the CAPI cluster is imported.
+// It is the client for the RancherTarget named by the cluster or namespace annotation, if set.
+func (r *CAPIImportReconciler) rancherClientFor(ctx context.Context, capiCluster *clusterv1.Cluster) (client.Client, error) {
+	target, err := rancherTargetName(ctx, r.Client, capiCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if target == "" {
+		return r.rancherClient(), nil
+	}
+
+	return r.RancherTargets.client(ctx, r.Client, target)
+}
+
 func (r *CAPIImportReconciler) reconcileDelete(ctx context.Context, capiCluster *clusterv1.Cluster) error {
 	log := log.FromContext(ctx)
 	log.Info("Reconciling rancher cluster deletion")
@@ -498,7 +1007,9 @@ func (r *CAPIImportReconciler) reconcileDelete(ctx context.Context, capiCluster
 	return nil
 }
 
-func (r *CAPIImportReconciler) deleteDependentRancherCluster(ctx context.Context, capiCluster *clusterv1.Cluster) error {
+func (r *CAPIImportReconciler) deleteDependentRancherCluster(ctx context.Context, rancherClient client.Client,
+	capiCluster *clusterv1.Cluster,
+) error {
 	log := log.FromContext(ctx)
 	log.Info("capi cluster is being deleted, deleting dependent rancher cluster")
 
@@ -510,7 +1021,43 @@ func (r *CAPIImportReconciler) deleteDependentRancherCluster(ctx context.Context
 		},
 	}
 
-	return client.IgnoreNotFound(r.Client.DeleteAllOf(ctx, &managementv3.Cluster{}, selectors...))
+	return client.IgnoreNotFound(rancherClient.DeleteAllOf(ctx, &managementv3.Cluster{}, selectors...))
+}
+
+// recordManagementCluster annotates the Rancher cluster with the management cluster importing it, and the CAPI cluster
+// with the name of the Rancher cluster. A Rancher cluster orphaned while its CAPI cluster was moved is adopted again.
+func (r *CAPIImportReconciler) recordManagementCluster(ctx context.Context, rancherCluster *managementv3.Cluster,
+	capiCluster *clusterv1.Cluster,
+) error {
+	id, err := clusterID(ctx, r.Client)
+	if err != nil {
+		return fmt.Errorf("error getting management cluster ID: %w", err)
+	}
+
+	annotations := rancherCluster.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[turtlesannotations.ManagementClusterAnnotation] = id
+	delete(annotations, turtlesannotations.OrphanedSinceAnnotation)
+	rancherCluster.SetAnnotations(annotations)
+
+	if rancherCluster.Name != "" {
+		recordRancherCluster(capiCluster, rancherCluster)
+	}
+
+	return nil
+}
+
+func recordRancherCluster(capiCluster *clusterv1.Cluster, rancherCluster *managementv3.Cluster) {
+	annotations := capiCluster.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[turtlesannotations.RancherClusterAnnotation] = rancherCluster.Name
+	capiCluster.SetAnnotations(annotations)
 }
 
 // optOutOfClusterOwner annotates the cluster with the opt-out annotation.
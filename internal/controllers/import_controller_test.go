@@ -27,6 +27,7 @@ import (
 	. "github.com/onsi/gomega"
 	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
 	provisioningv1 "github.com/rancher/turtles/api/rancher/provisioning/v1"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
 	"github.com/rancher/turtles/feature"
 	"github.com/rancher/turtles/internal/controllers/testdata"
 	"github.com/rancher/turtles/internal/test"
@@ -34,6 +35,7 @@ import (
 	turtlesannotations "github.com/rancher/turtles/util/annotations"
 	turtlesnaming "github.com/rancher/turtles/util/naming"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -461,6 +463,140 @@ var _ = Describe("reconcile CAPI Cluster", func() {
 		}, 10*time.Second).Should(Succeed())
 	})
 
+	It("should prune objects removed from the import manifest when pruning is enabled", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(sampleTemplate))
+		}))
+		defer server.Close()
+
+		r.ImportPrune = true
+
+		Expect(cl.Create(ctx, capiCluster)).To(Succeed())
+		setControlPlaneReady(capiCluster)
+		Expect(cl.Status().Update(ctx, capiCluster)).To(Succeed())
+
+		Expect(cl.Create(ctx, capiKubeconfigSecret)).To(Succeed())
+
+		stale := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "stale-agent-config",
+				Namespace: capiCluster.Namespace,
+			},
+		}
+		Expect(cl.Create(ctx, stale)).To(Succeed())
+
+		rancherCluster.Annotations = map[string]string{
+			turtlesannotations.ImportInventoryAnnotation: fmt.Sprintf(
+				`[{"apiVersion":"v1","kind":"ConfigMap","namespace":%q,"name":%q}]`, stale.Namespace, stale.Name),
+		}
+		Expect(cl.Create(ctx, rancherCluster)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			g.Expect(cl.List(ctx, rancherClusters, selectors...)).ToNot(HaveOccurred())
+			g.Expect(rancherClusters.Items).To(HaveLen(1))
+		}).Should(Succeed())
+		cluster := rancherClusters.Items[0]
+
+		clusterRegistrationToken.Name = cluster.Name
+		clusterRegistrationToken.Namespace = cluster.Name
+		_, err := testEnv.CreateNamespaceWithName(ctx, cluster.Name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cl.Create(ctx, clusterRegistrationToken)).To(Succeed())
+		token := clusterRegistrationToken.DeepCopy()
+		token.Status.ManifestURL = server.URL
+		Expect(cl.Status().Update(ctx, token)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			_, err := r.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: capiCluster.Namespace,
+					Name:      capiCluster.Name,
+				},
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			g.Expect(apierrors.IsNotFound(cl.Get(ctx, client.ObjectKeyFromObject(stale), stale))).To(BeTrue())
+
+			g.Expect(cl.List(ctx, rancherClusters, selectors...)).ToNot(HaveOccurred())
+			g.Expect(rancherClusters.Items).To(HaveLen(1))
+			g.Expect(rancherClusters.Items[0].Annotations).To(HaveKey(turtlesannotations.ImportInventoryAnnotation))
+			g.Expect(rancherClusters.Items[0].Annotations[turtlesannotations.ImportInventoryAnnotation]).ToNot(ContainSubstring(stale.Name))
+		}, 10*time.Second).Should(Succeed())
+	})
+
+	It("should re-apply the import manifest on a ready cluster when the manifest changed", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(sampleTemplate))
+		}))
+		defer server.Close()
+
+		Expect(cl.Create(ctx, capiCluster)).To(Succeed())
+		setControlPlaneReady(capiCluster)
+		Expect(cl.Status().Update(ctx, capiCluster)).To(Succeed())
+
+		Expect(cl.Create(ctx, capiKubeconfigSecret)).To(Succeed())
+
+		rancherCluster.Annotations[turtlesannotations.ImportInventoryAnnotation] = `{"manifestHash":"outdated","objects":[]}`
+		Expect(cl.Create(ctx, rancherCluster)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			g.Expect(cl.List(ctx, rancherClusters, selectors...)).ToNot(HaveOccurred())
+			g.Expect(rancherClusters.Items).To(HaveLen(1))
+		}).Should(Succeed())
+		cluster := rancherClusters.Items[0]
+
+		cluster.Status.Conditions = []metav1.Condition{
+			{
+				Type:               managementv3.ClusterConditionReady,
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: metav1.Now(),
+				Reason:             clusterv1.ReadyReason,
+				Message:            "Cluster is ready",
+			},
+		}
+		Expect(cl.Status().Update(ctx, &cluster)).To(Succeed())
+
+		clusterRegistrationToken.Name = cluster.Name
+		clusterRegistrationToken.Namespace = cluster.Name
+		_, err := testEnv.CreateNamespaceWithName(ctx, cluster.Name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cl.Create(ctx, clusterRegistrationToken)).To(Succeed())
+		token := clusterRegistrationToken.DeepCopy()
+		token.Status.ManifestURL = server.URL
+		Expect(cl.Status().Update(ctx, token)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			_, err := r.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: capiCluster.Namespace,
+					Name:      capiCluster.Name,
+				},
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			objs, err := manifestToObjects(strings.NewReader(sampleTemplate))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			for _, obj := range objs {
+				u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+				g.Expect(err).ToNot(HaveOccurred())
+
+				unstructuredObj := &unstructured.Unstructured{}
+				unstructuredObj.SetUnstructuredContent(u)
+				unstructuredObj.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+
+				g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(unstructuredObj), unstructuredObj)).To(Succeed())
+			}
+
+			g.Expect(cl.List(ctx, rancherClusters, selectors...)).ToNot(HaveOccurred())
+			g.Expect(rancherClusters.Items).To(HaveLen(1))
+			g.Expect(rancherClusters.Items[0].Annotations[turtlesannotations.ImportInventoryAnnotation]).To(
+				ContainSubstring(importManifestHash(sampleTemplate)))
+		}, 10*time.Second).Should(Succeed())
+	})
+
 	It("should reconcile a CAPI cluster when rancher cluster exists but cluster name not set", func() {
 		Expect(cl.Create(ctx, capiCluster)).To(Succeed())
 		setControlPlaneReady(capiCluster)
@@ -716,4 +852,223 @@ var _ = Describe("reconcile CAPI Cluster", func() {
 			g.Expect(rancherClusters.Items[0].Spec.Description).To(Equal(description))
 		}).Should(Succeed())
 	})
+
+	It("should regenerate the registration token and re-apply the manifest when reimport annotation is set", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(sampleTemplate))
+		}))
+		defer server.Close()
+
+		capiCluster.Annotations = map[string]string{
+			turtlesannotations.ClusterImportedAnnotation: "true",
+			turtlesannotations.ClusterReimportAnnotation: "",
+		}
+		Expect(cl.Create(ctx, capiCluster)).To(Succeed())
+		setControlPlaneReady(capiCluster)
+		Expect(cl.Status().Update(ctx, capiCluster)).To(Succeed())
+
+		Expect(cl.Create(ctx, capiKubeconfigSecret)).To(Succeed())
+
+		Expect(cl.Create(ctx, rancherCluster)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			g.Expect(cl.List(ctx, rancherClusters, selectors...)).ToNot(HaveOccurred())
+			g.Expect(rancherClusters.Items).To(HaveLen(1))
+		}).Should(Succeed())
+		cluster := rancherClusters.Items[0]
+
+		clusterRegistrationToken.Name = cluster.Name
+		clusterRegistrationToken.Namespace = cluster.Name
+		_, err := testEnv.CreateNamespaceWithName(ctx, cluster.Name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cl.Create(ctx, clusterRegistrationToken)).To(Succeed())
+		oldTokenUID := clusterRegistrationToken.UID
+
+		res, err := r.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: capiCluster.Namespace,
+				Name:      capiCluster.Name,
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(reimportRequeueDuration))
+
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(capiCluster), capiCluster)).To(Succeed())
+		Expect(conditions.GetReason(capiCluster, turtlesv1.RancherClusterReimportedCondition)).To(Equal(turtlesv1.ReimportInProgressReason))
+
+		// Rancher populates the manifest URL on the regenerated token
+		Eventually(func(g Gomega) {
+			_, err := r.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: capiCluster.Namespace,
+					Name:      capiCluster.Name,
+				},
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			token := clusterRegistrationToken.DeepCopy()
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(clusterRegistrationToken), token)).To(Succeed())
+			g.Expect(token.UID).ToNot(Equal(oldTokenUID))
+
+			token.Status.ManifestURL = server.URL
+			g.Expect(cl.Status().Update(ctx, token)).To(Succeed())
+		}).Should(Succeed())
+
+		Eventually(func(g Gomega) {
+			_, err := r.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: capiCluster.Namespace,
+					Name:      capiCluster.Name,
+				},
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(capiCluster), capiCluster)).To(Succeed())
+			g.Expect(capiCluster.Annotations).ToNot(HaveKey(turtlesannotations.ClusterReimportAnnotation))
+			g.Expect(capiCluster.Annotations).To(HaveKey(turtlesannotations.ClusterImportedAnnotation))
+			g.Expect(conditions.IsTrue(capiCluster, turtlesv1.RancherClusterReimportedCondition)).To(BeTrue())
+			g.Expect(conditions.GetReason(capiCluster, turtlesv1.RancherClusterReimportedCondition)).To(Equal(turtlesv1.ReimportSucceededReason))
+		}, 10*time.Second).Should(Succeed())
+	})
+
+	It("should hand the cluster back to the import flow when reimport is requested and rancher cluster is missing", func() {
+		capiCluster.Annotations = map[string]string{
+			turtlesannotations.ClusterImportedAnnotation: "true",
+			turtlesannotations.ClusterReimportAnnotation: "",
+		}
+		Expect(cl.Create(ctx, capiCluster)).To(Succeed())
+		setControlPlaneReady(capiCluster)
+		Expect(cl.Status().Update(ctx, capiCluster)).To(Succeed())
+
+		_, err := r.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: capiCluster.Namespace,
+				Name:      capiCluster.Name,
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(capiCluster), capiCluster)).To(Succeed())
+		Expect(capiCluster.Annotations).ToNot(HaveKey(turtlesannotations.ClusterReimportAnnotation))
+		Expect(capiCluster.Annotations).ToNot(HaveKey(turtlesannotations.ClusterImportedAnnotation))
+		Expect(conditions.GetReason(capiCluster, turtlesv1.RancherClusterReimportedCondition)).To(Equal(turtlesv1.ReimportRestartedReason))
+	})
+
+	It("should store the import manifest and diff in a secret without applying it when dry-run annotation is set", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(sampleTemplate))
+		}))
+		defer server.Close()
+
+		capiCluster.Annotations = map[string]string{
+			turtlesannotations.ClusterImportDryRunAnnotation: "true",
+		}
+		Expect(cl.Create(ctx, capiCluster)).To(Succeed())
+		setControlPlaneReady(capiCluster)
+		Expect(cl.Status().Update(ctx, capiCluster)).To(Succeed())
+
+		Expect(cl.Create(ctx, capiKubeconfigSecret)).To(Succeed())
+
+		Expect(cl.Create(ctx, rancherCluster)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			g.Expect(cl.List(ctx, rancherClusters, selectors...)).ToNot(HaveOccurred())
+			g.Expect(rancherClusters.Items).To(HaveLen(1))
+		}).Should(Succeed())
+		cluster := rancherClusters.Items[0]
+
+		clusterRegistrationToken.Name = cluster.Name
+		clusterRegistrationToken.Namespace = cluster.Name
+		_, err := testEnv.CreateNamespaceWithName(ctx, cluster.Name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cl.Create(ctx, clusterRegistrationToken)).To(Succeed())
+		token := clusterRegistrationToken.DeepCopy()
+		token.Status.ManifestURL = server.URL
+		Expect(cl.Status().Update(ctx, token)).To(Succeed())
+
+		_, err = r.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: capiCluster.Namespace,
+				Name:      capiCluster.Name,
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		secret := &corev1.Secret{}
+		Expect(cl.Get(ctx, client.ObjectKey{
+			Namespace: capiCluster.Namespace,
+			Name:      capiCluster.Name + importDryRunSecretSuffix,
+		}, secret)).To(Succeed())
+		Expect(string(secret.Data[importDryRunManifestKey])).To(Equal(sampleTemplate))
+		Expect(secret.Labels).To(HaveKeyWithValue(clusterv1.ClusterNameLabel, capiCluster.Name))
+
+		objs, err := manifestToObjects(strings.NewReader(sampleTemplate))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(strings.TrimSpace(string(secret.Data[importDryRunDiffKey])), "\n")).To(HaveLen(len(objs)))
+
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(capiCluster), capiCluster)).To(Succeed())
+		Expect(conditions.GetReason(capiCluster, turtlesv1.RancherImportDryRunCondition)).To(Equal(turtlesv1.ImportDryRunCompletedReason))
+		Expect(capiCluster.Annotations).ToNot(HaveKey(turtlesannotations.ClusterImportedAnnotation))
+	})
+
+	It("should keep the registration token and store the manifest in a secret when reimport is requested in dry-run", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(sampleTemplate))
+		}))
+		defer server.Close()
+
+		capiCluster.Annotations = map[string]string{
+			turtlesannotations.ClusterImportedAnnotation:     "true",
+			turtlesannotations.ClusterReimportAnnotation:     "",
+			turtlesannotations.ClusterImportDryRunAnnotation: "true",
+		}
+		Expect(cl.Create(ctx, capiCluster)).To(Succeed())
+		setControlPlaneReady(capiCluster)
+		Expect(cl.Status().Update(ctx, capiCluster)).To(Succeed())
+
+		Expect(cl.Create(ctx, capiKubeconfigSecret)).To(Succeed())
+
+		Expect(cl.Create(ctx, rancherCluster)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			g.Expect(cl.List(ctx, rancherClusters, selectors...)).ToNot(HaveOccurred())
+			g.Expect(rancherClusters.Items).To(HaveLen(1))
+		}).Should(Succeed())
+		cluster := rancherClusters.Items[0]
+
+		clusterRegistrationToken.Name = cluster.Name
+		clusterRegistrationToken.Namespace = cluster.Name
+		_, err := testEnv.CreateNamespaceWithName(ctx, cluster.Name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cl.Create(ctx, clusterRegistrationToken)).To(Succeed())
+		token := clusterRegistrationToken.DeepCopy()
+		token.Status.ManifestURL = server.URL
+		Expect(cl.Status().Update(ctx, token)).To(Succeed())
+
+		_, err = r.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: capiCluster.Namespace,
+				Name:      capiCluster.Name,
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(clusterRegistrationToken), token)).To(Succeed())
+		Expect(token.UID).To(Equal(clusterRegistrationToken.UID))
+
+		secret := &corev1.Secret{}
+		Expect(cl.Get(ctx, client.ObjectKey{
+			Namespace: capiCluster.Namespace,
+			Name:      capiCluster.Name + importDryRunSecretSuffix,
+		}, secret)).To(Succeed())
+		Expect(string(secret.Data[importDryRunManifestKey])).To(Equal(sampleTemplate))
+
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(capiCluster), capiCluster)).To(Succeed())
+		Expect(capiCluster.Annotations).To(HaveKey(turtlesannotations.ClusterReimportAnnotation))
+		Expect(conditions.GetReason(capiCluster, turtlesv1.RancherImportDryRunCondition)).To(Equal(turtlesv1.ImportDryRunCompletedReason))
+		Expect(conditions.Has(capiCluster, turtlesv1.RancherClusterReimportedCondition)).To(BeFalse())
+	})
 })
@@ -0,0 +1,217 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/priorityqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const importPriorityLabelName = "cluster-api.cattle.io/import-priority"
+
+// manifestCache keeps downloaded import manifests for a limited time, per registration URL and CA certificates,
+// so repeated reconciles of a cluster do not download the manifest from Rancher again.
+// Concurrent downloads of the same manifest are deduplicated.
+type manifestCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]manifestCacheEntry
+	group   singleflight.Group
+}
+
+type manifestCacheEntry struct {
+	manifest string
+	expires  time.Time
+}
+
+func newManifestCache(ttl time.Duration) *manifestCache {
+	return &manifestCache{
+		ttl:     ttl,
+		entries: map[string]manifestCacheEntry{},
+	}
+}
+
+// download returns the manifest from the cache, or downloads it if it is missing or expired.
+// A nil cache always downloads the manifest.
+func (c *manifestCache) download(url string, caCert, caBundle []byte, insecureSkipVerify bool) (string, error) {
+	if c == nil || c.ttl <= 0 {
+		return downloadManifest(url, caCert, caBundle, insecureSkipVerify)
+	}
+
+	key := manifestCacheKey(url, caCert, caBundle, insecureSkipVerify)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.manifest, nil
+	}
+
+	manifest, err, _ := c.group.Do(key, func() (any, error) {
+		manifest, err := downloadManifest(url, caCert, caBundle, insecureSkipVerify)
+		if err != nil {
+			return "", err
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.removeExpired()
+		c.entries[key] = manifestCacheEntry{
+			manifest: manifest,
+			expires:  time.Now().Add(c.ttl),
+		}
+
+		return manifest, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return manifest.(string), nil //nolint:forcetypeassert // the function above always returns a string
+}
+
+// removeExpired drops expired entries. The caller must hold the lock.
+func (c *manifestCache) removeExpired() {
+	now := time.Now()
+
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func manifestCacheKey(url string, caCert, caBundle []byte, insecureSkipVerify bool) string {
+	hash := sha256.New()
+	hash.Write([]byte(url))
+	hash.Write([]byte{0})
+	hash.Write(caCert)
+	hash.Write([]byte{0})
+	hash.Write(caBundle)
+	hash.Write([]byte{0})
+	hash.Write([]byte(strconv.FormatBool(insecureSkipVerify)))
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// reserveImport returns the duration to wait before the next import may be performed, according
+// to the global import rate limit. A zero duration means the import can proceed.
+func reserveImport(limiter *rate.Limiter) time.Duration {
+	if limiter == nil {
+		return 0
+	}
+
+	reservation := limiter.Reserve()
+	if !reservation.OK() {
+		return defaultRequeueDuration
+	}
+
+	delay := reservation.Delay()
+	if delay > 0 {
+		// Give the token back, the import is retried once the limiter allows it.
+		reservation.Cancel()
+	}
+
+	return delay
+}
+
+// importPriority returns the import priority of the cluster, read from the import priority label on
+// the cluster or its namespace. The cluster label takes precedence.
+func importPriority(ctx context.Context, cl client.Client, obj client.Object) *int {
+	if priority, ok := parsePriority(obj.GetLabels()[importPriorityLabelName]); ok {
+		return &priority
+	}
+
+	ns := &corev1.Namespace{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: obj.GetNamespace()}, ns); err != nil {
+		return nil
+	}
+
+	if priority, ok := parsePriority(ns.GetLabels()[importPriorityLabelName]); ok {
+		return &priority
+	}
+
+	return nil
+}
+
+func parsePriority(value string) (int, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	priority, err := strconv.Atoi(value)
+
+	return priority, err == nil
+}
+
+// enqueueWithImportPriority enqueues CAPI clusters with the priority set by the import priority label,
+// so clusters with a higher priority are imported first when many clusters are waiting for import.
+// Clusters without the label keep the low priority of controller-runtime for events of the initial list
+// and resyncs, so these do not compete with live updates.
+func enqueueWithImportPriority(cl client.Client) handler.EventHandler {
+	enqueue := func(ctx context.Context, obj client.Object, unchanged bool, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+		pq, ok := q.(priorityqueue.PriorityQueue[reconcile.Request])
+		if !ok {
+			q.Add(req)
+			return
+		}
+
+		priority := importPriority(ctx, cl, obj)
+		if priority == nil && unchanged {
+			priority = ptr.To(handler.LowPriority)
+		}
+
+		log.FromContext(ctx).V(4).Info("enqueueing cluster for import", "cluster", req.NamespacedName, "priority", ptr.Deref(priority, 0))
+
+		pq.AddWithOpts(priorityqueue.AddOpts{Priority: priority}, req)
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, e.Object, e.IsInInitialList, q)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, e.ObjectNew, e.ObjectOld.GetResourceVersion() == e.ObjectNew.GetResourceVersion(), q)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, e.Object, false, q)
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, e.Object, false, q)
+		},
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/priorityqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+)
+
+func TestManifestCache(t *testing.T) {
+	var downloads atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("manifest"))
+	}))
+	defer server.Close()
+
+	t.Run("should download the manifest once for the same URL", func(t *testing.T) {
+		g := NewWithT(t)
+		downloads.Store(0)
+
+		cache := newManifestCache(time.Minute)
+
+		for range 3 {
+			manifest, err := cache.download(server.URL, nil, nil, false)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(manifest).To(Equal("manifest"))
+		}
+
+		g.Expect(downloads.Load()).To(BeEquivalentTo(1))
+
+		_, err := cache.download(server.URL+"/other", nil, nil, false)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(downloads.Load()).To(BeEquivalentTo(2))
+	})
+
+	t.Run("should always download the manifest when the cache is disabled", func(t *testing.T) {
+		g := NewWithT(t)
+		downloads.Store(0)
+
+		var cache *manifestCache
+
+		for range 2 {
+			_, err := cache.download(server.URL, nil, nil, false)
+			g.Expect(err).NotTo(HaveOccurred())
+		}
+
+		g.Expect(downloads.Load()).To(BeEquivalentTo(2))
+	})
+}
+
+func TestReserveImport(t *testing.T) {
+	g := NewWithT(t)
+
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+
+	g.Expect(reserveImport(limiter)).To(BeZero())
+	g.Expect(reserveImport(limiter)).To(BeNumerically(">", 0))
+	g.Expect(reserveImport(nil)).To(BeZero())
+}
+
+func TestEnqueueWithImportPriority(t *testing.T) {
+	ctx := context.TODO()
+
+	cl := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+	}).Build()
+
+	cluster := func(labels map[string]string) *clusterv1.Cluster {
+		return &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default", Labels: labels}}
+	}
+
+	tests := []struct {
+		name     string
+		enqueue  func(handler.EventHandler, priorityqueue.PriorityQueue[reconcile.Request])
+		priority int
+	}{
+		{
+			name: "should keep the low priority for clusters of the initial list",
+			enqueue: func(h handler.EventHandler, q priorityqueue.PriorityQueue[reconcile.Request]) {
+				h.Create(ctx, event.CreateEvent{Object: cluster(nil), IsInInitialList: true}, q)
+			},
+			priority: handler.LowPriority,
+		},
+		{
+			name: "should use the default priority for new clusters",
+			enqueue: func(h handler.EventHandler, q priorityqueue.PriorityQueue[reconcile.Request]) {
+				h.Create(ctx, event.CreateEvent{Object: cluster(nil)}, q)
+			},
+			priority: 0,
+		},
+		{
+			name: "should use the import priority label for clusters of the initial list",
+			enqueue: func(h handler.EventHandler, q priorityqueue.PriorityQueue[reconcile.Request]) {
+				h.Create(ctx, event.CreateEvent{Object: cluster(map[string]string{importPriorityLabelName: "10"}), IsInInitialList: true}, q)
+			},
+			priority: 10,
+		},
+		{
+			name: "should keep the low priority for resyncs",
+			enqueue: func(h handler.EventHandler, q priorityqueue.PriorityQueue[reconcile.Request]) {
+				h.Update(ctx, event.UpdateEvent{ObjectOld: cluster(nil), ObjectNew: cluster(nil)}, q)
+			},
+			priority: handler.LowPriority,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			q := priorityqueue.New[reconcile.Request]("test")
+			defer q.ShutDown()
+
+			tt.enqueue(enqueueWithImportPriority(cl), q)
+
+			item, priority, _ := q.GetWithPriority()
+			g.Expect(item.Name).To(Equal("cluster"))
+			g.Expect(priority).To(Equal(tt.priority))
+		})
+	}
+}
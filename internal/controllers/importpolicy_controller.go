@@ -0,0 +1,84 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	"github.com/rancher/turtles/util"
+)
+
+// ImportPolicyReconciler reports in the Valid condition of ImportPolicies whether their selectors are valid.
+// Policies with an invalid selector are skipped when clusters are matched.
+type ImportPolicyReconciler struct {
+	Client client.Client
+}
+
+// SetupWithManager sets up reconciler with manager.
+func (r *ImportPolicyReconciler) SetupWithManager(_ context.Context, mgr ctrl.Manager, options controller.Options) error {
+	if err := ctrl.NewControllerManagedBy(mgr).
+		Named("importpolicy").
+		For(&turtlesv1.ImportPolicy{}).
+		WithOptions(options).
+		Complete(r); err != nil {
+		return fmt.Errorf("creating import policy controller: %w", err)
+	}
+
+	return nil
+}
+
+// +kubebuilder:rbac:groups=turtles-capi.cattle.io,resources=importpolicies/status,verbs=get;update;patch
+
+// Reconcile validates the selectors of the ImportPolicy and reports the result in its Valid condition.
+func (r *ImportPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	policy := &turtlesv1.ImportPolicy{}
+	if err := r.Client.Get(ctx, req.NamespacedName, policy); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	patchBase := client.MergeFrom(policy.DeepCopy())
+
+	if err := util.ValidateImportPolicy(policy); err != nil {
+		conditions.Set(policy, metav1.Condition{
+			Type:    turtlesv1.ImportPolicyValidCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  turtlesv1.ImportPolicyInvalidSelectorReason,
+			Message: fmt.Sprintf("policy is skipped when matching clusters: %s", err),
+		})
+	} else {
+		conditions.Set(policy, metav1.Condition{
+			Type:   turtlesv1.ImportPolicyValidCondition,
+			Status: metav1.ConditionTrue,
+			Reason: turtlesv1.ImportPolicyValidReason,
+		})
+	}
+
+	if err := r.Client.Status().Patch(ctx, policy, patchBase); err != nil {
+		return ctrl.Result{}, fmt.Errorf("updating import policy status: %w", err)
+	}
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+)
+
+var _ = Describe("ImportPolicyReconciler", func() {
+	var (
+		fakeClient client.Client
+		r          *ImportPolicyReconciler
+		policy     *turtlesv1.ImportPolicy
+	)
+
+	BeforeEach(func() {
+		policy = &turtlesv1.ImportPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "policy"},
+		}
+	})
+
+	reconcilePolicy := func() *turtlesv1.ImportPolicy {
+		fakeClient = fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(policy).
+			WithStatusSubresource(&turtlesv1.ImportPolicy{}).
+			Build()
+		r = &ImportPolicyReconciler{Client: fakeClient}
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+		Expect(err).NotTo(HaveOccurred())
+
+		result := &turtlesv1.ImportPolicy{}
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(policy), result)).To(Succeed())
+
+		return result
+	}
+
+	It("should report a valid policy", func() {
+		result := reconcilePolicy()
+		Expect(conditions.IsTrue(result, turtlesv1.ImportPolicyValidCondition)).To(BeTrue())
+	})
+
+	It("should report the invalid selector of a policy", func() {
+		policy.Spec.NamespaceSelector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: metav1.LabelSelectorOpExists, Values: []string{"prod"}}},
+		}
+
+		result := reconcilePolicy()
+		condition := conditions.Get(result, turtlesv1.ImportPolicyValidCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(turtlesv1.ImportPolicyInvalidSelectorReason))
+		Expect(condition.Message).To(ContainSubstring("spec.namespaceSelector"))
+	})
+})
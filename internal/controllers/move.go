@@ -0,0 +1,84 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+// relinkRancherCluster returns the Rancher cluster recorded on the CAPI cluster, when it is no longer found by
+// the owner labels, i.e. after the CAPI cluster was moved between management clusters. The Rancher cluster is
+// labeled with the CAPI cluster as the owner again, instead of importing the cluster as a duplicate.
+// Only Rancher clusters created by Turtles are linked.
+func relinkRancherCluster(ctx context.Context, rancherClient client.Client,
+	capiCluster *clusterv1.Cluster,
+) (*managementv3.Cluster, error) {
+	name := capiCluster.GetAnnotations()[turtlesannotations.RancherClusterAnnotation]
+	if name == "" {
+		return nil, nil //nolint:nilnil // the CAPI cluster was not imported before
+	}
+
+	rancherCluster := &managementv3.Cluster{}
+	if err := rancherClient.Get(ctx, client.ObjectKey{Namespace: capiCluster.Namespace, Name: name}, rancherCluster); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	if _, owned := rancherCluster.GetLabels()[ownedLabelName]; !owned || !rancherCluster.DeletionTimestamp.IsZero() {
+		return nil, nil //nolint:nilnil // the Rancher cluster can't be linked
+	}
+
+	log.FromContext(ctx).Info("Linking CAPI cluster to the existing Rancher cluster", "rancherCluster", name)
+
+	patchBase := client.MergeFromWithOptions(rancherCluster.DeepCopy(), client.MergeFromWithOptimisticLock{})
+
+	labels := rancherCluster.GetLabels()
+	labels[capiClusterOwner] = capiCluster.Name
+	labels[capiClusterOwnerNamespace] = capiCluster.Namespace
+	rancherCluster.SetLabels(labels)
+
+	if err := rancherClient.Patch(ctx, rancherCluster, patchBase); err != nil {
+		return nil, fmt.Errorf("linking Rancher cluster %s: %w", name, err)
+	}
+
+	return rancherCluster, nil
+}
+
+// managedByOtherCluster returns true if the Rancher cluster was imported by another CAPI management cluster,
+// i.e. after its CAPI cluster was moved. Rancher clusters without the management cluster annotation are
+// considered to be managed by this cluster.
+func managedByOtherCluster(ctx context.Context, cl client.Client, rancherCluster *managementv3.Cluster) (bool, error) {
+	managementCluster := rancherCluster.GetAnnotations()[turtlesannotations.ManagementClusterAnnotation]
+	if managementCluster == "" {
+		return false, nil
+	}
+
+	id, err := clusterID(ctx, cl)
+	if err != nil {
+		return false, err
+	}
+
+	return managementCluster != id, nil
+}
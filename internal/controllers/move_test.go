@@ -0,0 +1,122 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+var _ = Describe("Moved clusters", func() {
+	var (
+		ctx            context.Context
+		moveScheme     *runtime.Scheme
+		capiCluster    *clusterv1.Cluster
+		rancherCluster *managementv3.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		moveScheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(moveScheme)).To(Succeed())
+		Expect(clusterv1.AddToScheme(moveScheme)).To(Succeed())
+		Expect(managementv3.AddToScheme(moveScheme)).To(Succeed())
+
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "moved",
+				Namespace: "target",
+				Annotations: map[string]string{
+					turtlesannotations.RancherClusterAnnotation: "c-moved",
+				},
+			},
+		}
+		rancherCluster = &managementv3.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "c-moved",
+				Namespace: "target",
+				Labels: map[string]string{
+					capiClusterOwner:          "moved",
+					capiClusterOwnerNamespace: "source",
+					ownedLabelName:            "",
+				},
+			},
+		}
+	})
+
+	It("should link the recorded Rancher cluster to the CAPI cluster", func() {
+		rancherClient := fake.NewClientBuilder().WithScheme(moveScheme).WithObjects(rancherCluster).Build()
+
+		linked, err := relinkRancherCluster(ctx, rancherClient, capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(linked).NotTo(BeNil())
+
+		Expect(rancherClient.Get(ctx, client.ObjectKeyFromObject(rancherCluster), rancherCluster)).To(Succeed())
+		Expect(rancherCluster.Labels).To(HaveKeyWithValue(capiClusterOwnerNamespace, "target"))
+	})
+
+	It("should not link a Rancher cluster which was not created by Turtles", func() {
+		delete(rancherCluster.Labels, ownedLabelName)
+		rancherClient := fake.NewClientBuilder().WithScheme(moveScheme).WithObjects(rancherCluster).Build()
+
+		linked, err := relinkRancherCluster(ctx, rancherClient, capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(linked).To(BeNil())
+	})
+
+	It("should not link when no Rancher cluster is recorded", func() {
+		capiCluster.Annotations = nil
+		rancherClient := fake.NewClientBuilder().WithScheme(moveScheme).WithObjects(rancherCluster).Build()
+
+		linked, err := relinkRancherCluster(ctx, rancherClient, capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(linked).To(BeNil())
+	})
+
+	It("should detect Rancher clusters managed by another management cluster", func() {
+		kubeSystem := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceSystem, UID: types.UID("source")}}
+		cl := fake.NewClientBuilder().WithScheme(moveScheme).WithObjects(kubeSystem).Build()
+
+		other, err := managedByOtherCluster(ctx, cl, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).To(BeFalse())
+
+		rancherCluster.Annotations = map[string]string{turtlesannotations.ManagementClusterAnnotation: "source"}
+		other, err = managedByOtherCluster(ctx, cl, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).To(BeFalse())
+
+		rancherCluster.Annotations[turtlesannotations.ManagementClusterAnnotation] = "target"
+		other, err = managedByOtherCluster(ctx, cl, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).To(BeTrue())
+	})
+})
@@ -55,12 +55,19 @@ const (
 )
 
 // OperatorReconciler is a mapping wrapper for CAPIProvider -> operator provider resources.
-type OperatorReconciler struct{}
+type OperatorReconciler struct {
+	// PostProcessors alter provider manifests, after the built-in post processors.
+	PostProcessors provider.PostProcessorChain
+	// ManifestPatchesConfigMap references a ConfigMap with patches applied to the manifest of every provider.
+	ManifestPatchesConfigMap client.ObjectKey
+}
 
 // SetupWithManager is a mapping wrapper for CAPIProvider -> operator provider resources.
 func (r *OperatorReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options ctr.Options) error {
 	if err := (&CAPIProviderReconciler{
-		Client: mgr.GetClient(),
+		Client:                   mgr.GetClient(),
+		PostProcessors:           r.PostProcessors,
+		ManifestPatchesConfigMap: r.ManifestPatchesConfigMap,
 		GenericProviderReconciler: controller.GenericProviderReconciler{
 			Provider:     &turtlesv1.CAPIProvider{},
 			ProviderList: &turtlesv1.CAPIProviderList{},
@@ -96,6 +103,13 @@ func (r *OperatorReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Mana
 type CAPIProviderReconciler struct {
 	controller.GenericProviderReconciler
 	client.Client
+
+	// PostProcessors alter provider manifests, after the built-in post processors.
+	PostProcessors provider.PostProcessorChain
+	// ManifestPatchesConfigMap references a ConfigMap with patches applied to the manifest of every provider.
+	ManifestPatchesConfigMap client.ObjectKey
+
+	manifestPatches *provider.ManifestPatches
 }
 
 // BuildWithManager builds the CAPIProviderReconciler.
@@ -121,12 +135,27 @@ func (r *CAPIProviderReconciler) BuildWithManager(ctx context.Context, mgr ctrl.
 		handler.EnqueueRequestsFromMapFunc(newCoreProviderToProviderFuncMapForProviderList(mgr.GetClient())),
 	)
 
-	customAlterFuncs := []repository.ComponentsAlterFn{}
+	r.manifestPatches = &provider.ManifestPatches{
+		Client:    r.Client,
+		ConfigMap: r.ManifestPatchesConfigMap,
+	}
 
-	customAlterFuncs = append(customAlterFuncs, provider.AddClusterIndexedLabelFn)
+	postProcessors := provider.PostProcessorChain{
+		provider.FromComponentsAlterFn("cluster-indexed-label", provider.AddClusterIndexedLabelFn),
+	}
 
 	if feature.Gates.Enabled(feature.NoCertManager) {
-		customAlterFuncs = append(customAlterFuncs, provider.WranglerPatcher)
+		postProcessors = append(postProcessors, provider.FromComponentsAlterFn("wrangler", provider.WranglerPatcher))
+	}
+
+	postProcessors = append(postProcessors, r.PostProcessors...)
+	postProcessors = append(postProcessors, r.manifestPatches)
+
+	customAlterFuncs := []repository.ComponentsAlterFn{
+		postProcessors.ComponentsAlterFn(ctx, func() *turtlesv1.CAPIProvider {
+			capiProvider, _ := r.Provider.(*turtlesv1.CAPIProvider)
+			return capiProvider
+		}),
 	}
 
 	rec := controller.NewPhaseReconciler(
@@ -142,6 +171,7 @@ func (r *CAPIProviderReconciler) BuildWithManager(ctx context.Context, mgr ctrl.
 		r.waitForClusterctlConfigUpdate,
 		r.setProviderSpec,
 		r.syncSecrets,
+		r.syncManifestPatches,
 	}
 
 	if feature.Gates.Enabled(feature.NoCertManager) {
@@ -151,6 +181,7 @@ func (r *CAPIProviderReconciler) BuildWithManager(ctx context.Context, mgr ctrl.
 	}
 
 	r.ReconcilePhases = append(r.ReconcilePhases, []controller.PhaseFn{
+		r.checkWebhookCertificates,
 		rec.ApplyFromCache,
 		rec.PreflightChecks,
 		rec.InitializePhaseReconciler,
@@ -167,6 +198,7 @@ func (r *CAPIProviderReconciler) BuildWithManager(ctx context.Context, mgr ctrl.
 
 	r.DeletePhases = []controller.PhaseFn{
 		r.waitForClusterctlConfigUpdate,
+		r.deleteWebhookCertificateMetrics,
 		rec.Delete,
 	}
 
@@ -321,6 +353,30 @@ func (r *CAPIProviderReconciler) cleanupWranglerResources(ctx context.Context) (
 	return &controller.Result{}, nil
 }
 
+func (r *CAPIProviderReconciler) syncManifestPatches(ctx context.Context) (*controller.Result, error) {
+	if capiProvider, ok := r.Provider.(*turtlesv1.CAPIProvider); ok {
+		return &controller.Result{}, r.manifestPatches.Sync(ctx, capiProvider)
+	}
+
+	return &controller.Result{}, nil
+}
+
+func (r *CAPIProviderReconciler) checkWebhookCertificates(ctx context.Context) (*controller.Result, error) {
+	if capiProvider, ok := r.Provider.(*turtlesv1.CAPIProvider); ok {
+		return provider.CheckWebhookCertificates(ctx, r.Client, capiProvider)
+	}
+
+	return &controller.Result{}, nil
+}
+
+func (r *CAPIProviderReconciler) deleteWebhookCertificateMetrics(_ context.Context) (*controller.Result, error) {
+	if capiProvider, ok := r.Provider.(*turtlesv1.CAPIProvider); ok {
+		provider.DeleteWebhookCertificateMetrics(capiProvider)
+	}
+
+	return &controller.Result{}, nil
+}
+
 func getProvider(provider operatorv1.GenericProvider) clusterctlv1.Provider {
 	clusterctlProvider := &clusterctlv1.Provider{}
 	if p, ok := provider.(*turtlesv1.CAPIProvider); ok {
@@ -0,0 +1,269 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+// RancherTargetWatcher is called when the cache of a RancherTarget is started, to watch its Rancher clusters.
+// The context is canceled when the target is stopped.
+type RancherTargetWatcher func(ctx context.Context, name string, target cluster.Cluster) error
+
+// RancherTargets keeps a client and a cache for each Rancher Manager cluster referenced by a RancherTarget.
+// A target is started when its client is first requested, and the registered watchers are notified.
+// It is started again when the target or its kubeconfig Secret changes, and stopped when it is evicted.
+// RancherTargets runs with the manager, and stops all targets when the manager stops.
+type RancherTargets struct {
+	scheme     *runtime.Scheme
+	newCluster func(kubeconfig []byte, scheme *runtime.Scheme) (cluster.Cluster, error)
+
+	mu       sync.Mutex
+	ctx      context.Context
+	targets  map[string]*rancherTarget
+	watchers []RancherTargetWatcher
+}
+
+type rancherTarget struct {
+	cluster cluster.Cluster
+	version string
+	cancel  context.CancelFunc
+}
+
+// NewRancherTargets creates the RancherTargets, which must be added to the manager.
+func NewRancherTargets(scheme *runtime.Scheme) *RancherTargets {
+	return &RancherTargets{
+		scheme:     scheme,
+		newCluster: newKubeconfigCluster,
+		targets:    map[string]*rancherTarget{},
+	}
+}
+
+// Start records the context the targets are started with, and stops the targets when it is done.
+func (t *RancherTargets) Start(ctx context.Context) error {
+	t.mu.Lock()
+	t.ctx = ctx
+	t.mu.Unlock()
+
+	<-ctx.Done()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for name, target := range t.targets {
+		target.cancel()
+		delete(t.targets, name)
+	}
+
+	return nil
+}
+
+// Watch registers a watcher, called for every target started from now on.
+func (t *RancherTargets) Watch(watcher RancherTargetWatcher) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.watchers = append(t.watchers, watcher)
+}
+
+// client returns the client for the named RancherTarget. The kubeconfig Secret is read on each call,
+// and the target is reused as long as neither the target nor the Secret changed.
+func (t *RancherTargets) client(ctx context.Context, cl client.Reader, name string) (client.Client, error) {
+	target := &turtlesv1.RancherTarget{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: name}, target); err != nil {
+		return nil, fmt.Errorf("getting Rancher target %s: %w", name, err)
+	}
+
+	ref := target.Spec.KubeconfigSecretRef
+
+	secret := &corev1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret); err != nil {
+		return nil, fmt.Errorf("getting kubeconfig secret for Rancher target %s: %w", name, err)
+	}
+
+	version := fmt.Sprintf("%d/%s", target.Generation, secret.ResourceVersion)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ctx == nil {
+		return nil, errors.New("the Rancher targets are not started yet")
+	}
+
+	if cached, ok := t.targets[name]; ok {
+		if cached.version == version {
+			return cached.cluster.GetClient(), nil
+		}
+
+		cached.cancel()
+		delete(t.targets, name)
+	}
+
+	kubeconfig, ok := secret.Data[target.KubeconfigKey()]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig secret for Rancher target %s has no key %s", name, target.KubeconfigKey())
+	}
+
+	targetCluster, err := t.newCluster(kubeconfig, t.scheme)
+	if err != nil {
+		return nil, fmt.Errorf("creating client for Rancher target %s: %w", name, err)
+	}
+
+	if err := t.start(ctx, name, targetCluster, version); err != nil {
+		return nil, err
+	}
+
+	return targetCluster.GetClient(), nil
+}
+
+// start starts the cache of the target and notifies the watchers. It must be called with the lock held.
+func (t *RancherTargets) start(ctx context.Context, name string, targetCluster cluster.Cluster, version string) error {
+	log := log.FromContext(t.ctx).WithValues("rancherTarget", name)
+
+	targetCtx, cancel := context.WithCancel(ctrl.LoggerInto(t.ctx, log))
+
+	go func() {
+		if err := targetCluster.Start(targetCtx); err != nil {
+			log.Error(err, "Rancher target cache stopped with error")
+		}
+	}()
+
+	if !targetCluster.GetCache().WaitForCacheSync(ctx) {
+		cancel()
+
+		return fmt.Errorf("starting cache for Rancher target %s", name)
+	}
+
+	for _, watch := range t.watchers {
+		if err := watch(targetCtx, name, targetCluster); err != nil {
+			cancel()
+
+			return fmt.Errorf("watching Rancher target %s: %w", name, err)
+		}
+	}
+
+	log.Info("Started Rancher target")
+
+	t.targets[name] = &rancherTarget{
+		cluster: targetCluster,
+		version: version,
+		cancel:  cancel,
+	}
+
+	return nil
+}
+
+// evict stops the named target, if it is running.
+func (t *RancherTargets) evict(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if target, ok := t.targets[name]; ok {
+		target.cancel()
+		delete(t.targets, name)
+	}
+}
+
+// RancherTargetReconciler starts the targets for existing RancherTargets, so their Rancher clusters are watched
+// and cleaned up even before a CAPI cluster is imported, and stops them when the RancherTarget is deleted.
+type RancherTargetReconciler struct {
+	Client  client.Client
+	Targets *RancherTargets
+}
+
+// SetupWithManager sets up reconciler with manager.
+func (r *RancherTargetReconciler) SetupWithManager(_ context.Context, mgr ctrl.Manager, options controller.Options) error {
+	if err := ctrl.NewControllerManagedBy(mgr).
+		Named("ranchertarget").
+		For(&turtlesv1.RancherTarget{}).
+		WithOptions(options).
+		Complete(r); err != nil {
+		return fmt.Errorf("creating Rancher target controller: %w", err)
+	}
+
+	return nil
+}
+
+// +kubebuilder:rbac:groups=turtles-capi.cattle.io,resources=ranchertargets,verbs=get;list;watch
+
+// Reconcile starts the target of the RancherTarget, or starts it again if the target changed,
+// and stops it once the RancherTarget is deleted.
+func (r *RancherTargetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	target := &turtlesv1.RancherTarget{}
+	if err := r.Client.Get(ctx, req.NamespacedName, target); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	} else if err != nil || !target.DeletionTimestamp.IsZero() {
+		r.Targets.evict(req.Name)
+
+		return ctrl.Result{}, nil
+	}
+
+	if _, err := r.Targets.client(ctx, r.Client, target.Name); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// rancherTargetName returns the name of the RancherTarget set by the annotation on the object, or on its namespace.
+// An empty name means the object belongs to the default Rancher Manager cluster.
+func rancherTargetName(ctx context.Context, cl client.Client, obj client.Object) (string, error) {
+	if target := obj.GetAnnotations()[turtlesannotations.RancherTargetAnnotation]; target != "" {
+		return target, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: obj.GetNamespace()}, ns); err != nil {
+		return "", fmt.Errorf("getting namespace %s: %w", obj.GetNamespace(), err)
+	}
+
+	return ns.GetAnnotations()[turtlesannotations.RancherTargetAnnotation], nil
+}
+
+// newKubeconfigCluster creates the client and cache for a Rancher Manager cluster from a kubeconfig.
+// Like for the Rancher Manager cluster set with `--rancher-kubeconfig`, Secrets and ConfigMaps are not cached.
+func newKubeconfigCluster(kubeconfig []byte, scheme *runtime.Scheme) (cluster.Cluster, error) {
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig: %w", err)
+	}
+
+	return cluster.New(cfg, func(o *cluster.Options) {
+		o.Scheme = scheme
+		o.Client.Cache = &client.CacheOptions{
+			DisableFor: []client.Object{
+				&corev1.ConfigMap{},
+				&corev1.Secret{},
+			},
+		}
+	})
+}
@@ -0,0 +1,293 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+// fakeTargetCluster is a Rancher target cluster, which runs until its context is canceled.
+type fakeTargetCluster struct {
+	cluster.Cluster
+
+	client  client.Client
+	stopped chan struct{}
+}
+
+func (c *fakeTargetCluster) GetClient() client.Client { return c.client }
+
+func (c *fakeTargetCluster) GetCache() cache.Cache { return &informertest.FakeInformers{} }
+
+func (c *fakeTargetCluster) Start(ctx context.Context) error {
+	<-ctx.Done()
+	close(c.stopped)
+
+	return nil
+}
+
+func (c *fakeTargetCluster) isStopped() bool {
+	select {
+	case <-c.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+func newRancherTargetsScheme(g Gomega) *runtime.Scheme {
+	targetsScheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(targetsScheme)).To(Succeed())
+	g.Expect(clusterv1.AddToScheme(targetsScheme)).To(Succeed())
+	g.Expect(turtlesv1.AddToScheme(targetsScheme)).To(Succeed())
+
+	return targetsScheme
+}
+
+// newTestRancherTargets returns started RancherTargets, which create fake target clusters.
+func newTestRancherTargets(t *testing.T, g Gomega, targetsScheme *runtime.Scheme) (*RancherTargets, *[]*fakeTargetCluster) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	t.Cleanup(cancel)
+
+	created := &[]*fakeTargetCluster{}
+	targets := NewRancherTargets(targetsScheme)
+	targets.newCluster = func(_ []byte, scheme *runtime.Scheme) (cluster.Cluster, error) {
+		c := &fakeTargetCluster{
+			client:  fake.NewClientBuilder().WithScheme(scheme).Build(),
+			stopped: make(chan struct{}),
+		}
+		*created = append(*created, c)
+
+		return c, nil
+	}
+
+	go func() { _ = targets.Start(ctx) }()
+
+	g.Eventually(func() bool {
+		targets.mu.Lock()
+		defer targets.mu.Unlock()
+
+		return targets.ctx != nil
+	}).Should(BeTrue())
+
+	return targets, created
+}
+
+func newRancherTarget() (*turtlesv1.RancherTarget, *corev1.Secret) {
+	target := &turtlesv1.RancherTarget{
+		ObjectMeta: metav1.ObjectMeta{Name: "eu"},
+		Spec: turtlesv1.RancherTargetSpec{
+			KubeconfigSecretRef: turtlesv1.KubeconfigSecretReference{Name: "eu-kubeconfig", Namespace: "default"},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "eu-kubeconfig", Namespace: "default"},
+		Data:       map[string][]byte{turtlesv1.RancherTargetKubeconfigKey: []byte("kubeconfig")},
+	}
+
+	return target, secret
+}
+
+func TestRancherTargetName(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	targetsScheme := newRancherTargetsScheme(g)
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "default",
+		Annotations: map[string]string{turtlesannotations.RancherTargetAnnotation: "us"},
+	}}
+	cl := fake.NewClientBuilder().WithScheme(targetsScheme).WithObjects(namespace).Build()
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		namespace   string
+		want        string
+	}{
+		{
+			name:      "should resolve the target from the namespace annotation",
+			namespace: "default",
+			want:      "us",
+		},
+		{
+			name:        "should prefer the cluster annotation over the namespace annotation",
+			annotations: map[string]string{turtlesannotations.RancherTargetAnnotation: "eu"},
+			namespace:   "default",
+			want:        "eu",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			capiCluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{
+				Name:        "cluster",
+				Namespace:   tt.namespace,
+				Annotations: tt.annotations,
+			}}
+
+			name, err := rancherTargetName(ctx, cl, capiCluster)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(name).To(Equal(tt.want))
+		})
+	}
+
+	t.Run("should return no target when neither the cluster nor the namespace is annotated", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cl := fake.NewClientBuilder().WithScheme(targetsScheme).
+			WithObjects(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}).
+			Build()
+		capiCluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"}}
+
+		name, err := rancherTargetName(ctx, cl, capiCluster)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(name).To(BeEmpty())
+	})
+}
+
+func TestRancherTargetsClient(t *testing.T) {
+	t.Run("should start the target once and notify the watchers", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx := context.TODO()
+		targetsScheme := newRancherTargetsScheme(g)
+		targets, created := newTestRancherTargets(t, g, targetsScheme)
+
+		watched := []string{}
+		targets.Watch(func(_ context.Context, name string, _ cluster.Cluster) error {
+			watched = append(watched, name)
+			return nil
+		})
+
+		target, secret := newRancherTarget()
+		cl := fake.NewClientBuilder().WithScheme(targetsScheme).WithObjects(target, secret).Build()
+
+		first, err := targets.client(ctx, cl, "eu")
+		g.Expect(err).NotTo(HaveOccurred())
+
+		second, err := targets.client(ctx, cl, "eu")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(second).To(BeIdenticalTo(first))
+		g.Expect(*created).To(HaveLen(1))
+		g.Expect(watched).To(Equal([]string{"eu"}))
+	})
+
+	t.Run("should start the target again when the kubeconfig secret changes", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx := context.TODO()
+		targetsScheme := newRancherTargetsScheme(g)
+		targets, created := newTestRancherTargets(t, g, targetsScheme)
+
+		target, secret := newRancherTarget()
+		cl := fake.NewClientBuilder().WithScheme(targetsScheme).WithObjects(target, secret).Build()
+
+		first, err := targets.client(ctx, cl, "eu")
+		g.Expect(err).NotTo(HaveOccurred())
+
+		secret.Data[turtlesv1.RancherTargetKubeconfigKey] = []byte("rotated")
+		g.Expect(cl.Update(ctx, secret)).To(Succeed())
+
+		second, err := targets.client(ctx, cl, "eu")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(second).NotTo(BeIdenticalTo(first))
+		g.Expect(*created).To(HaveLen(2))
+		g.Eventually((*created)[0].isStopped, time.Second).Should(BeTrue())
+		g.Expect((*created)[1].isStopped()).To(BeFalse())
+	})
+
+	t.Run("should fail before the targets are started", func(t *testing.T) {
+		g := NewWithT(t)
+		targetsScheme := newRancherTargetsScheme(g)
+
+		target, secret := newRancherTarget()
+		cl := fake.NewClientBuilder().WithScheme(targetsScheme).WithObjects(target, secret).Build()
+
+		_, err := NewRancherTargets(targetsScheme).client(context.TODO(), cl, "eu")
+		g.Expect(err).To(MatchError(ContainSubstring("not started")))
+	})
+
+	t.Run("should fail when the target does not exist", func(t *testing.T) {
+		g := NewWithT(t)
+		targetsScheme := newRancherTargetsScheme(g)
+		targets, _ := newTestRancherTargets(t, g, targetsScheme)
+
+		cl := fake.NewClientBuilder().WithScheme(targetsScheme).Build()
+
+		_, err := targets.client(context.TODO(), cl, "eu")
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("should fail when the kubeconfig key is missing", func(t *testing.T) {
+		g := NewWithT(t)
+		targetsScheme := newRancherTargetsScheme(g)
+		targets, created := newTestRancherTargets(t, g, targetsScheme)
+
+		target, secret := newRancherTarget()
+		target.Spec.KubeconfigSecretRef.Key = "kubeconfig"
+		cl := fake.NewClientBuilder().WithScheme(targetsScheme).WithObjects(target, secret).Build()
+
+		_, err := targets.client(context.TODO(), cl, "eu")
+		g.Expect(err).To(MatchError(ContainSubstring("has no key kubeconfig")))
+		g.Expect(*created).To(BeEmpty())
+	})
+}
+
+func TestRancherTargetReconciler(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	targetsScheme := newRancherTargetsScheme(g)
+	targets, created := newTestRancherTargets(t, g, targetsScheme)
+
+	target, secret := newRancherTarget()
+	cl := fake.NewClientBuilder().WithScheme(targetsScheme).WithObjects(target, secret).Build()
+	r := &RancherTargetReconciler{Client: cl, Targets: targets}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(target)}
+
+	_, err := r.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*created).To(HaveLen(1))
+	g.Expect(targets.targets).To(HaveKey("eu"))
+
+	g.Expect(cl.Delete(ctx, target)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(targets.targets).NotTo(HaveKey("eu"))
+	g.Eventually((*created)[0].isStopped, time.Second).Should(BeTrue())
+}
@@ -0,0 +1,242 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+const coreDNSLabelSelector = "kube-dns"
+
+// ImportReadinessGates defines the checks performed on the workload cluster before the import manifest is applied.
+type ImportReadinessGates struct {
+	// MinReadyNodes is the minimum number of Ready nodes in the workload cluster.
+	MinReadyNodes int
+	// RequireCoreDNS requires an available CoreDNS deployment in the workload cluster.
+	RequireCoreDNS bool
+	// ChecksConfigMap references a ConfigMap with user defined checks. Each key of the ConfigMap holds
+	// a readinessCheck in the YAML format.
+	ChecksConfigMap client.ObjectKey
+}
+
+// readinessCheck is a user defined check, evaluating a CEL expression on objects in the workload cluster.
+// When Name is set, the expression receives the object as `object`, otherwise all objects of the kind
+// in the namespace are passed as `objects`.
+type readinessCheck struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Expression string `json:"expression"`
+}
+
+// IsSet returns true if any readiness gate is configured.
+func (g ImportReadinessGates) IsSet() bool {
+	return g.MinReadyNodes > 0 || g.RequireCoreDNS || g.ChecksConfigMap.Name != ""
+}
+
+// checkReadinessGates evaluates the readiness gates on the workload cluster and returns a list of failed gates.
+// The user defined checks are read on each call, so changes are picked up without restarting the controller.
+func checkReadinessGates(ctx context.Context, cl, remoteClient client.Client, gates ImportReadinessGates) ([]string, error) {
+	failures := []string{}
+
+	if gates.MinReadyNodes > 0 {
+		ready, err := countReadyNodes(ctx, remoteClient)
+		if err != nil {
+			return nil, err
+		}
+
+		if ready < gates.MinReadyNodes {
+			failures = append(failures, fmt.Sprintf("%d of required %d nodes are ready", ready, gates.MinReadyNodes))
+		}
+	}
+
+	if gates.RequireCoreDNS {
+		available, err := coreDNSAvailable(ctx, remoteClient)
+		if err != nil {
+			return nil, err
+		}
+
+		if !available {
+			failures = append(failures, "CoreDNS is not available")
+		}
+	}
+
+	if gates.ChecksConfigMap.Name == "" {
+		return failures, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := cl.Get(ctx, gates.ChecksConfigMap, configMap); err != nil {
+		return nil, fmt.Errorf("error getting readiness checks config map %s: %w", gates.ChecksConfigMap, err)
+	}
+
+	names := make([]string, 0, len(configMap.Data))
+	for name := range configMap.Data {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		check := readinessCheck{}
+		if err := yaml.UnmarshalStrict([]byte(configMap.Data[name]), &check); err != nil {
+			return nil, fmt.Errorf("parsing readiness check %s: %w", name, err)
+		}
+
+		passed, err := evaluateReadinessCheck(ctx, remoteClient, check)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating readiness check %s: %w", name, err)
+		}
+
+		if !passed {
+			failures = append(failures, fmt.Sprintf("check %s failed", name))
+		}
+	}
+
+	return failures, nil
+}
+
+func countReadyNodes(ctx context.Context, remoteClient client.Client) (int, error) {
+	nodes := &corev1.NodeList{}
+	if err := remoteClient.List(ctx, nodes); err != nil {
+		return 0, fmt.Errorf("listing nodes in remote cluster: %w", err)
+	}
+
+	ready := 0
+
+	for _, node := range nodes.Items {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				ready++
+			}
+		}
+	}
+
+	return ready, nil
+}
+
+// coreDNSAvailable returns true if a CoreDNS deployment in the kube-system namespace has available replicas.
+// Deployments are selected by the `k8s-app=kube-dns` label, used by kubeadm, RKE2 and K3s.
+func coreDNSAvailable(ctx context.Context, remoteClient client.Client) (bool, error) {
+	deployments := &appsv1.DeploymentList{}
+	if err := remoteClient.List(ctx, deployments,
+		client.InNamespace("kube-system"),
+		client.MatchingLabels{"k8s-app": coreDNSLabelSelector},
+	); err != nil {
+		return false, fmt.Errorf("listing CoreDNS deployments in remote cluster: %w", err)
+	}
+
+	for _, deployment := range deployments.Items {
+		if deployment.Status.AvailableReplicas > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func evaluateReadinessCheck(ctx context.Context, remoteClient client.Client, check readinessCheck) (bool, error) {
+	if check.APIVersion == "" || check.Kind == "" || strings.TrimSpace(check.Expression) == "" {
+		return false, errors.New("apiVersion, kind and expression are required")
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.Variable("objects", cel.ListType(cel.DynType)),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	ast, issues := env.Compile(check.Expression)
+	if issues.Err() != nil {
+		return false, fmt.Errorf("compiling expression: %w", issues.Err())
+	}
+
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return false, fmt.Errorf("expression must return a bool, got %s", ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return false, err
+	}
+
+	vars := map[string]any{
+		"object":  nil,
+		"objects": []any{},
+	}
+
+	if check.Name != "" {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(check.APIVersion)
+		obj.SetKind(check.Kind)
+
+		if err := remoteClient.Get(ctx, client.ObjectKey{Namespace: check.Namespace, Name: check.Name}, obj); err != nil {
+			if client.IgnoreNotFound(err) == nil {
+				log.FromContext(ctx).V(4).Info("readiness check object not found", "kind", check.Kind, "name", check.Name)
+				return false, nil
+			}
+
+			return false, fmt.Errorf("getting object in remote cluster: %w", err)
+		}
+
+		vars["object"] = obj.Object
+	} else {
+		list := &unstructured.UnstructuredList{}
+		list.SetAPIVersion(check.APIVersion)
+		list.SetKind(check.Kind + "List")
+
+		if err := remoteClient.List(ctx, list, client.InNamespace(check.Namespace)); err != nil {
+			return false, fmt.Errorf("listing objects in remote cluster: %w", err)
+		}
+
+		objects := make([]any, 0, len(list.Items))
+		for _, item := range list.Items {
+			objects = append(objects, item.Object)
+		}
+
+		vars["objects"] = objects
+	}
+
+	out, _, err := program.ContextEval(ctx, vars)
+	if err != nil {
+		// Missing fields, i.e. an empty status, are reported as a failed check.
+		log.FromContext(ctx).V(4).Info("readiness check evaluation failed", "kind", check.Kind, "error", err.Error())
+		return false, nil
+	}
+
+	passed, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression must return a bool, got %T", out.Value())
+	}
+
+	return passed, nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("checkReadinessGates", func() {
+	var (
+		ctx          context.Context
+		cl           client.Client
+		remoteClient client.Client
+		readyNode    *corev1.Node
+		coreDNS      *appsv1.Deployment
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		cl = fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+		readyNode = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			},
+		}
+
+		coreDNS = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "coredns",
+				Namespace: "kube-system",
+				Labels:    map[string]string{"k8s-app": "kube-dns"},
+			},
+			Status: appsv1.DeploymentStatus{AvailableReplicas: 1},
+		}
+	})
+
+	It("should pass when no gates are configured", func() {
+		remoteClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+		failures, err := checkReadinessGates(ctx, cl, remoteClient, ImportReadinessGates{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failures).To(BeEmpty())
+	})
+
+	It("should report missing ready nodes and CoreDNS", func() {
+		remoteClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(readyNode).Build()
+
+		failures, err := checkReadinessGates(ctx, cl, remoteClient, ImportReadinessGates{MinReadyNodes: 2, RequireCoreDNS: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failures).To(ConsistOf("1 of required 2 nodes are ready", "CoreDNS is not available"))
+	})
+
+	It("should pass when enough nodes are ready and CoreDNS is available", func() {
+		remoteClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(readyNode, coreDNS).Build()
+
+		failures, err := checkReadinessGates(ctx, cl, remoteClient, ImportReadinessGates{MinReadyNodes: 1, RequireCoreDNS: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failures).To(BeEmpty())
+	})
+
+	It("should evaluate user defined CEL checks", func() {
+		remoteClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(readyNode, coreDNS).Build()
+
+		Expect(cl.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "readiness-checks", Namespace: "default"},
+			Data: map[string]string{
+				"coredns": `
+apiVersion: apps/v1
+kind: Deployment
+namespace: kube-system
+name: coredns
+expression: object.status.availableReplicas >= 2
+`,
+				"nodes": `
+apiVersion: v1
+kind: Node
+expression: objects.size() == 1
+`,
+			},
+		})).To(Succeed())
+
+		failures, err := checkReadinessGates(ctx, cl, remoteClient, ImportReadinessGates{
+			ChecksConfigMap: client.ObjectKey{Namespace: "default", Name: "readiness-checks"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failures).To(ConsistOf("check coredns failed"))
+	})
+
+	It("should return error for an invalid CEL check", func() {
+		remoteClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+		Expect(cl.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "readiness-checks", Namespace: "default"},
+			Data: map[string]string{
+				"invalid": `
+apiVersion: v1
+kind: Node
+expression: objects.size() ==
+`,
+			},
+		})).To(Succeed())
+
+		_, err := checkReadinessGates(ctx, cl, remoteClient, ImportReadinessGates{
+			ChecksConfigMap: client.ObjectKey{Namespace: "default", Name: "readiness-checks"},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
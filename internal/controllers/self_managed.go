@@ -0,0 +1,130 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+// localClusterName is the name of the Rancher cluster representing the Rancher Manager cluster itself.
+const localClusterName = "local"
+
+// isSelfManaged returns true if the CAPI cluster represents the management cluster Turtles runs in.
+// The self-managed annotation takes precedence, otherwise, when the detection is enabled, the UIDs of the kube-system namespace
+// in the management and the workload cluster are compared. A workload cluster which can't be reached is not self-managed,
+// so it is imported as usual.
+func (r *CAPIImportReconciler) isSelfManaged(ctx context.Context, rancherClient client.Client,
+	capiCluster *clusterv1.Cluster,
+) (bool, error) {
+	if value, ok := capiCluster.GetAnnotations()[turtlesannotations.SelfManagedClusterAnnotation]; ok {
+		selfManaged, err := strconv.ParseBool(value)
+
+		return err == nil && selfManaged, nil
+	}
+
+	// The local Rancher cluster only exists when Rancher runs in the management cluster.
+	if !r.DetectSelfManaged || rancherClient != r.Client {
+		return false, nil
+	}
+
+	local, err := clusterID(ctx, r.Client)
+	if err != nil {
+		return false, err
+	}
+
+	remoteClient, err := r.remoteClientGetter(ctx, capiCluster.Name, r.Client, client.ObjectKeyFromObject(capiCluster))
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Unable to get remote cluster client, importing the cluster as a workload cluster")
+
+		return false, nil
+	}
+
+	remote, err := clusterID(ctx, remoteClient)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Unable to identify the remote cluster, importing the cluster as a workload cluster")
+
+		return false, nil
+	}
+
+	return local != "" && local == remote, nil
+}
+
+// clusterID returns the UID of the kube-system namespace, identifying the cluster.
+func clusterID(ctx context.Context, cl client.Client) (string, error) {
+	ns := &corev1.Namespace{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: metav1.NamespaceSystem}, ns); err != nil {
+		return "", fmt.Errorf("getting kube-system namespace: %w", err)
+	}
+
+	return string(ns.UID), nil
+}
+
+// reconcileSelfManaged links the self-managed CAPI cluster to the local Rancher cluster, instead of importing it.
+// The local cluster is labeled with the owner CAPI cluster, but not as owned by Turtles, so it is never deleted
+// together with the CAPI cluster, nor cleaned up as orphaned. The CAPI cluster is marked as imported and
+// self-managed, which stops further import reconciliation and protects it from deletion by the admission webhook.
+func (r *CAPIImportReconciler) reconcileSelfManaged(ctx context.Context, rancherClient client.Client,
+	capiCluster *clusterv1.Cluster,
+) error {
+	log := log.FromContext(ctx)
+	log.Info("CAPI cluster represents the management cluster, linking it to the local Rancher cluster")
+
+	localCluster := &managementv3.Cluster{}
+	if err := rancherClient.Get(ctx, client.ObjectKey{Name: localClusterName}, localCluster); err != nil {
+		return fmt.Errorf("getting local Rancher cluster: %w", err)
+	}
+
+	patchBase := client.MergeFrom(localCluster.DeepCopy())
+
+	labels := localCluster.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	labels[capiClusterOwner] = capiCluster.Name
+	labels[capiClusterOwnerNamespace] = capiCluster.Namespace
+	localCluster.SetLabels(labels)
+
+	if err := rancherClient.Patch(ctx, localCluster, patchBase); err != nil {
+		return fmt.Errorf("linking local Rancher cluster: %w", err)
+	}
+
+	annotations := capiCluster.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[turtlesannotations.SelfManagedClusterAnnotation] = trueValue
+	annotations[turtlesannotations.ClusterImportedAnnotation] = trueValue
+	capiCluster.SetAnnotations(annotations)
+	controllerutil.RemoveFinalizer(capiCluster, managementv3.CapiClusterFinalizer)
+
+	return nil
+}
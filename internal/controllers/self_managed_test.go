@@ -0,0 +1,164 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+var _ = Describe("Self-managed clusters", func() {
+	var (
+		ctx         context.Context
+		testScheme  *runtime.Scheme
+		capiCluster *clusterv1.Cluster
+		kubeSystem  *corev1.Namespace
+		r           *CAPIImportReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		testScheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(clusterv1.AddToScheme(testScheme)).To(Succeed())
+		Expect(managementv3.AddToScheme(testScheme)).To(Succeed())
+
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "rancher",
+				Namespace:  "default",
+				Finalizers: []string{managementv3.CapiClusterFinalizer},
+			},
+		}
+		kubeSystem = &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceSystem, UID: types.UID("management")},
+		}
+
+		r = &CAPIImportReconciler{
+			Client:            fake.NewClientBuilder().WithScheme(testScheme).WithObjects(kubeSystem.DeepCopy()).Build(),
+			DetectSelfManaged: true,
+		}
+	})
+
+	remoteWithUID := func(uid string) func(context.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+		return func(context.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+			ns := kubeSystem.DeepCopy()
+			ns.UID = types.UID(uid)
+
+			return fake.NewClientBuilder().WithScheme(testScheme).WithObjects(ns).Build(), nil
+		}
+	}
+
+	It("should detect a cluster sharing the kube-system namespace with the management cluster", func() {
+		r.remoteClientGetter = remoteWithUID("management")
+
+		selfManaged, err := r.isSelfManaged(ctx, r.Client, capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selfManaged).To(BeTrue())
+	})
+
+	It("should not detect a downstream cluster", func() {
+		r.remoteClientGetter = remoteWithUID("downstream")
+
+		selfManaged, err := r.isSelfManaged(ctx, r.Client, capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selfManaged).To(BeFalse())
+	})
+
+	It("should not detect a cluster which can't be reached", func() {
+		r.remoteClientGetter = func(context.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+			return nil, errors.New("cluster unreachable")
+		}
+
+		selfManaged, err := r.isSelfManaged(ctx, r.Client, capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selfManaged).To(BeFalse())
+	})
+
+	It("should not detect a cluster without a readable kube-system namespace", func() {
+		r.remoteClientGetter = func(context.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+			return fake.NewClientBuilder().WithScheme(testScheme).Build(), nil
+		}
+
+		selfManaged, err := r.isSelfManaged(ctx, r.Client, capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selfManaged).To(BeFalse())
+	})
+
+	It("should skip the detection when the annotation is set", func() {
+		r.remoteClientGetter = func(context.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+			return nil, errors.New("unexpected remote access")
+		}
+		capiCluster.Annotations = map[string]string{turtlesannotations.SelfManagedClusterAnnotation: "false"}
+
+		selfManaged, err := r.isSelfManaged(ctx, r.Client, capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selfManaged).To(BeFalse())
+	})
+
+	It("should skip the detection when it is disabled", func() {
+		r.DetectSelfManaged = false
+		r.remoteClientGetter = func(context.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+			return nil, errors.New("unexpected remote access")
+		}
+
+		selfManaged, err := r.isSelfManaged(ctx, r.Client, capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selfManaged).To(BeFalse())
+	})
+
+	It("should skip the detection when Rancher runs in another cluster", func() {
+		r.remoteClientGetter = func(context.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+			return nil, errors.New("unexpected remote access")
+		}
+
+		selfManaged, err := r.isSelfManaged(ctx, fake.NewClientBuilder().WithScheme(testScheme).Build(), capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selfManaged).To(BeFalse())
+	})
+
+	It("should link the local Rancher cluster without marking it as owned", func() {
+		local := &managementv3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: localClusterName}}
+		rancherClient := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(local).Build()
+
+		Expect(r.reconcileSelfManaged(ctx, rancherClient, capiCluster)).To(Succeed())
+
+		Expect(rancherClient.Get(ctx, client.ObjectKeyFromObject(local), local)).To(Succeed())
+		Expect(local.Labels).To(HaveKeyWithValue(capiClusterOwner, "rancher"))
+		Expect(local.Labels).To(HaveKeyWithValue(capiClusterOwnerNamespace, "default"))
+		Expect(local.Labels).NotTo(HaveKey(ownedLabelName))
+
+		Expect(turtlesannotations.IsSelfManagedCluster(capiCluster)).To(BeTrue())
+		Expect(turtlesannotations.HasClusterImportAnnotation(capiCluster)).To(BeTrue())
+		Expect(capiCluster.Finalizers).To(BeEmpty())
+	})
+})
@@ -19,43 +19,103 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 
+	"github.com/blang/semver/v4"
+	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
 )
 
+const (
+	uiPluginNamespace    = "cattle-ui-plugin-system"
+	uiPluginManagedLabel = "turtles-capi.cattle.io/ui-plugin-managed"
+	uiPluginFieldOwner   = "ui-plugin-controller"
+	uiPluginOwnerRole    = "rancher-turtles-manager-role"
+)
+
+// UIPluginState is the state of the CAPI UI extension managed by Turtles.
+type UIPluginState string
+
+const (
+	// UIPluginInstalled means the UI extension is installed and compatible with the running Turtles version.
+	UIPluginInstalled UIPluginState = "Installed"
+	// UIPluginUpgraded means the UI extension was upgraded to a new version.
+	UIPluginUpgraded UIPluginState = "Upgraded"
+	// UIPluginIncompatible means the UI extension is not compatible with the running Turtles version.
+	// It is removed, or not installed, until a compatible version is provided.
+	UIPluginIncompatible UIPluginState = "Incompatible"
+)
+
+// UIPluginCompatibility defines the UI extension versions compatible with a range of Turtles versions.
+type UIPluginCompatibility struct {
+	// TurtlesVersions is a semver range of Turtles versions, e.g. ">=0.25.0 <0.26.0".
+	TurtlesVersions string
+	// PluginVersions is a semver range of the compatible UI extension versions.
+	PluginVersions string
+}
+
+// DefaultUIPluginCompatibility pins the CAPI UI extension versions compatible with each Turtles minor release.
+// Both ranges are bounded, so a new UI extension minor release is only accepted once it is added here.
+// Turtles versions not covered by the matrix, like development builds, accept any UI extension version.
+var DefaultUIPluginCompatibility = []UIPluginCompatibility{
+	{TurtlesVersions: ">=0.25.0 <0.26.0", PluginVersions: ">=0.9.0 <0.10.0"},
+}
+
 // UIPluginReconciler reconciles a UIPlugin object.
 type UIPluginReconciler struct {
 	client.Client
 	*runtime.Scheme
 
 	UncachedClient client.Client
+
+	// TurtlesVersion is the version of the running Turtles controller.
+	TurtlesVersion string
+	// Compatibility is the version matrix used to validate UI extension versions.
+	// DefaultUIPluginCompatibility is used when not set.
+	Compatibility []UIPluginCompatibility
+
+	recorder events.EventRecorder
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *UIPluginReconciler) SetupWithManager(_ context.Context, mgr ctrl.Manager, _ controller.Options) error {
 	uiPlugin := &metav1.PartialObjectMetadata{}
-	uiPlugin.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "catalog.cattle.io",
-		Version: "v1",
-		Kind:    "UIPlugin",
-	})
+	uiPlugin.SetGroupVersionKind(uiPluginGVK())
+
+	if r.Compatibility == nil {
+		r.Compatibility = DefaultUIPluginCompatibility
+	}
+
+	r.recorder = mgr.GetEventRecorder("rancher-turtles-ui-plugin")
 
 	if err := ctrl.NewControllerManagedBy(mgr).
 		Named("ui-plugin").
 		For(uiPlugin).
 		WithEventFilter(predicate.NewPredicateFuncs(func(plugin client.Object) bool {
+			if plugin.GetNamespace() == uiPluginNamespace {
+				_, managed := plugin.GetLabels()[uiPluginManagedLabel]
+				return managed
+			}
+
 			return plugin.GetNamespace() == os.Getenv("POD_NAMESPACE")
 		})).
 		Complete(r); err != nil {
@@ -66,15 +126,19 @@ func (r *UIPluginReconciler) SetupWithManager(_ context.Context, mgr ctrl.Manage
 }
 
 //+kubebuilder:rbac:groups=catalog.cattle.io,resources=uiplugins,verbs=get;list;watch;create;patch;delete
+//+kubebuilder:rbac:groups=turtles-capi.cattle.io,resources=uipluginstatuses;uipluginstatuses/status,verbs=get;list;create;patch;delete;deletecollection
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resourceNames=rancher-turtles-manager-role,resources=clusterroles,verbs=get;list
+//
+//nolint:lll
 
-// Reconcile moves the UIPlugin into cattle-ui-plugin-system namespace.
+// Reconcile moves the UIPlugin into cattle-ui-plugin-system namespace, and keeps the installed UIPlugin
+// state up to date with the running Turtles version. The state is reported in the UIPluginStatus
+// with the name of the UIPlugin in the Turtles namespace.
 func (r *UIPluginReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
 	plugin := &unstructured.Unstructured{}
-	plugin.SetKind("UIPlugin")
-	plugin.SetAPIVersion("catalog.cattle.io/v1")
+	plugin.SetGroupVersionKind(uiPluginGVK())
 
 	if err := r.Get(ctx, req.NamespacedName, plugin); err != nil {
 		log.Error(err, "Unable to get UIPlugin")
@@ -86,19 +150,72 @@ func (r *UIPluginReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, nil
 	}
 
+	if plugin.GetNamespace() == uiPluginNamespace {
+		return ctrl.Result{}, r.reconcileInstalled(ctx, plugin)
+	}
+
+	version := uiPluginVersion(plugin)
+
+	compatible, err := r.compatible(version)
+	if err != nil {
+		log.Error(err, "Unable to check UIPlugin version compatibility")
+
+		return ctrl.Result{}, err
+	}
+
+	if !compatible {
+		log.Info("UIPlugin version is not compatible with Turtles, skipping installation",
+			"pluginVersion", version, "turtlesVersion", r.TurtlesVersion)
+		r.recorder.Eventf(plugin, nil, corev1.EventTypeWarning, string(UIPluginIncompatible), "Install",
+			"UI extension version %s is not compatible with Turtles version %s", version, r.TurtlesVersion)
+
+		if err := r.reportState(ctx, plugin.GetName(), version, UIPluginIncompatible); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// The rejected UIPlugin would be checked again on every resync, remove it until a compatible one is provided.
+		if err := r.Delete(ctx, plugin); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to cleanup incompatible source UIPlugin")
+
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	}
+
 	role := &rbacv1.ClusterRole{}
 	if err := r.UncachedClient.Get(ctx, types.NamespacedName{
-		Name: "rancher-turtles-manager-role",
+		Name: uiPluginOwnerRole,
 	}, role); err != nil {
 		log.Error(err, "Unable to get turtles clusterRole")
 
 		return ctrl.Result{}, err
 	}
 
+	installed, err := r.installedPlugins(ctx)
+	if err != nil {
+		log.Error(err, "Unable to list installed UIPlugins")
+
+		return ctrl.Result{}, err
+	}
+
+	state := UIPluginInstalled
+
+	for _, existing := range installed {
+		if existing.GetName() == plugin.GetName() && uiPluginVersion(&existing) != version {
+			state = UIPluginUpgraded
+		}
+	}
+
 	destination := &unstructured.Unstructured{}
 	destination.SetGroupVersionKind(plugin.GroupVersionKind())
 	destination.SetName(plugin.GetName())
-	destination.SetNamespace("cattle-ui-plugin-system")
+	destination.SetNamespace(uiPluginNamespace)
+	destination.SetLabels(map[string]string{uiPluginManagedLabel: "true"})
+	destination.SetAnnotations(map[string]string{
+		turtlesannotations.UIPluginStateAnnotation:          string(state),
+		turtlesannotations.UIPluginTurtlesVersionAnnotation: r.TurtlesVersion,
+	})
 	destination.Object["spec"] = plugin.Object["spec"]
 
 	if err := controllerutil.SetOwnerReference(role, destination, r.Scheme); err != nil {
@@ -111,13 +228,34 @@ func (r *UIPluginReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	if err := r.Apply(ctx, applyConfiguration, []client.ApplyOption{
 		client.ForceOwnership,
-		client.FieldOwner("ui-plugin-controller"),
+		client.FieldOwner(uiPluginFieldOwner),
 	}...); err != nil {
 		log.Error(err, "Unable to patch UIPlugin")
 
 		return ctrl.Result{}, err
 	}
 
+	log.Info("UIPlugin installed", "state", state, "pluginVersion", version)
+
+	if err := r.reportState(ctx, plugin.GetName(), version, state); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Remove stale copies, installed from a previous UIPlugin under a different name.
+	for _, existing := range installed {
+		if existing.GetName() == plugin.GetName() {
+			continue
+		}
+
+		log.Info("Removing stale UIPlugin", "name", existing.GetName())
+
+		if err := r.Delete(ctx, &existing); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to remove stale UIPlugin")
+
+			return ctrl.Result{}, err
+		}
+	}
+
 	if err := r.Delete(ctx, plugin); err != nil {
 		log.Error(err, "Unable to cleanup source UIPlugin")
 
@@ -126,3 +264,215 @@ func (r *UIPluginReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	return ctrl.Result{}, nil
 }
+
+// reconcileInstalled updates the state of an installed UIPlugin, after Turtles has been upgraded.
+// An incompatible UI extension is removed, as Rancher would serve it to the UI otherwise, until
+// a compatible UIPlugin is provided.
+func (r *UIPluginReconciler) reconcileInstalled(ctx context.Context, plugin *unstructured.Unstructured) error {
+	log := log.FromContext(ctx)
+	version := uiPluginVersion(plugin)
+
+	compatible, err := r.compatible(version)
+	if err != nil {
+		return err
+	}
+
+	if !compatible {
+		log.Info("Installed UIPlugin is not compatible with Turtles, removing it", "pluginVersion", version, "turtlesVersion", r.TurtlesVersion)
+		r.recorder.Eventf(plugin, nil, corev1.EventTypeWarning, string(UIPluginIncompatible), "Upgrade",
+			"UI extension version %s is not compatible with Turtles version %s, removing it", version, r.TurtlesVersion)
+
+		if err := r.reportState(ctx, plugin.GetName(), version, UIPluginIncompatible); err != nil {
+			return err
+		}
+
+		if err := r.Delete(ctx, plugin); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("removing incompatible UIPlugin: %w", err)
+		}
+
+		return nil
+	}
+
+	state := UIPluginInstalled
+
+	// Keep the upgraded state until the next Turtles upgrade.
+	if plugin.GetAnnotations()[turtlesannotations.UIPluginTurtlesVersionAnnotation] == r.TurtlesVersion &&
+		plugin.GetAnnotations()[turtlesannotations.UIPluginStateAnnotation] == string(UIPluginUpgraded) {
+		state = UIPluginUpgraded
+	}
+
+	if err := r.reportState(ctx, plugin.GetName(), version, state); err != nil {
+		return err
+	}
+
+	annotations := map[string]string{}
+	maps.Copy(annotations, plugin.GetAnnotations())
+	annotations[turtlesannotations.UIPluginStateAnnotation] = string(state)
+	annotations[turtlesannotations.UIPluginTurtlesVersionAnnotation] = r.TurtlesVersion
+
+	if maps.Equal(annotations, plugin.GetAnnotations()) {
+		return nil
+	}
+
+	patchBase := client.MergeFrom(plugin.DeepCopy())
+	plugin.SetAnnotations(annotations)
+
+	if err := r.Patch(ctx, plugin, patchBase); err != nil {
+		return fmt.Errorf("updating UIPlugin state: %w", err)
+	}
+
+	return nil
+}
+
+// reportState reports the state of the UI extension through the UIPluginCompatible condition of the UIPluginStatus.
+// The status of the UIPlugin is owned by Rancher, and is overwritten by its UIPlugin controller.
+func (r *UIPluginReconciler) reportState(ctx context.Context, name, version string, state UIPluginState) error {
+	status := &turtlesv1.UIPluginStatus{}
+	key := client.ObjectKey{Namespace: os.Getenv("POD_NAMESPACE"), Name: name}
+
+	if err := r.UncachedClient.Get(ctx, key, status); apierrors.IsNotFound(err) {
+		status = &turtlesv1.UIPluginStatus{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			Spec:       turtlesv1.UIPluginStatusSpec{PluginName: name},
+		}
+
+		if err := r.Create(ctx, status); err != nil {
+			return fmt.Errorf("creating UIPlugin status: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("getting UIPlugin status: %w", err)
+	}
+
+	patchBase := client.MergeFrom(status.DeepCopy())
+	status.Status.State = string(state)
+	status.Status.PluginVersion = version
+	status.Status.TurtlesVersion = r.TurtlesVersion
+
+	if state == UIPluginIncompatible {
+		conditions.Set(status, metav1.Condition{
+			Type:    turtlesv1.UIPluginCompatibleCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  turtlesv1.UIPluginIncompatibleReason,
+			Message: fmt.Sprintf("UI extension version %s is not compatible with Turtles version %s", version, r.TurtlesVersion),
+		})
+	} else {
+		conditions.Set(status, metav1.Condition{
+			Type:   turtlesv1.UIPluginCompatibleCondition,
+			Status: metav1.ConditionTrue,
+			Reason: turtlesv1.UIPluginCompatibleReason,
+		})
+	}
+
+	if err := r.Status().Patch(ctx, status, patchBase); err != nil {
+		return fmt.Errorf("updating UIPlugin status: %w", err)
+	}
+
+	return nil
+}
+
+// installedPlugins returns the UIPlugins installed by Turtles.
+func (r *UIPluginReconciler) installedPlugins(ctx context.Context) ([]unstructured.Unstructured, error) {
+	return managedUIPlugins(ctx, r.UncachedClient)
+}
+
+// compatible returns true if the UI extension version is compatible with the running Turtles version.
+func (r *UIPluginReconciler) compatible(pluginVersion string) (bool, error) {
+	turtlesVersion, err := semver.ParseTolerant(r.TurtlesVersion)
+	if err != nil {
+		// Unknown Turtles versions are not covered by the compatibility matrix.
+		return true, nil //nolint:nilerr // an unparsable version accepts any UI extension
+	}
+
+	for _, entry := range r.Compatibility {
+		turtlesRange, err := semver.ParseRange(entry.TurtlesVersions)
+		if err != nil {
+			return false, fmt.Errorf("parsing Turtles version range %q: %w", entry.TurtlesVersions, err)
+		}
+
+		if !turtlesRange(turtlesVersion) {
+			continue
+		}
+
+		pluginRange, err := semver.ParseRange(entry.PluginVersions)
+		if err != nil {
+			return false, fmt.Errorf("parsing UIPlugin version range %q: %w", entry.PluginVersions, err)
+		}
+
+		version, err := semver.ParseTolerant(pluginVersion)
+		if err != nil {
+			return false, nil //nolint:nilerr // an unparsable UI extension version is not compatible
+		}
+
+		return pluginRange(version), nil
+	}
+
+	return true, nil
+}
+
+// RemoveUIPlugins removes the UIPlugins installed by Turtles and their UIPluginStatuses.
+// It is used when the UI plugin feature is disabled.
+func RemoveUIPlugins(ctx context.Context, cl client.Client) error {
+	log := log.FromContext(ctx)
+
+	installed, err := managedUIPlugins(ctx, cl)
+	if err != nil {
+		return err
+	}
+
+	for _, plugin := range installed {
+		log.Info("Removing UIPlugin, UI plugin feature is disabled", "name", plugin.GetName())
+
+		if err := cl.Delete(ctx, &plugin); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("removing UIPlugin %s: %w", plugin.GetName(), err)
+		}
+	}
+
+	if err := cl.DeleteAllOf(ctx, &turtlesv1.UIPluginStatus{}, client.InNamespace(os.Getenv("POD_NAMESPACE"))); err != nil {
+		return fmt.Errorf("removing UIPlugin statuses: %w", err)
+	}
+
+	return nil
+}
+
+// managedUIPlugins lists UIPlugins installed by Turtles, identified by the managed label or by the owner
+// reference to the Turtles ClusterRole, set by earlier Turtles versions.
+func managedUIPlugins(ctx context.Context, cl client.Client) ([]unstructured.Unstructured, error) {
+	plugins := &unstructured.UnstructuredList{}
+	plugins.SetGroupVersionKind(uiPluginGVK().GroupVersion().WithKind("UIPluginList"))
+
+	if err := cl.List(ctx, plugins, client.InNamespace(uiPluginNamespace)); err != nil {
+		return nil, fmt.Errorf("listing UIPlugins: %w", err)
+	}
+
+	managed := []unstructured.Unstructured{}
+
+	for _, plugin := range plugins.Items {
+		if _, ok := plugin.GetLabels()[uiPluginManagedLabel]; ok {
+			managed = append(managed, plugin)
+			continue
+		}
+
+		for _, ref := range plugin.GetOwnerReferences() {
+			if ref.Kind == "ClusterRole" && ref.Name == uiPluginOwnerRole {
+				managed = append(managed, plugin)
+				break
+			}
+		}
+	}
+
+	return managed, nil
+}
+
+func uiPluginVersion(plugin *unstructured.Unstructured) string {
+	version, _, _ := unstructured.NestedString(plugin.Object, "spec", "plugin", "version")
+
+	return version
+}
+
+func uiPluginGVK() schema.GroupVersionKind {
+	return schema.GroupVersionKind{
+		Group:   "catalog.cattle.io",
+		Version: "v1",
+		Kind:    "UIPlugin",
+	}
+}
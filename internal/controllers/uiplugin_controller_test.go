@@ -0,0 +1,209 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+var _ = Describe("UIPluginReconciler", func() {
+	const testNamespace = "rancher-turtles-system"
+
+	var ctx context.Context
+
+	newUIPlugin := func(name, namespace, version string) *unstructured.Unstructured {
+		p := &unstructured.Unstructured{}
+		p.SetGroupVersionKind(uiPluginGVK())
+		p.SetName(name)
+		p.SetNamespace(namespace)
+		Expect(unstructured.SetNestedField(p.Object, version, "spec", "plugin", "version")).To(Succeed())
+
+		return p
+	}
+
+	newManagedUIPlugin := func(version string, annotations map[string]string) *unstructured.Unstructured {
+		plugin := newUIPlugin("capi", uiPluginNamespace, version)
+		plugin.SetLabels(map[string]string{uiPluginManagedLabel: "true"})
+		plugin.SetAnnotations(annotations)
+
+		return plugin
+	}
+
+	newReconciler := func(objects ...client.Object) *UIPluginReconciler {
+		uiPluginScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(uiPluginScheme)).To(Succeed())
+		Expect(turtlesv1.AddToScheme(uiPluginScheme)).To(Succeed())
+
+		cl := fake.NewClientBuilder().WithScheme(uiPluginScheme).
+			WithObjects(objects...).
+			WithStatusSubresource(&turtlesv1.UIPluginStatus{}).
+			Build()
+
+		return &UIPluginReconciler{
+			Client:         cl,
+			Scheme:         uiPluginScheme,
+			UncachedClient: cl,
+			TurtlesVersion: "v0.25.1",
+			Compatibility:  DefaultUIPluginCompatibility,
+			recorder:       events.NewFakeRecorder(10),
+		}
+	}
+
+	getStatus := func(r *UIPluginReconciler, name string) *turtlesv1.UIPluginStatus {
+		status := &turtlesv1.UIPluginStatus{}
+		Expect(r.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: name}, status)).To(Succeed())
+
+		return status
+	}
+
+	expectRemovedAsIncompatible := func(r *UIPluginReconciler, plugin *unstructured.Unstructured) {
+		err := r.Get(ctx, client.ObjectKeyFromObject(plugin), plugin.DeepCopy())
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		status := getStatus(r, plugin.GetName())
+		Expect(status.Status.State).To(Equal(string(UIPluginIncompatible)))
+		Expect(conditions.IsFalse(status, turtlesv1.UIPluginCompatibleCondition)).To(BeTrue())
+		Expect(conditions.GetReason(status, turtlesv1.UIPluginCompatibleCondition)).To(Equal(turtlesv1.UIPluginIncompatibleReason))
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		podNamespace, set := os.LookupEnv("POD_NAMESPACE")
+		Expect(os.Setenv("POD_NAMESPACE", testNamespace)).To(Succeed())
+		DeferCleanup(func() {
+			if set {
+				os.Setenv("POD_NAMESPACE", podNamespace)
+			} else {
+				os.Unsetenv("POD_NAMESPACE")
+			}
+		})
+	})
+
+	It("should only accept UI extension versions within the bounded range of the Turtles minor release", func() {
+		r := &UIPluginReconciler{TurtlesVersion: "v0.25.1", Compatibility: DefaultUIPluginCompatibility}
+
+		Expect(r.compatible("0.9.0")).To(BeTrue())
+		Expect(r.compatible("v0.8.2")).To(BeFalse())
+		Expect(r.compatible("0.10.0")).To(BeFalse())
+		Expect(r.compatible("1.0.0")).To(BeFalse())
+		Expect(r.compatible("invalid")).To(BeFalse())
+
+		r.TurtlesVersion = "v0.0.0-master"
+		Expect(r.compatible("0.1.0")).To(BeTrue())
+
+		r.Compatibility = []UIPluginCompatibility{{TurtlesVersions: "invalid", PluginVersions: ">=0.1.0"}}
+		_, err := r.compatible("0.1.0")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should report the state of a compatible UIPlugin", func() {
+		plugin := newManagedUIPlugin("0.9.1", nil)
+		r := newReconciler(plugin)
+
+		Expect(r.reconcileInstalled(ctx, plugin)).To(Succeed())
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(plugin), plugin)).To(Succeed())
+		Expect(plugin.GetAnnotations()).To(HaveKeyWithValue(turtlesannotations.UIPluginStateAnnotation, string(UIPluginInstalled)))
+		Expect(plugin.GetAnnotations()).To(HaveKeyWithValue(turtlesannotations.UIPluginTurtlesVersionAnnotation, "v0.25.1"))
+
+		status := getStatus(r, "capi")
+		Expect(status.Spec.PluginName).To(Equal("capi"))
+		Expect(status.Status.State).To(Equal(string(UIPluginInstalled)))
+		Expect(status.Status.PluginVersion).To(Equal("0.9.1"))
+		Expect(conditions.IsTrue(status, turtlesv1.UIPluginCompatibleCondition)).To(BeTrue())
+	})
+
+	It("should remove an incompatible UIPlugin after a Turtles upgrade", func() {
+		plugin := newManagedUIPlugin("0.8.0", map[string]string{
+			turtlesannotations.UIPluginStateAnnotation:          string(UIPluginInstalled),
+			turtlesannotations.UIPluginTurtlesVersionAnnotation: "v0.24.0",
+		})
+		r := newReconciler(plugin)
+
+		Expect(r.reconcileInstalled(ctx, plugin)).To(Succeed())
+		expectRemovedAsIncompatible(r, plugin)
+	})
+
+	It("should remove an installed UIPlugin newer than the compatible range", func() {
+		plugin := newManagedUIPlugin("0.10.0", map[string]string{
+			turtlesannotations.UIPluginStateAnnotation:          string(UIPluginInstalled),
+			turtlesannotations.UIPluginTurtlesVersionAnnotation: "v0.25.1",
+		})
+		r := newReconciler(plugin)
+
+		Expect(r.reconcileInstalled(ctx, plugin)).To(Succeed())
+		expectRemovedAsIncompatible(r, plugin)
+	})
+
+	It("should not install an incompatible source UIPlugin", func() {
+		source := newUIPlugin("capi", testNamespace, "0.8.0")
+		r := newReconciler(source)
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(source)})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(managedUIPlugins(ctx, r.Client)).To(BeEmpty())
+		expectRemovedAsIncompatible(r, source)
+	})
+
+	It("should remove the UIPlugins installed by Turtles and their statuses", func() {
+		plugin := newManagedUIPlugin("0.9.1", nil)
+		owned := newUIPlugin("capi-old", uiPluginNamespace, "0.8.0")
+		owned.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion: "rbac.authorization.k8s.io/v1",
+			Kind:       "ClusterRole",
+			Name:       uiPluginOwnerRole,
+			UID:        "uid",
+		}})
+		other := newUIPlugin("other", uiPluginNamespace, "1.0.0")
+		status := &turtlesv1.UIPluginStatus{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "capi"},
+			Spec:       turtlesv1.UIPluginStatusSpec{PluginName: "capi"},
+		}
+		cl := newReconciler(plugin, owned, other, status).Client
+
+		Expect(RemoveUIPlugins(ctx, cl)).To(Succeed())
+
+		plugins := &unstructured.UnstructuredList{}
+		plugins.SetGroupVersionKind(uiPluginGVK().GroupVersion().WithKind("UIPluginList"))
+		Expect(cl.List(ctx, plugins)).To(Succeed())
+		Expect(plugins.Items).To(HaveLen(1))
+		Expect(plugins.Items[0].GetName()).To(Equal("other"))
+
+		statuses := &turtlesv1.UIPluginStatusList{}
+		Expect(cl.List(ctx, statuses)).To(Succeed())
+		Expect(statuses.Items).To(BeEmpty())
+	})
+})
@@ -0,0 +1,77 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	"github.com/rancher/turtles/util"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+// reconcileVersionManagement applies the version management policy of the CAPI cluster to the Rancher cluster,
+// enabling Rancher version management only when Rancher owns the Kubernetes version. The owner is reported
+// with the KubernetesVersionManaged condition on the CAPI cluster.
+func (r *CAPIImportReconciler) reconcileVersionManagement(ctx context.Context, rancherCluster *managementv3.Cluster,
+	capiCluster *clusterv1.Cluster,
+) error {
+	owner, err := util.VersionManagement(ctx, r.Client, capiCluster)
+	if err != nil {
+		conditions.Set(capiCluster, metav1.Condition{
+			Type:    turtlesv1.KubernetesVersionManagedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  turtlesv1.VersionManagementInvalidReason,
+			Message: err.Error(),
+		})
+
+		return err
+	}
+
+	annotations := rancherCluster.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	rancherManaged := owner == turtlesannotations.VersionManagementRancher
+	annotations[turtlesannotations.ImportedClusterVersionManagementAnnotation] = strconv.FormatBool(rancherManaged)
+	annotations[turtlesannotations.VersionManagementAnnotation] = owner
+	rancherCluster.SetAnnotations(annotations)
+
+	condition := metav1.Condition{
+		Type:    turtlesv1.KubernetesVersionManagedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  turtlesv1.VersionManagedByCAPIReason,
+		Message: "Kubernetes version is managed by the CAPI cluster topology",
+	}
+
+	if rancherManaged {
+		condition.Reason = turtlesv1.VersionManagedByRancherReason
+		condition.Message = "Kubernetes version is managed by Rancher"
+	}
+
+	conditions.Set(capiCluster, condition)
+
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+var _ = Describe("reconcileVersionManagement", func() {
+	var (
+		ctx            context.Context
+		namespace      *corev1.Namespace
+		capiCluster    *clusterv1.Cluster
+		rancherCluster *managementv3.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "default"},
+		}
+		rancherCluster = &managementv3.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "c-cluster1", Namespace: "default"},
+		}
+	})
+
+	It("should disable Rancher version management by default", func() {
+		r := &CAPIImportReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(namespace).Build()}
+
+		Expect(r.reconcileVersionManagement(ctx, rancherCluster, capiCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).To(HaveKeyWithValue(turtlesannotations.ImportedClusterVersionManagementAnnotation, "false"))
+		Expect(rancherCluster.Annotations).To(HaveKeyWithValue(turtlesannotations.VersionManagementAnnotation,
+			turtlesannotations.VersionManagementCAPI))
+		Expect(conditions.GetReason(capiCluster, turtlesv1.KubernetesVersionManagedCondition)).To(Equal(turtlesv1.VersionManagedByCAPIReason))
+	})
+
+	It("should enable Rancher version management from the namespace policy", func() {
+		namespace.Annotations = map[string]string{
+			turtlesannotations.VersionManagementAnnotation: turtlesannotations.VersionManagementRancher,
+		}
+		r := &CAPIImportReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(namespace).Build()}
+
+		Expect(r.reconcileVersionManagement(ctx, rancherCluster, capiCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).To(HaveKeyWithValue(turtlesannotations.ImportedClusterVersionManagementAnnotation, "true"))
+		Expect(conditions.GetReason(capiCluster, turtlesv1.KubernetesVersionManagedCondition)).To(Equal(turtlesv1.VersionManagedByRancherReason))
+	})
+
+	It("should prefer the cluster policy over the namespace policy", func() {
+		namespace.Annotations = map[string]string{
+			turtlesannotations.VersionManagementAnnotation: turtlesannotations.VersionManagementRancher,
+		}
+		capiCluster.Annotations = map[string]string{
+			turtlesannotations.VersionManagementAnnotation: turtlesannotations.VersionManagementCAPI,
+		}
+		r := &CAPIImportReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(namespace).Build()}
+
+		Expect(r.reconcileVersionManagement(ctx, rancherCluster, capiCluster)).To(Succeed())
+		Expect(rancherCluster.Annotations).To(HaveKeyWithValue(turtlesannotations.ImportedClusterVersionManagementAnnotation, "false"))
+	})
+
+	It("should report an invalid policy", func() {
+		capiCluster.Annotations = map[string]string{turtlesannotations.VersionManagementAnnotation: "both"}
+		r := &CAPIImportReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(namespace).Build()}
+
+		Expect(r.reconcileVersionManagement(ctx, rancherCluster, capiCluster)).NotTo(Succeed())
+		Expect(conditions.IsFalse(capiCluster, turtlesv1.KubernetesVersionManagedCondition)).To(BeTrue())
+	})
+})
@@ -0,0 +1,439 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"sigs.k8s.io/cluster-api-operator/controller"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+// appliedSpecHashAnnotation is set by the CAPI Operator on the provider with the hash of the applied manifest.
+// Removing it forces the provider manifest to be applied again, instead of being restored from the cache.
+// The annotation is internal to the CAPI Operator, which offers no input to force the manifest apply: it has to be
+// verified against the operator sources when the sigs.k8s.io/cluster-api-operator requirement is updated, and
+// appliedSpecHashOperatorVersion set to the verified version.
+const appliedSpecHashAnnotation = "operator.cluster.x-k8s.io/applied-spec-hash"
+
+// appliedSpecHashOperatorVersion is the CAPI Operator version the appliedSpecHashAnnotation was verified with.
+const appliedSpecHashOperatorVersion = "v0.28.0"
+
+const (
+	// certificateVerificationInterval is the interval between checks of certificates issued during a migration
+	// or a rotation.
+	certificateVerificationInterval = 10 * time.Second
+
+	// certificateExpiryThreshold is the remaining validity below which a webhook certificate is reported as expiring.
+	certificateExpiryThreshold = 7 * 24 * time.Hour
+)
+
+// ErrCertManagerNotInstalled is returned when cert-manager resources are requested, but cert-manager is not installed.
+var ErrCertManagerNotInstalled = errors.New("cert-manager is not installed")
+
+// certificateGVK is the cert-manager Certificate kind.
+var certificateGVK = schema.GroupVersionKind{
+	Group:   "cert-manager.io",
+	Version: "v1",
+	Kind:    "Certificate",
+}
+
+// wranglerCertificate is a certificate Secret requested from wrangler by a provider Service.
+type wranglerCertificate struct {
+	Service corev1.Service
+	Secret  *corev1.Secret
+}
+
+// listWranglerCertificates returns the certificate Secrets requested by the provider Services through the
+// `need-a-cert.cattle.io/secret-name` annotation. A nil Secret means it does not exist yet.
+func listWranglerCertificates(ctx context.Context, cl client.Client, provider *turtlesv1.CAPIProvider) ([]wranglerCertificate, error) {
+	listOpts, err := getSelector(provider)
+	if err != nil {
+		return nil, fmt.Errorf("getting selector: %w", err)
+	}
+
+	servicesList := &corev1.ServiceList{}
+	if err := cl.List(ctx, servicesList, listOpts...); err != nil {
+		return nil, fmt.Errorf("listing Services: %w", err)
+	}
+
+	certificates := []wranglerCertificate{}
+
+	for _, service := range servicesList.Items {
+		secretName, found := service.GetAnnotations()[CertificateAnnotationKey]
+		if !found {
+			continue
+		}
+
+		secret := &corev1.Secret{}
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: service.Namespace, Name: secretName}, secret); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("getting Secret %s/%s: %w", service.Namespace, secretName, err)
+			}
+
+			secret = nil
+		}
+
+		certificates = append(certificates, wranglerCertificate{Service: service, Secret: secret})
+	}
+
+	return certificates, nil
+}
+
+// verifyWranglerCertificates checks the certificates requested from wrangler by the provider Services,
+// and returns true once cert-manager resources can be safely removed.
+//
+// When the provider still has cert-manager Certificates, but no Service requests a certificate from wrangler,
+// the provider manifest is applied again so the Services are annotated. Until then, the migration is Pending.
+// The migration is Verifying until every requested Secret exists and holds a valid serving certificate.
+func verifyWranglerCertificates(ctx context.Context, cl client.Client, provider *turtlesv1.CAPIProvider) (*controller.Result, bool, error) {
+	log := log.FromContext(ctx)
+
+	certificates, err := listWranglerCertificates(ctx, cl, provider)
+	if err != nil {
+		return &controller.Result{}, false, err
+	}
+
+	if len(certificates) == 0 {
+		_, total, err := pendingCertManagerCertificates(ctx, cl, provider)
+		if err != nil && !errors.Is(err, ErrCertManagerNotInstalled) {
+			return &controller.Result{}, false, err
+		}
+
+		if total == 0 {
+			// The provider does not use certificates, or is not installed yet.
+			return &controller.Result{}, true, nil
+		}
+
+		log.Info("Applying provider manifest to request certificates from wrangler")
+
+		forceManifestApply(provider)
+		setCertificateMigration(provider, turtlesv1.WranglerIssuer, turtlesv1.CertificateMigrationPending, nil,
+			"Waiting for the provider manifest to be applied with wrangler annotations")
+
+		// Continue with the installation, which applies the patched manifest.
+		return &controller.Result{}, false, nil
+	}
+
+	pending := []string{}
+	messages := []string{}
+	now := time.Now()
+
+	for _, certificate := range certificates {
+		key := certificate.Service.Namespace + "/" + certificate.Service.Annotations[CertificateAnnotationKey]
+
+		if certificate.Secret == nil {
+			pending = append(pending, key)
+			messages = append(messages, fmt.Sprintf("secret %s does not exist", key))
+
+			continue
+		}
+
+		if _, err := parseServingCertificate(certificate.Secret, &certificate.Service, now); err != nil {
+			pending = append(pending, key)
+			messages = append(messages, err.Error())
+		}
+	}
+
+	if len(pending) > 0 {
+		log.Info("Waiting for wrangler certificates", "pending", pending)
+
+		setCertificateMigration(provider, turtlesv1.WranglerIssuer, turtlesv1.CertificateMigrationVerifying, pending,
+			strings.Join(messages, "; "))
+
+		return &controller.Result{RequeueAfter: certificateVerificationInterval}, false, nil
+	}
+
+	return &controller.Result{}, true, nil
+}
+
+// CheckWebhookCertificates inspects the webhook serving certificates issued by wrangler for the provider Services,
+// and reports their validity and expiry in metrics and in the WebhookCertificatesValid condition.
+// The certificates are checked on each provider reconcile, including the periodic resync.
+//
+// When the provider has the `turtles-capi.cattle.io/rotate-certificates` annotation, the certificate Secrets are
+// deleted so wrangler issues new ones. Once all certificates are valid again, provider pods are restarted
+// to load them.
+func CheckWebhookCertificates(ctx context.Context, cl client.Client, provider *turtlesv1.CAPIProvider) (*controller.Result, error) {
+	log := log.FromContext(ctx)
+
+	if !conditions.IsTrue(provider, string(turtlesv1.CAPIProviderWranglerManagedCertificatesCondition)) {
+		// Certificates are not managed by wrangler. Nothing to do.
+		conditions.Delete(provider, string(turtlesv1.CAPIProviderWebhookCertificatesCondition))
+		deleteStaleWebhookCertificateMetrics(provider, sets.New[string]())
+
+		return &controller.Result{}, nil
+	}
+
+	certificates, err := listWranglerCertificates(ctx, cl, provider)
+	if err != nil {
+		return &controller.Result{}, err
+	}
+
+	secretNames := sets.New[string]()
+	for _, certificate := range certificates {
+		secretNames.Insert(certificate.Service.Annotations[CertificateAnnotationKey])
+	}
+
+	deleteStaleWebhookCertificateMetrics(provider, secretNames)
+
+	if len(certificates) == 0 {
+		conditions.Delete(provider, string(turtlesv1.CAPIProviderWebhookCertificatesCondition))
+
+		return &controller.Result{}, nil
+	}
+
+	if turtlesannotations.HasRotateCertificatesAnnotation(provider) {
+		for _, certificate := range certificates {
+			if certificate.Secret == nil {
+				continue
+			}
+
+			log.Info("Deleting webhook certificate Secret to regenerate it", "secretName", certificate.Secret.Name)
+
+			if err := cl.Delete(ctx, certificate.Secret); client.IgnoreNotFound(err) != nil {
+				return &controller.Result{}, fmt.Errorf("deleting Secret %s/%s: %w", certificate.Secret.Namespace, certificate.Secret.Name, err)
+			}
+		}
+
+		annotations := provider.GetAnnotations()
+		delete(annotations, turtlesannotations.RotateCertificatesAnnotation)
+		provider.SetAnnotations(annotations)
+
+		conditions.Set(provider, metav1.Condition{
+			Type:    string(turtlesv1.CAPIProviderWebhookCertificatesCondition),
+			Status:  metav1.ConditionFalse,
+			Reason:  turtlesv1.WebhookCertificatesRotatingReason,
+			Message: "Waiting for wrangler to issue new webhook certificates",
+		})
+
+		return &controller.Result{RequeueAfter: certificateVerificationInterval}, nil
+	}
+
+	invalid := []string{}
+	expiring := []string{}
+	now := time.Now()
+
+	for _, certificate := range certificates {
+		secretName := certificate.Service.Annotations[CertificateAnnotationKey]
+		labels := prometheus.Labels{"namespace": provider.GetNamespace(), "provider": provider.GetName(), "secret": secretName}
+
+		if certificate.Secret == nil {
+			webhookCertificateExpiry.Delete(labels)
+			webhookCertificateValid.With(labels).Set(0)
+			invalid = append(invalid, fmt.Sprintf("secret %s/%s does not exist", certificate.Service.Namespace, secretName))
+
+			continue
+		}
+
+		cert, err := parseServingCertificate(certificate.Secret, &certificate.Service, now)
+		if cert != nil {
+			webhookCertificateExpiry.With(labels).Set(float64(cert.NotAfter.Unix()))
+		} else {
+			webhookCertificateExpiry.Delete(labels)
+		}
+
+		if err != nil {
+			webhookCertificateValid.With(labels).Set(0)
+			invalid = append(invalid, err.Error())
+
+			continue
+		}
+
+		webhookCertificateValid.With(labels).Set(1)
+
+		if cert.NotAfter.Sub(now) < certificateExpiryThreshold {
+			expiring = append(expiring, fmt.Sprintf("certificate in secret %s/%s expires at %s",
+				certificate.Secret.Namespace, certificate.Secret.Name, cert.NotAfter.Format(time.RFC3339)))
+		}
+	}
+
+	if conditions.GetReason(provider, string(turtlesv1.CAPIProviderWebhookCertificatesCondition)) ==
+		turtlesv1.WebhookCertificatesRotatingReason {
+		if len(invalid) > 0 {
+			log.Info("Waiting for wrangler to issue new webhook certificates", "pending", invalid)
+
+			return &controller.Result{RequeueAfter: certificateVerificationInterval}, nil
+		}
+
+		if err := providerDeploymentRestart(ctx, cl, provider); err != nil {
+			return &controller.Result{}, fmt.Errorf("restarting Deployment: %w", err)
+		}
+	}
+
+	switch {
+	case len(invalid) > 0:
+		conditions.Set(provider, metav1.Condition{
+			Type:    string(turtlesv1.CAPIProviderWebhookCertificatesCondition),
+			Status:  metav1.ConditionFalse,
+			Reason:  turtlesv1.WebhookCertificatesInvalidReason,
+			Message: strings.Join(invalid, "; "),
+		})
+	case len(expiring) > 0:
+		conditions.Set(provider, metav1.Condition{
+			Type:    string(turtlesv1.CAPIProviderWebhookCertificatesCondition),
+			Status:  metav1.ConditionFalse,
+			Reason:  turtlesv1.WebhookCertificatesExpiringReason,
+			Message: strings.Join(expiring, "; "),
+		})
+	default:
+		conditions.Set(provider, metav1.Condition{
+			Type:   string(turtlesv1.CAPIProviderWebhookCertificatesCondition),
+			Status: metav1.ConditionTrue,
+			Reason: turtlesv1.WebhookCertificatesValidReason,
+		})
+	}
+
+	return &controller.Result{}, nil
+}
+
+// parseServingCertificate validates the serving certificate in a TLS Secret of a Service, and returns the parsed certificate.
+// The certificate must match the private key, be currently valid, and be issued for the Service DNS name.
+func parseServingCertificate(secret *corev1.Secret, service *corev1.Service, now time.Time) (*x509.Certificate, error) {
+	certPEM, keyPEM := secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return nil, fmt.Errorf("secret %s/%s is missing %s or %s", secret.Namespace, secret.Name, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	}
+
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return nil, fmt.Errorf("secret %s/%s holds an invalid key pair: %w", secret.Namespace, secret.Name, err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("secret %s/%s holds no PEM encoded certificate", secret.Namespace, secret.Name)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate in secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return cert, fmt.Errorf("certificate in secret %s/%s is not valid at %s", secret.Namespace, secret.Name, now.Format(time.RFC3339))
+	}
+
+	dnsName := fmt.Sprintf("%s.%s.svc", service.Name, service.Namespace)
+	if err := cert.VerifyHostname(dnsName); err != nil {
+		return cert, fmt.Errorf("certificate in secret %s/%s is not valid for %s: %w", secret.Namespace, secret.Name, dnsName, err)
+	}
+
+	return cert, nil
+}
+
+// pendingCertManagerCertificates returns the names of the provider cert-manager Certificates which are not Ready,
+// and the total number of provider Certificates.
+func pendingCertManagerCertificates(ctx context.Context, cl client.Client, provider *turtlesv1.CAPIProvider) ([]string, int, error) {
+	listOpts, err := getSelector(provider)
+	if err != nil {
+		return nil, 0, fmt.Errorf("getting selector: %w", err)
+	}
+
+	certList := &unstructured.UnstructuredList{}
+	certList.SetGroupVersionKind(certificateGVK)
+
+	if err := cl.List(ctx, certList, listOpts...); err != nil {
+		if apimeta.IsNoMatchError(err) {
+			return nil, 0, ErrCertManagerNotInstalled
+		}
+
+		return nil, 0, fmt.Errorf("listing Certificates: %w", err)
+	}
+
+	pending := []string{}
+
+	for _, cert := range certList.Items {
+		if !certificateReady(cert) {
+			pending = append(pending, cert.GetNamespace()+"/"+cert.GetName())
+		}
+	}
+
+	return pending, len(certList.Items), nil
+}
+
+func certificateReady(cert unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(cert.Object, "status", "conditions")
+
+	for _, c := range conditions {
+		condition, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if condition["type"] == "Ready" && condition["status"] == string(metav1.ConditionTrue) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setCertificateMigration records the certificate migration progress in the provider status.
+func setCertificateMigration(
+	provider *turtlesv1.CAPIProvider,
+	target turtlesv1.CertificateIssuer,
+	phase turtlesv1.CertificateMigrationPhase,
+	pending []string,
+	message string,
+) {
+	current := provider.Status.CertificateMigration
+
+	transitionTime := metav1.Now()
+	if current != nil && current.Target == target && current.Phase == phase {
+		transitionTime = current.LastTransitionTime
+	}
+
+	provider.Status.CertificateMigration = &turtlesv1.CertificateMigrationStatus{
+		Target:              target,
+		Phase:               phase,
+		PendingCertificates: pending,
+		Message:             message,
+		LastTransitionTime:  transitionTime,
+	}
+}
+
+// forceManifestApply makes the CAPI Operator apply the provider manifest, instead of restoring it from the cache.
+func forceManifestApply(provider *turtlesv1.CAPIProvider) {
+	annotations := provider.GetAnnotations()
+	if _, ok := annotations[appliedSpecHashAnnotation]; !ok {
+		return
+	}
+
+	delete(annotations, appliedSpecHashAnnotation)
+	provider.SetAnnotations(annotations)
+}
@@ -0,0 +1,408 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"runtime/debug"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+func servingCertificateSecret(name, namespace, dnsName string, notAfter time.Time) *corev1.Secret {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		},
+	}
+}
+
+func certManagerCertificate(name, namespace string, ready bool) *unstructured.Unstructured {
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(certificateGVK)
+	cert.SetName(name)
+	cert.SetNamespace(namespace)
+	cert.SetLabels(map[string]string{CAPIProviderLabel: "infrastructure-docker"})
+
+	status := string(metav1.ConditionFalse)
+	if ready {
+		status = string(metav1.ConditionTrue)
+	}
+
+	Expect(unstructured.SetNestedSlice(cert.Object, []any{
+		map[string]any{"type": "Ready", "status": status},
+	}, "status", "conditions")).To(Succeed())
+
+	return cert
+}
+
+var _ = Describe("Certificate migration", func() {
+	var (
+		testScheme   *runtime.Scheme
+		capiProvider *turtlesv1.CAPIProvider
+		webhookSvc   *corev1.Service
+	)
+
+	BeforeEach(func() {
+		testScheme = runtime.NewScheme()
+		Expect(corev1.AddToScheme(testScheme)).To(Succeed())
+		Expect(appsv1.AddToScheme(testScheme)).To(Succeed())
+		Expect(turtlesv1.AddToScheme(testScheme)).To(Succeed())
+
+		capiProvider = &turtlesv1.CAPIProvider{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "docker",
+				Namespace:   "capd-system",
+				Annotations: map[string]string{appliedSpecHashAnnotation: "hash"},
+			},
+			Spec: turtlesv1.CAPIProviderSpec{
+				Name: "docker",
+				Type: turtlesv1.Infrastructure,
+			},
+		}
+
+		webhookSvc = &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "capd-webhook-service",
+				Namespace: "capd-system",
+				Labels:    map[string]string{CAPIProviderLabel: "infrastructure-docker"},
+			},
+		}
+	})
+
+	It("should apply the provider manifest again before requesting wrangler certificates", func() {
+		cl := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+			webhookSvc, certManagerCertificate("capd-serving-cert", "capd-system", true),
+		).Build()
+
+		res, err := CleanupCertManagerResources(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.IsZero()).To(BeTrue())
+
+		Expect(capiProvider.Annotations).NotTo(HaveKey(appliedSpecHashAnnotation))
+		Expect(capiProvider.Status.CertificateMigration).NotTo(BeNil())
+		Expect(capiProvider.Status.CertificateMigration.Target).To(Equal(turtlesv1.WranglerIssuer))
+		Expect(capiProvider.Status.CertificateMigration.Phase).To(Equal(turtlesv1.CertificateMigrationPending))
+		Expect(conditions.Has(capiProvider, string(turtlesv1.CAPIProviderWranglerManagedCertificatesCondition))).To(BeFalse())
+	})
+
+	It("should keep cert-manager resources until the wrangler certificate is issued", func() {
+		webhookSvc.Annotations = map[string]string{CertificateAnnotationKey: "capd-webhook-service-cert"}
+		cert := certManagerCertificate("capd-serving-cert", "capd-system", true)
+
+		cl := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(webhookSvc, cert).Build()
+
+		res, err := CleanupCertManagerResources(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(certificateVerificationInterval))
+
+		Expect(capiProvider.Status.CertificateMigration.Phase).To(Equal(turtlesv1.CertificateMigrationVerifying))
+		Expect(capiProvider.Status.CertificateMigration.PendingCertificates).To(ConsistOf("capd-system/capd-webhook-service-cert"))
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(cert), cert)).To(Succeed())
+	})
+
+	It("should remove cert-manager resources once the wrangler certificate is valid", func() {
+		webhookSvc.Annotations = map[string]string{CertificateAnnotationKey: "capd-webhook-service-cert"}
+		cert := certManagerCertificate("capd-serving-cert", "capd-system", true)
+		secret := servingCertificateSecret("capd-webhook-service-cert", "capd-system",
+			"capd-webhook-service.capd-system.svc", time.Now().Add(time.Hour))
+
+		cl := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(webhookSvc, cert, secret).Build()
+
+		res, err := CleanupCertManagerResources(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.IsZero()).To(BeTrue())
+
+		Expect(capiProvider.Status.CertificateMigration.Phase).To(Equal(turtlesv1.CertificateMigrationCompleted))
+		Expect(conditions.IsTrue(capiProvider, string(turtlesv1.CAPIProviderWranglerManagedCertificatesCondition))).To(BeTrue())
+
+		err = cl.Get(ctx, client.ObjectKeyFromObject(cert), cert)
+		Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should revert to cert-manager and wait for Certificates to be ready", func() {
+		webhookSvc.Annotations = map[string]string{CertificateAnnotationKey: "capd-webhook-service-cert"}
+		conditions.Set(capiProvider, metav1.Condition{
+			Type:   string(turtlesv1.CAPIProviderWranglerManagedCertificatesCondition),
+			Status: metav1.ConditionTrue,
+			Reason: "CertificatesManaged",
+		})
+
+		cl := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(webhookSvc).Build()
+
+		_, err := CleanupWranglerResources(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(webhookSvc), webhookSvc)).To(Succeed())
+		Expect(webhookSvc.Annotations).NotTo(HaveKey(CertificateAnnotationKey))
+		Expect(capiProvider.Annotations).NotTo(HaveKey(appliedSpecHashAnnotation))
+		Expect(capiProvider.Status.CertificateMigration.Target).To(Equal(turtlesv1.CertManagerIssuer))
+		Expect(capiProvider.Status.CertificateMigration.Phase).To(Equal(turtlesv1.CertificateMigrationPending))
+
+		// The provider manifest is applied with a cert-manager Certificate, which is not ready yet.
+		cert := certManagerCertificate("capd-serving-cert", "capd-system", false)
+		Expect(cl.Create(ctx, cert)).To(Succeed())
+
+		res, err := CleanupWranglerResources(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(certificateVerificationInterval))
+		Expect(capiProvider.Status.CertificateMigration.Phase).To(Equal(turtlesv1.CertificateMigrationVerifying))
+		Expect(capiProvider.Status.CertificateMigration.PendingCertificates).To(ConsistOf("capd-system/capd-serving-cert"))
+
+		Expect(unstructured.SetNestedSlice(cert.Object, []any{
+			map[string]any{"type": "Ready", "status": string(metav1.ConditionTrue)},
+		}, "status", "conditions")).To(Succeed())
+		Expect(cl.Update(ctx, cert)).To(Succeed())
+
+		res, err = CleanupWranglerResources(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.IsZero()).To(BeTrue())
+		Expect(capiProvider.Status.CertificateMigration.Phase).To(Equal(turtlesv1.CertificateMigrationCompleted))
+	})
+
+	It("should not complete the revert to cert-manager before Certificates exist", func() {
+		capiProvider.Status.CertificateMigration = &turtlesv1.CertificateMigrationStatus{
+			Target: turtlesv1.CertManagerIssuer,
+			Phase:  turtlesv1.CertificateMigrationPending,
+		}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "capd-controller-manager",
+				Namespace: "capd-system",
+				Labels:    map[string]string{CAPIProviderLabel: "infrastructure-docker"},
+			},
+		}
+
+		cl := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(webhookSvc, deployment).Build()
+
+		res, err := CleanupWranglerResources(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(certificateVerificationInterval))
+		Expect(capiProvider.Status.CertificateMigration.Phase).To(Equal(turtlesv1.CertificateMigrationVerifying))
+		Expect(capiProvider.Status.CertificateMigration.PendingCertificates).To(BeEmpty())
+
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+		Expect(deployment.Spec.Template.Annotations).To(BeEmpty())
+	})
+
+	It("should do nothing when the provider was never converted to wrangler", func() {
+		cl := fake.NewClientBuilder().WithScheme(testScheme).Build()
+
+		res, err := CleanupWranglerResources(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.IsZero()).To(BeTrue())
+		Expect(capiProvider.Status.CertificateMigration).To(BeNil())
+		Expect(capiProvider.Annotations).To(HaveKey(appliedSpecHashAnnotation))
+	})
+
+	It("should reject invalid serving certificates", func() {
+		svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "default"}}
+
+		valid := servingCertificateSecret("cert", "default", "webhook.default.svc", time.Now().Add(time.Hour))
+		_, err := parseServingCertificate(valid, svc, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+
+		expired := servingCertificateSecret("cert", "default", "webhook.default.svc", time.Now().Add(time.Hour))
+		_, err = parseServingCertificate(expired, svc, time.Now().Add(2*time.Hour))
+		Expect(err).To(MatchError(ContainSubstring("is not valid at")))
+
+		wrongHost := servingCertificateSecret("cert", "default", "other.default.svc", time.Now().Add(time.Hour))
+		_, err = parseServingCertificate(wrongHost, svc, time.Now())
+		Expect(err).To(MatchError(ContainSubstring("is not valid for webhook.default.svc")))
+
+		mismatched := servingCertificateSecret("cert", "default", "webhook.default.svc", time.Now().Add(time.Hour))
+		mismatched.Data[corev1.TLSPrivateKeyKey] = wrongHost.Data[corev1.TLSPrivateKeyKey]
+		_, err = parseServingCertificate(mismatched, svc, time.Now())
+		Expect(err).To(MatchError(ContainSubstring("invalid key pair")))
+
+		delete(valid.Data, corev1.TLSCertKey)
+		_, err = parseServingCertificate(valid, svc, time.Now())
+		Expect(err).To(MatchError(ContainSubstring("is missing")))
+	})
+
+	It("should report webhook certificate expiry and rotate certificates on request", func() {
+		webhookSvc.Annotations = map[string]string{CertificateAnnotationKey: "capd-webhook-service-cert"}
+		conditions.Set(capiProvider, metav1.Condition{
+			Type:   string(turtlesv1.CAPIProviderWranglerManagedCertificatesCondition),
+			Status: metav1.ConditionTrue,
+			Reason: "CertificatesManaged",
+		})
+
+		secret := servingCertificateSecret("capd-webhook-service-cert", "capd-system",
+			"capd-webhook-service.capd-system.svc", time.Now().Add(24*time.Hour))
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "capd-controller-manager",
+				Namespace: "capd-system",
+				Labels:    map[string]string{CAPIProviderLabel: "infrastructure-docker"},
+			},
+		}
+
+		cl := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(webhookSvc, secret, deployment).Build()
+
+		res, err := CheckWebhookCertificates(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.IsZero()).To(BeTrue())
+		Expect(conditions.GetReason(capiProvider, string(turtlesv1.CAPIProviderWebhookCertificatesCondition))).
+			To(Equal(turtlesv1.WebhookCertificatesExpiringReason))
+
+		labels := prometheus.Labels{"namespace": "capd-system", "provider": "docker", "secret": "capd-webhook-service-cert"}
+		Expect(testutil.ToFloat64(webhookCertificateValid.With(labels))).To(Equal(float64(1)))
+		Expect(testutil.ToFloat64(webhookCertificateExpiry.With(labels))).To(BeNumerically(">", float64(time.Now().Unix())))
+
+		capiProvider.Annotations[turtlesannotations.RotateCertificatesAnnotation] = ""
+
+		res, err = CheckWebhookCertificates(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(certificateVerificationInterval))
+		Expect(capiProvider.Annotations).NotTo(HaveKey(turtlesannotations.RotateCertificatesAnnotation))
+		Expect(conditions.GetReason(capiProvider, string(turtlesv1.CAPIProviderWebhookCertificatesCondition))).
+			To(Equal(turtlesv1.WebhookCertificatesRotatingReason))
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(secret), &corev1.Secret{})).NotTo(Succeed())
+
+		// Rotation is in progress until wrangler issues a new certificate.
+		res, err = CheckWebhookCertificates(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(certificateVerificationInterval))
+
+		Expect(cl.Create(ctx, servingCertificateSecret("capd-webhook-service-cert", "capd-system",
+			"capd-webhook-service.capd-system.svc", time.Now().Add(365*24*time.Hour)))).To(Succeed())
+
+		res, err = CheckWebhookCertificates(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.IsZero()).To(BeTrue())
+		Expect(conditions.IsTrue(capiProvider, string(turtlesv1.CAPIProviderWebhookCertificatesCondition))).To(BeTrue())
+
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+		Expect(deployment.Spec.Template.Annotations).To(HaveKey("kubectl.kubernetes.io/restartedAt"))
+
+		DeleteWebhookCertificateMetrics(capiProvider)
+		Expect(testutil.CollectAndCount(webhookCertificateValid)).To(BeZero())
+	})
+
+	It("should only remove the webhook certificate metrics of certificates which no longer exist", func() {
+		DeferCleanup(DeleteWebhookCertificateMetrics, capiProvider)
+
+		webhookSvc.Annotations = map[string]string{CertificateAnnotationKey: "capd-webhook-service-cert"}
+		conditions.Set(capiProvider, metav1.Condition{
+			Type:   string(turtlesv1.CAPIProviderWranglerManagedCertificatesCondition),
+			Status: metav1.ConditionTrue,
+			Reason: "CertificatesManaged",
+		})
+
+		cl := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(webhookSvc,
+			servingCertificateSecret("capd-webhook-service-cert", "capd-system",
+				"capd-webhook-service.capd-system.svc", time.Now().Add(365*24*time.Hour)),
+			servingCertificateSecret("capd-webhook-service-new-cert", "capd-system",
+				"capd-webhook-service.capd-system.svc", time.Now().Add(365*24*time.Hour)),
+		).Build()
+
+		oldLabels := prometheus.Labels{"namespace": "capd-system", "provider": "docker", "secret": "capd-webhook-service-cert"}
+		newLabels := prometheus.Labels{"namespace": "capd-system", "provider": "docker", "secret": "capd-webhook-service-new-cert"}
+
+		_, err := CheckWebhookCertificates(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testutil.ToFloat64(webhookCertificateValid.With(oldLabels))).To(Equal(float64(1)))
+
+		// Metrics of existing certificates are kept between reconciles.
+		_, err = CheckWebhookCertificates(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testutil.CollectAndCount(webhookCertificateValid)).To(Equal(1))
+
+		webhookSvc.Annotations[CertificateAnnotationKey] = "capd-webhook-service-new-cert"
+		Expect(cl.Update(ctx, webhookSvc)).To(Succeed())
+
+		_, err = CheckWebhookCertificates(ctx, cl, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testutil.CollectAndCount(webhookCertificateValid)).To(Equal(1))
+		Expect(testutil.CollectAndCount(webhookCertificateExpiry)).To(Equal(1))
+		Expect(webhookCertificateValid.Delete(oldLabels)).To(BeFalse())
+		Expect(testutil.ToFloat64(webhookCertificateValid.With(newLabels))).To(Equal(float64(1)))
+	})
+})
+
+var _ = Describe("Provider manifest apply", func() {
+	It("should rely on the applied spec hash annotation of the verified CAPI Operator version", func() {
+		info, ok := debug.ReadBuildInfo()
+		Expect(ok).To(BeTrue())
+
+		var operatorVersion string
+		for _, dep := range info.Deps {
+			if dep.Path == "sigs.k8s.io/cluster-api-operator" {
+				operatorVersion = dep.Version
+			}
+		}
+
+		Expect(operatorVersion).To(Equal(appliedSpecHashOperatorVersion),
+			"verify the %s annotation in the CAPI Operator sources and update appliedSpecHashOperatorVersion", appliedSpecHashAnnotation)
+	})
+
+	It("should remove the applied spec hash annotation to force the manifest apply", func() {
+		provider := &turtlesv1.CAPIProvider{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{appliedSpecHashAnnotation: "hash", "other": "value"},
+			},
+		}
+
+		forceManifestApply(provider)
+		Expect(provider.Annotations).To(Equal(map[string]string{"other": "value"}))
+	})
+})
@@ -0,0 +1,85 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+)
+
+var (
+	webhookCertificateExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "turtles_provider_webhook_certificate_expiry_timestamp_seconds",
+		Help: "Expiry time of a CAPI provider webhook serving certificate issued by wrangler, in seconds since the Unix epoch.",
+	}, []string{"namespace", "provider", "secret"})
+
+	webhookCertificateValid = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "turtles_provider_webhook_certificate_valid",
+		Help: "Whether a CAPI provider webhook serving certificate issued by wrangler exists and is valid for the webhook Service.",
+	}, []string{"namespace", "provider", "secret"})
+
+	// reportedWebhookCertificates holds the certificate Secrets with reported metrics, by provider.
+	reportedWebhookCertificates   = map[client.ObjectKey]sets.Set[string]{}
+	reportedWebhookCertificatesMu sync.Mutex
+)
+
+func init() {
+	metrics.Registry.MustRegister(webhookCertificateExpiry, webhookCertificateValid)
+}
+
+// DeleteWebhookCertificateMetrics removes all webhook certificate metrics of the provider, when it is deleted.
+func DeleteWebhookCertificateMetrics(provider *turtlesv1.CAPIProvider) {
+	reportedWebhookCertificatesMu.Lock()
+	defer reportedWebhookCertificatesMu.Unlock()
+
+	labels := prometheus.Labels{"namespace": provider.GetNamespace(), "provider": provider.GetName()}
+
+	webhookCertificateExpiry.DeletePartialMatch(labels)
+	webhookCertificateValid.DeletePartialMatch(labels)
+
+	delete(reportedWebhookCertificates, client.ObjectKeyFromObject(provider))
+}
+
+// deleteStaleWebhookCertificateMetrics removes the webhook certificate metrics of the provider for the certificate
+// Secrets which are no longer referenced by its Services, and records the referenced ones.
+func deleteStaleWebhookCertificateMetrics(provider *turtlesv1.CAPIProvider, secretNames sets.Set[string]) {
+	reportedWebhookCertificatesMu.Lock()
+	defer reportedWebhookCertificatesMu.Unlock()
+
+	key := client.ObjectKeyFromObject(provider)
+
+	for _, secretName := range reportedWebhookCertificates[key].Difference(secretNames).UnsortedList() {
+		labels := prometheus.Labels{"namespace": provider.GetNamespace(), "provider": provider.GetName(), "secret": secretName}
+
+		webhookCertificateExpiry.Delete(labels)
+		webhookCertificateValid.Delete(labels)
+	}
+
+	if secretNames.Len() == 0 {
+		delete(reportedWebhookCertificates, key)
+
+		return
+	}
+
+	reportedWebhookCertificates[key] = secretNames
+}
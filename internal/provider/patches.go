@@ -0,0 +1,259 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+// ManifestPatches is a PostProcessor applying patches from ConfigMaps to provider manifests.
+//
+// Each key of a ConfigMap holds a patch in the YAML format, with the same fields as the CAPIProvider `spec.patches`.
+// Patches are either RFC 6902 JSON patches, or strategic merge patches. Strategic merge patches fall back to
+// JSON merge patches for kinds unknown to Kubernetes, like CRDs. Patches are applied in the order of the ConfigMap keys.
+//
+// Patches from the shared ConfigMap are applied to every provider, followed by patches from the ConfigMap named
+// by the `turtles-capi.cattle.io/manifest-patches` annotation on the provider. Both are applied after the
+// provider `spec.patches`.
+type ManifestPatches struct {
+	Client client.Reader
+	// ConfigMap references a ConfigMap with patches applied to the manifest of every provider.
+	ConfigMap client.ObjectKey
+}
+
+// Name returns the name of the post processor.
+func (p *ManifestPatches) Name() string {
+	return "manifest-patches"
+}
+
+// PostProcess applies the patches configured for the provider.
+func (p *ManifestPatches) PostProcess(
+	ctx context.Context, provider *turtlesv1.CAPIProvider, objs []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	patches, err := p.Patches(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return ApplyManifestPatches(objs, patches)
+}
+
+// Patches returns the patches configured for the provider.
+func (p *ManifestPatches) Patches(ctx context.Context, provider *turtlesv1.CAPIProvider) ([]operatorv1.Patch, error) {
+	patches := []operatorv1.Patch{}
+
+	if p.ConfigMap.Name != "" {
+		shared, err := p.readPatches(ctx, p.ConfigMap)
+		if apierrors.IsNotFound(err) {
+			// The shared ConfigMap is optional, it may be created at any time.
+			log.FromContext(ctx).V(4).Info("Shared manifest patches ConfigMap not found", "configMap", p.ConfigMap)
+		} else if err != nil {
+			return nil, err
+		}
+
+		patches = append(patches, shared...)
+	}
+
+	if name := provider.GetAnnotations()[turtlesannotations.ManifestPatchesAnnotation]; name != "" {
+		own, err := p.readPatches(ctx, client.ObjectKey{Namespace: provider.GetNamespace(), Name: name})
+		if err != nil {
+			return nil, err
+		}
+
+		patches = append(patches, own...)
+	}
+
+	return patches, nil
+}
+
+// Sync makes the CAPI Operator apply the provider manifest again when the configured patches changed
+// since the manifest was last applied. Otherwise, the manifest would be restored from the cache without them.
+func (p *ManifestPatches) Sync(ctx context.Context, provider *turtlesv1.CAPIProvider) error {
+	patches, err := p.Patches(ctx, provider)
+	if err != nil {
+		return err
+	}
+
+	hash := ""
+
+	if len(patches) > 0 {
+		data, err := json.Marshal(patches)
+		if err != nil {
+			return fmt.Errorf("marshalling manifest patches: %w", err)
+		}
+
+		sum := sha256.Sum256(data)
+		hash = hex.EncodeToString(sum[:])
+	}
+
+	annotations := provider.GetAnnotations()
+	if annotations[turtlesannotations.ManifestPatchesHashAnnotation] == hash {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("Manifest patches changed, applying provider manifest again")
+
+	forceManifestApply(provider)
+
+	annotations = provider.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	if hash == "" {
+		delete(annotations, turtlesannotations.ManifestPatchesHashAnnotation)
+	} else {
+		annotations[turtlesannotations.ManifestPatchesHashAnnotation] = hash
+	}
+
+	provider.SetAnnotations(annotations)
+
+	return nil
+}
+
+func (p *ManifestPatches) readPatches(ctx context.Context, key client.ObjectKey) ([]operatorv1.Patch, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := p.Client.Get(ctx, key, configMap); err != nil {
+		return nil, fmt.Errorf("getting manifest patches ConfigMap %s: %w", key, err)
+	}
+
+	names := make([]string, 0, len(configMap.Data))
+	for name := range configMap.Data {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	patches := make([]operatorv1.Patch, 0, len(names))
+
+	for _, name := range names {
+		patch := operatorv1.Patch{}
+		if err := yaml.UnmarshalStrict([]byte(configMap.Data[name]), &patch); err != nil {
+			return nil, fmt.Errorf("parsing manifest patch %s in ConfigMap %s: %w", name, key, err)
+		}
+
+		patches = append(patches, patch)
+	}
+
+	return patches, nil
+}
+
+// ApplyManifestPatches applies the patches to the matching manifest components.
+func ApplyManifestPatches(objs []unstructured.Unstructured, patches []operatorv1.Patch) ([]unstructured.Unstructured, error) {
+	for i, patch := range patches {
+		patchJSON, err := yaml.YAMLToJSON([]byte(patch.Patch))
+		if err != nil {
+			return nil, fmt.Errorf("patch %d: converting YAML to JSON: %w", i, err)
+		}
+
+		selector := labels.Everything()
+
+		if patch.Target != nil && patch.Target.LabelSelector != "" {
+			selector, err = labels.Parse(patch.Target.LabelSelector)
+			if err != nil {
+				return nil, fmt.Errorf("patch %d: parsing label selector %q: %w", i, patch.Target.LabelSelector, err)
+			}
+		}
+
+		for j := range objs {
+			obj := &objs[j]
+
+			if !patchTargetMatches(obj, patch.Target, selector) {
+				continue
+			}
+
+			if err := applyManifestPatch(obj, patchJSON); err != nil {
+				return nil, fmt.Errorf("patch %d: applying to %s %s/%s: %w", i, obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+	}
+
+	return objs, nil
+}
+
+func patchTargetMatches(obj *unstructured.Unstructured, target *operatorv1.PatchSelector, selector labels.Selector) bool {
+	if target == nil {
+		return true
+	}
+
+	gvk := obj.GroupVersionKind()
+
+	switch {
+	case target.Group != "" && target.Group != gvk.Group,
+		target.Version != "" && target.Version != gvk.Version,
+		target.Kind != "" && target.Kind != gvk.Kind,
+		target.Name != "" && target.Name != obj.GetName(),
+		target.Namespace != "" && target.Namespace != obj.GetNamespace():
+		return false
+	}
+
+	return selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+func applyManifestPatch(obj *unstructured.Unstructured, patchJSON []byte) error {
+	objJSON, err := obj.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("marshalling object: %w", err)
+	}
+
+	var patched []byte
+
+	var operations []json.RawMessage
+	if json.Unmarshal(patchJSON, &operations) == nil {
+		patch, err := jsonpatch.DecodePatch(patchJSON)
+		if err != nil {
+			return fmt.Errorf("decoding JSON patch: %w", err)
+		}
+
+		if patched, err = patch.Apply(objJSON); err != nil {
+			return fmt.Errorf("applying JSON patch: %w", err)
+		}
+	} else if typed, err := scheme.Scheme.New(obj.GroupVersionKind()); err == nil {
+		if patched, err = strategicpatch.StrategicMergePatch(objJSON, patchJSON, typed); err != nil {
+			return fmt.Errorf("applying strategic merge patch: %w", err)
+		}
+	} else if patched, err = jsonpatch.MergePatch(objJSON, patchJSON); err != nil {
+		return fmt.Errorf("applying merge patch: %w", err)
+	}
+
+	if err := obj.UnmarshalJSON(patched); err != nil {
+		return fmt.Errorf("unmarshalling patched object: %w", err)
+	}
+
+	return nil
+}
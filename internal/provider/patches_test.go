@@ -0,0 +1,219 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+func manifestComponents() []unstructured.Unstructured {
+	deployment := unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":      "capd-controller-manager",
+			"namespace": "capd-system",
+			"labels":    map[string]any{"control-plane": "controller-manager"},
+		},
+		"spec": map[string]any{
+			"template": map[string]any{
+				"spec": map[string]any{
+					"containers": []any{
+						map[string]any{
+							"name":  "manager",
+							"image": "capd:v1.13.0",
+							"env":   []any{map[string]any{"name": "EXISTING", "value": "true"}},
+						},
+					},
+				},
+			},
+		},
+	}}
+
+	namespace := unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]any{"name": "capd-system"},
+	}}
+
+	return []unstructured.Unstructured{deployment, namespace}
+}
+
+var _ = Describe("Manifest patches", func() {
+	var (
+		testScheme   *runtime.Scheme
+		capiProvider *turtlesv1.CAPIProvider
+		sharedKey    client.ObjectKey
+	)
+
+	BeforeEach(func() {
+		testScheme = runtime.NewScheme()
+		Expect(corev1.AddToScheme(testScheme)).To(Succeed())
+		Expect(turtlesv1.AddToScheme(testScheme)).To(Succeed())
+
+		capiProvider = &turtlesv1.CAPIProvider{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "docker",
+				Namespace:   "capd-system",
+				Annotations: map[string]string{appliedSpecHashAnnotation: "hash"},
+			},
+			Spec: turtlesv1.CAPIProviderSpec{
+				Name: "docker",
+				Type: turtlesv1.Infrastructure,
+			},
+		}
+
+		sharedKey = client.ObjectKey{Namespace: "rancher-turtles-system", Name: "provider-patches"}
+	})
+
+	It("should merge containers with a strategic merge patch", func() {
+		patched, err := ApplyManifestPatches(manifestComponents(), []operatorv1.Patch{{
+			Target: &operatorv1.PatchSelector{Kind: "Deployment"},
+			Patch: `
+spec:
+  template:
+    spec:
+      containers:
+      - name: manager
+        env:
+        - name: HTTPS_PROXY
+          value: http://proxy:3128
+`,
+		}})
+		Expect(err).NotTo(HaveOccurred())
+
+		containers, _, err := unstructured.NestedSlice(patched[0].Object, "spec", "template", "spec", "containers")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(containers).To(HaveLen(1))
+
+		container := containers[0].(map[string]any)
+		Expect(container["image"]).To(Equal("capd:v1.13.0"))
+		Expect(container["env"]).To(ConsistOf(
+			map[string]any{"name": "EXISTING", "value": "true"},
+			map[string]any{"name": "HTTPS_PROXY", "value": "http://proxy:3128"},
+		))
+	})
+
+	It("should apply JSON patches to the matching objects only", func() {
+		patched, err := ApplyManifestPatches(manifestComponents(), []operatorv1.Patch{{
+			Target: &operatorv1.PatchSelector{Kind: "Namespace"},
+			Patch: `
+- op: add
+  path: /metadata/labels
+  value:
+    pod-security.kubernetes.io/enforce: restricted
+`,
+		}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(patched[0].GetLabels()).NotTo(HaveKey("pod-security.kubernetes.io/enforce"))
+		Expect(patched[1].GetLabels()).To(HaveKeyWithValue("pod-security.kubernetes.io/enforce", "restricted"))
+	})
+
+	It("should apply shared patches before the provider patches", func() {
+		cl := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: sharedKey.Name, Namespace: sharedKey.Namespace},
+				Data: map[string]string{
+					"labels": `
+target:
+  kind: Namespace
+patch: |
+  metadata:
+    labels:
+      team: shared
+      shared: "true"
+`,
+				},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "docker-patches", Namespace: "capd-system"},
+				Data: map[string]string{
+					"labels": `
+target:
+  kind: Namespace
+patch: |
+  metadata:
+    labels:
+      team: docker
+`,
+				},
+			},
+		).Build()
+
+		capiProvider.Annotations[turtlesannotations.ManifestPatchesAnnotation] = "docker-patches"
+
+		chain := PostProcessorChain{
+			FromComponentsAlterFn("cluster-indexed-label", AddClusterIndexedLabelFn),
+			&ManifestPatches{Client: cl, ConfigMap: sharedKey},
+		}
+
+		patched, err := chain.ComponentsAlterFn(ctx, func() *turtlesv1.CAPIProvider { return capiProvider })(manifestComponents())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patched[1].GetLabels()).To(Equal(map[string]string{"team": "docker", "shared": "true"}))
+	})
+
+	It("should fail when the provider patches ConfigMap is missing", func() {
+		cl := fake.NewClientBuilder().WithScheme(testScheme).Build()
+		capiProvider.Annotations[turtlesannotations.ManifestPatchesAnnotation] = "missing"
+
+		_, err := PostProcessorChain{&ManifestPatches{Client: cl, ConfigMap: sharedKey}}.
+			PostProcess(ctx, capiProvider, manifestComponents())
+		Expect(err).To(MatchError(ContainSubstring("post processor manifest-patches")))
+	})
+
+	It("should apply the provider manifest again when patches change", func() {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: sharedKey.Name, Namespace: sharedKey.Namespace},
+			Data: map[string]string{
+				"labels": `
+target:
+  kind: Namespace
+patch: '{"metadata": {"labels": {"team": "shared"}}}'
+`,
+			},
+		}
+		cl := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(configMap).Build()
+		patches := &ManifestPatches{Client: cl, ConfigMap: sharedKey}
+
+		Expect(patches.Sync(ctx, capiProvider)).To(Succeed())
+		Expect(capiProvider.Annotations).NotTo(HaveKey(appliedSpecHashAnnotation))
+		Expect(capiProvider.Annotations).To(HaveKey(turtlesannotations.ManifestPatchesHashAnnotation))
+
+		// Unchanged patches keep the cached manifest.
+		capiProvider.Annotations[appliedSpecHashAnnotation] = "hash"
+		Expect(patches.Sync(ctx, capiProvider)).To(Succeed())
+		Expect(capiProvider.Annotations).To(HaveKey(appliedSpecHashAnnotation))
+
+		Expect(cl.Delete(ctx, configMap)).To(Succeed())
+		Expect(patches.Sync(ctx, capiProvider)).To(Succeed())
+		Expect(capiProvider.Annotations).NotTo(HaveKey(appliedSpecHashAnnotation))
+		Expect(capiProvider.Annotations).NotTo(HaveKey(turtlesannotations.ManifestPatchesHashAnnotation))
+	})
+})
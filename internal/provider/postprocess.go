@@ -0,0 +1,96 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+)
+
+// PostProcessor alters the components of a provider manifest after they are fetched, and before they are
+// applied by the CAPI Operator.
+type PostProcessor interface {
+	// Name identifies the post processor in errors.
+	Name() string
+	// PostProcess returns the altered components of the provider manifest.
+	PostProcess(ctx context.Context, provider *turtlesv1.CAPIProvider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error)
+}
+
+type postProcessorFunc struct {
+	name string
+	fn   func(ctx context.Context, provider *turtlesv1.CAPIProvider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error)
+}
+
+func (p postProcessorFunc) Name() string {
+	return p.name
+}
+
+func (p postProcessorFunc) PostProcess(
+	ctx context.Context, provider *turtlesv1.CAPIProvider, objs []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	return p.fn(ctx, provider, objs)
+}
+
+// NewPostProcessor returns a named PostProcessor calling the function.
+func NewPostProcessor(
+	name string,
+	fn func(ctx context.Context, provider *turtlesv1.CAPIProvider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error),
+) PostProcessor {
+	return postProcessorFunc{name: name, fn: fn}
+}
+
+// FromComponentsAlterFn returns a named PostProcessor calling a function which alters components
+// of any provider in the same way, like WranglerPatcher.
+func FromComponentsAlterFn(name string, fn repository.ComponentsAlterFn) PostProcessor {
+	return NewPostProcessor(name,
+		func(_ context.Context, _ *turtlesv1.CAPIProvider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+			return fn(objs)
+		})
+}
+
+// PostProcessorChain runs post processors in order, each receiving the components returned by the previous one.
+type PostProcessorChain []PostProcessor
+
+// PostProcess runs the chain on the components of the provider manifest.
+func (c PostProcessorChain) PostProcess(
+	ctx context.Context, provider *turtlesv1.CAPIProvider, objs []unstructured.Unstructured,
+) ([]unstructured.Unstructured, error) {
+	var err error
+
+	for _, p := range c {
+		objs, err = p.PostProcess(ctx, provider, objs)
+		if err != nil {
+			return nil, fmt.Errorf("post processor %s: %w", p.Name(), err)
+		}
+	}
+
+	return objs, nil
+}
+
+// ComponentsAlterFn returns the chain as a function for the CAPI Operator, which has no knowledge of the provider
+// being reconciled. The provider is resolved on each call with the getProvider function.
+func (c PostProcessorChain) ComponentsAlterFn(ctx context.Context, getProvider func() *turtlesv1.CAPIProvider) repository.ComponentsAlterFn {
+	return func(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+		return c.PostProcess(ctx, getProvider(), objs)
+	}
+}
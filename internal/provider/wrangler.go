@@ -244,14 +244,25 @@ func addValidatingWebhookServices(object unstructured.Unstructured, services map
 // CleanupCertManagerResources will delete all Certificate and Issuer resources associated with a CAPI provider.
 // Additionally, it will remove all `cert-manager.io/inject-ca-from` annotations from provider resources.
 // Finally, provider pods are restarted to ensure loading of new certificates.
+//
+// The migration is staged: cert-manager resources are only deleted once every certificate Secret requested
+// from wrangler by the provider Services exists and holds a valid serving certificate. The progress is
+// recorded in the provider status.
 func CleanupCertManagerResources(ctx context.Context, cl client.Client, provider *turtlesv1.CAPIProvider) (*controller.Result, error) {
 	log := log.FromContext(ctx)
 
 	if conditions.IsTrue(provider, string(turtlesv1.CAPIProviderWranglerManagedCertificatesCondition)) {
 		// Provider already converted to Wrangler. Nothing to do.
+		setCertificateMigration(provider, turtlesv1.WranglerIssuer, turtlesv1.CertificateMigrationCompleted, nil,
+			"Certificates are managed by wrangler")
+
 		return &controller.Result{}, nil
 	}
 
+	if res, verified, err := verifyWranglerCertificates(ctx, cl, provider); err != nil || !verified {
+		return res, err
+	}
+
 	listOpts, err := getSelector(provider)
 	if err != nil {
 		return &controller.Result{}, fmt.Errorf("getting selector: %w", err)
@@ -374,28 +385,85 @@ func CleanupCertManagerResources(ctx context.Context, cl client.Client, provider
 		LastTransitionTime: metav1.Now(),
 	})
 
+	setCertificateMigration(provider, turtlesv1.WranglerIssuer, turtlesv1.CertificateMigrationCompleted, nil,
+		"Certificates are managed by wrangler")
+
 	return &controller.Result{}, nil
 }
 
-// CleanupWranglerResources removes the `need-a-cert.cattle.io/secret-name` from all provider Services.
-// Finally, provider pods are restarted to ensure loading of new certificates.
+// CleanupWranglerResources reverts a provider to cert-manager managed certificates.
+// The `need-a-cert.cattle.io/secret-name` annotation is removed from all provider Services, and the provider
+// manifest is applied again, including cert-manager Certificates and Issuers. Once all provider Certificates
+// are ready, provider pods are restarted to ensure loading of new certificates.
 func CleanupWranglerResources(ctx context.Context, cl client.Client, provider *turtlesv1.CAPIProvider) (*controller.Result, error) {
-	if conditions.IsFalse(provider, string(turtlesv1.CAPIProviderWranglerManagedCertificatesCondition)) {
-		// Provider not converted to Wrangler. Nothing to do.
+	if conditions.IsTrue(provider, string(turtlesv1.CAPIProviderWranglerManagedCertificatesCondition)) {
+		if err := removeWranglerAnnotations(ctx, cl, provider); err != nil {
+			return &controller.Result{}, err
+		}
+
+		conditions.Delete(provider, string(turtlesv1.CAPIProviderWranglerManagedCertificatesCondition))
+		forceManifestApply(provider)
+		setCertificateMigration(provider, turtlesv1.CertManagerIssuer, turtlesv1.CertificateMigrationPending, nil,
+			"Waiting for the provider manifest to be applied with cert-manager resources")
+
 		return &controller.Result{}, nil
 	}
 
+	migration := provider.Status.CertificateMigration
+	if migration == nil || migration.Target != turtlesv1.CertManagerIssuer || migration.Phase == turtlesv1.CertificateMigrationCompleted {
+		// Provider not converted to Wrangler, or already reverted. Nothing to do.
+		return &controller.Result{}, nil
+	}
+
+	pending, total, err := pendingCertManagerCertificates(ctx, cl, provider)
+	if errors.Is(err, ErrCertManagerNotInstalled) {
+		setCertificateMigration(provider, turtlesv1.CertManagerIssuer, turtlesv1.CertificateMigrationVerifying, nil, err.Error())
+
+		return &controller.Result{RequeueAfter: certificateVerificationInterval}, nil
+	}
+
+	if err != nil {
+		return &controller.Result{}, err
+	}
+
+	if total == 0 {
+		// The provider manifest is not applied with cert-manager Certificates yet, restarting now would keep
+		// the provider without serving certificates.
+		setCertificateMigration(provider, turtlesv1.CertManagerIssuer, turtlesv1.CertificateMigrationVerifying, nil,
+			"Waiting for the provider manifest to be applied with cert-manager Certificates")
+
+		return &controller.Result{RequeueAfter: certificateVerificationInterval}, nil
+	}
+
+	if len(pending) > 0 {
+		setCertificateMigration(provider, turtlesv1.CertManagerIssuer, turtlesv1.CertificateMigrationVerifying, pending,
+			"Waiting for cert-manager Certificates to be ready")
+
+		return &controller.Result{RequeueAfter: certificateVerificationInterval}, nil
+	}
+
+	if err := providerDeploymentRestart(ctx, cl, provider); err != nil {
+		return &controller.Result{}, fmt.Errorf("restarting Deployment: %w", err)
+	}
+
+	setCertificateMigration(provider, turtlesv1.CertManagerIssuer, turtlesv1.CertificateMigrationCompleted, nil,
+		"Certificates are managed by cert-manager")
+
+	return &controller.Result{}, nil
+}
+
+func removeWranglerAnnotations(ctx context.Context, cl client.Client, provider *turtlesv1.CAPIProvider) error {
 	log := log.FromContext(ctx)
 	log.Info("Cleaning wrangler annotation from Services")
 
 	listOpts, err := getSelector(provider)
 	if err != nil {
-		return &controller.Result{}, fmt.Errorf("getting selector: %w", err)
+		return fmt.Errorf("getting selector: %w", err)
 	}
 
 	servicesList := &corev1.ServiceList{}
 	if err := cl.List(ctx, servicesList, listOpts...); err != nil {
-		return &controller.Result{}, fmt.Errorf("listing Services: %w", err)
+		return fmt.Errorf("listing Services: %w", err)
 	}
 
 	for _, service := range servicesList.Items {
@@ -413,19 +481,13 @@ func CleanupWranglerResources(ctx context.Context, cl client.Client, provider *t
 		service.SetAnnotations(annotations)
 
 		if err := cl.Update(ctx, &service); err != nil {
-			return &controller.Result{}, fmt.Errorf("updating Service %s/%s: %w", service.GetNamespace(), service.GetName(), err)
+			return fmt.Errorf("updating Service %s/%s: %w", service.GetNamespace(), service.GetName(), err)
 		}
 
 		log.Info("Removed wrangler annotation from Service", "serviceName", service.GetName())
 	}
 
-	if err := providerDeploymentRestart(ctx, cl, provider); err != nil {
-		return &controller.Result{}, fmt.Errorf("restarting Deployment: %w", err)
-	}
-
-	conditions.Delete(provider, string(turtlesv1.CAPIProviderWranglerManagedCertificatesCondition))
-
-	return &controller.Result{}, nil
+	return nil
 }
 
 // providerDeploymentRestart will force a provider re-rollout by adding an annotation
@@ -0,0 +1,112 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/rancher/turtles/util"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+// CAPIClusterValidator denies the deletion of the self-managed CAPI cluster, which represents the management
+// cluster itself. Deleting it would deprovision the infrastructure Turtles and Rancher run on.
+// It also denies topology version changes of clusters whose Kubernetes version is managed by Rancher.
+type CAPIClusterValidator struct {
+	Client client.Client
+}
+
+var _ admission.Validator[*clusterv1.Cluster] = &CAPIClusterValidator{}
+
+// SetupWebhookWithManager registers the validating webhook with the manager.
+func (v *CAPIClusterValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &clusterv1.Cluster{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate validates the version management annotation of the cluster.
+func (v *CAPIClusterValidator) ValidateCreate(_ context.Context, capiCluster *clusterv1.Cluster) (admission.Warnings, error) {
+	return nil, validateVersionManagementAnnotation(capiCluster)
+}
+
+// ValidateUpdate validates the version management annotation of the cluster, and denies topology version changes
+// when the Kubernetes version is managed by Rancher.
+func (v *CAPIClusterValidator) ValidateUpdate(ctx context.Context, oldCluster, newCluster *clusterv1.Cluster) (admission.Warnings, error) {
+	if err := validateVersionManagementAnnotation(newCluster); err != nil {
+		return nil, err
+	}
+
+	oldVersion := oldCluster.Spec.Topology.Version
+	if oldVersion == "" || oldVersion == newCluster.Spec.Topology.Version {
+		return nil, nil
+	}
+
+	owner, err := util.VersionManagement(ctx, v.Client, newCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if owner != turtlesannotations.VersionManagementRancher {
+		return nil, nil
+	}
+
+	log.FromContext(ctx).Info("Denying topology version change of Rancher managed CAPI cluster", "cluster", client.ObjectKeyFromObject(newCluster))
+
+	return nil, apierrors.NewForbidden(
+		clusterv1.GroupVersion.WithResource("clusters").GroupResource(),
+		newCluster.Name,
+		fmt.Errorf("kubernetes version is managed by Rancher, set the %s annotation to %s to upgrade the cluster with CAPI",
+			turtlesannotations.VersionManagementAnnotation, turtlesannotations.VersionManagementCAPI),
+	)
+}
+
+// ValidateDelete denies the deletion of a self-managed CAPI cluster, unless it has the force delete annotation.
+func (v *CAPIClusterValidator) ValidateDelete(ctx context.Context, capiCluster *clusterv1.Cluster) (admission.Warnings, error) {
+	if !turtlesannotations.IsSelfManagedCluster(capiCluster) || turtlesannotations.IsForceDelete(capiCluster) {
+		return nil, nil
+	}
+
+	log.FromContext(ctx).Info("Denying deletion of self-managed CAPI cluster", "cluster", client.ObjectKeyFromObject(capiCluster))
+
+	return nil, apierrors.NewForbidden(
+		clusterv1.GroupVersion.WithResource("clusters").GroupResource(),
+		capiCluster.Name,
+		fmt.Errorf("cluster is the self-managed management cluster, set the %s annotation to allow the deletion",
+			turtlesannotations.ForceDeleteAnnotation),
+	)
+}
+
+// validateVersionManagementAnnotation denies unknown values of the version management annotation on the cluster.
+func validateVersionManagementAnnotation(capiCluster *clusterv1.Cluster) error {
+	switch capiCluster.GetAnnotations()[turtlesannotations.VersionManagementAnnotation] {
+	case "", turtlesannotations.VersionManagementCAPI, turtlesannotations.VersionManagementRancher:
+		return nil
+	default:
+		return apierrors.NewBadRequest(fmt.Sprintf("invalid %s annotation value, expected %s or %s",
+			turtlesannotations.VersionManagementAnnotation, turtlesannotations.VersionManagementCAPI,
+			turtlesannotations.VersionManagementRancher))
+	}
+}
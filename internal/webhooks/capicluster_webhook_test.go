@@ -0,0 +1,128 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+var _ = Describe("CAPIClusterValidator", func() {
+	var (
+		ctx         context.Context
+		capiCluster *clusterv1.Cluster
+		v           *CAPIClusterValidator
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		v = &CAPIClusterValidator{}
+
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "rancher",
+				Namespace: "default",
+				Annotations: map[string]string{
+					turtlesannotations.SelfManagedClusterAnnotation: "true",
+				},
+			},
+		}
+	})
+
+	It("should deny deletion of a self-managed cluster", func() {
+		_, err := v.ValidateDelete(ctx, capiCluster)
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+	})
+
+	It("should allow deletion of a self-managed cluster with the force delete annotation", func() {
+		capiCluster.Annotations[turtlesannotations.ForceDeleteAnnotation] = "true"
+
+		_, err := v.ValidateDelete(ctx, capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should allow deletion of a downstream cluster", func() {
+		capiCluster.Annotations = nil
+
+		_, err := v.ValidateDelete(ctx, capiCluster)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("CAPIClusterValidator version management", func() {
+	var (
+		ctx         context.Context
+		namespace   *corev1.Namespace
+		capiCluster *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "cluster1",
+				Namespace: "default",
+				Annotations: map[string]string{
+					turtlesannotations.VersionManagementAnnotation: turtlesannotations.VersionManagementRancher,
+				},
+			},
+			Spec: clusterv1.ClusterSpec{
+				Topology: clusterv1.Topology{Version: "v1.31.2"},
+			},
+		}
+	})
+
+	It("should deny topology upgrades of Rancher managed clusters", func() {
+		v := &CAPIClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(namespace).Build()}
+
+		upgraded := capiCluster.DeepCopy()
+		upgraded.Spec.Topology.Version = "v1.32.0"
+
+		_, err := v.ValidateUpdate(ctx, capiCluster, upgraded)
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+	})
+
+	It("should allow topology upgrades of CAPI managed clusters", func() {
+		delete(capiCluster.Annotations, turtlesannotations.VersionManagementAnnotation)
+		v := &CAPIClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(namespace).Build()}
+
+		upgraded := capiCluster.DeepCopy()
+		upgraded.Spec.Topology.Version = "v1.32.0"
+
+		_, err := v.ValidateUpdate(ctx, capiCluster, upgraded)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should deny invalid version management annotations", func() {
+		capiCluster.Annotations[turtlesannotations.VersionManagementAnnotation] = "both"
+		v := &CAPIClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+		_, err := v.ValidateCreate(ctx, capiCluster)
+		Expect(apierrors.IsBadRequest(err)).To(BeTrue())
+	})
+})
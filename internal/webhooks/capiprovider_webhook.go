@@ -0,0 +1,260 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	"github.com/rancher/turtles/internal/provider"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+// maxReportedClusters is the maximum number of clusters listed in the denial message.
+const maxReportedClusters = 5
+
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
+
+// CAPIProviderValidator denies the deletion of CAPIProviders still used by CAPI clusters, or while objects
+// of the provider's CRDs exist, unless the provider has the force delete annotation.
+type CAPIProviderValidator struct {
+	// Client is used to list the CAPI clusters, the provider CRDs and their objects. An uncached reader
+	// is expected, so no informers are started for the provider kinds.
+	Client client.Reader
+}
+
+var _ admission.Validator[*turtlesv1.CAPIProvider] = &CAPIProviderValidator{}
+
+// SetupWebhookWithManager registers the validating webhook with the manager.
+func (v *CAPIProviderValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &turtlesv1.CAPIProvider{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate implements admission.Validator.
+func (v *CAPIProviderValidator) ValidateCreate(_ context.Context, _ *turtlesv1.CAPIProvider) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate implements admission.Validator.
+func (v *CAPIProviderValidator) ValidateUpdate(_ context.Context, _, _ *turtlesv1.CAPIProvider) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateDelete denies the deletion of the provider while CAPI clusters use it, or objects of its CRDs exist.
+// A core provider is used by any cluster, while infrastructure and control plane providers are used by
+// clusters referencing a kind from the provider's CRDs. The CRDs are found through the `cluster.x-k8s.io/provider`
+// label, so for an infrastructure provider its infrastructure clusters, machines and templates are found too.
+func (v *CAPIProviderValidator) ValidateDelete(ctx context.Context, capiProvider *turtlesv1.CAPIProvider) (admission.Warnings, error) {
+	log := log.FromContext(ctx)
+
+	crds, err := v.providerCRDs(ctx, capiProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters, err := v.clustersUsingProvider(ctx, capiProvider, crds)
+	if err != nil {
+		return nil, err
+	}
+
+	kinds, err := v.kindsWithObjects(ctx, crds)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(clusters) == 0 && len(kinds) == 0 {
+		return nil, nil
+	}
+
+	reasons := []string{}
+
+	if len(clusters) > 0 {
+		reported := clusters
+		if len(reported) > maxReportedClusters {
+			reported = append(reported[:maxReportedClusters:maxReportedClusters], "...")
+		}
+
+		reasons = append(reasons, fmt.Sprintf("provider is used by %d cluster(s): %s", len(clusters), strings.Join(reported, ", ")))
+	}
+
+	if len(kinds) > 0 {
+		reasons = append(reasons, fmt.Sprintf("objects of the provider CRDs still exist: %s", strings.Join(kinds, ", ")))
+	}
+
+	if turtlesannotations.IsForceDelete(capiProvider) {
+		log.Info("Allowing forced deletion of CAPIProvider in use", "provider", client.ObjectKeyFromObject(capiProvider))
+
+		return admission.Warnings{
+			fmt.Sprintf("provider is deleted while still in use: %s", strings.Join(reasons, "; ")),
+		}, nil
+	}
+
+	log.Info("Denying deletion of CAPIProvider in use", "provider", client.ObjectKeyFromObject(capiProvider),
+		"clusters", len(clusters))
+
+	return nil, apierrors.NewForbidden(
+		turtlesv1.GroupVersion.WithResource("capiproviders").GroupResource(),
+		capiProvider.Name,
+		fmt.Errorf("%s. Delete them first, or set the %s annotation to \"true\"",
+			strings.Join(reasons, "; "), turtlesannotations.ForceDeleteAnnotation),
+	)
+}
+
+// providerCRDs returns the CRDs installed for the provider.
+func (v *CAPIProviderValidator) providerCRDs(ctx context.Context, capiProvider *turtlesv1.CAPIProvider) ([]unstructured.Unstructured, error) {
+	crdList := &unstructured.UnstructuredList{}
+	crdList.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "apiextensions.k8s.io",
+		Version: "v1",
+		Kind:    "CustomResourceDefinitionList",
+	})
+
+	if err := v.Client.List(ctx, crdList, client.MatchingLabels{
+		provider.CAPIProviderLabel: capiProvider.Spec.Type.ToName() + capiProvider.ProviderName(),
+	}); err != nil {
+		return nil, fmt.Errorf("listing provider CRDs: %w", err)
+	}
+
+	return crdList.Items, nil
+}
+
+// clustersUsingProvider returns the namespaced names of the CAPI clusters using the provider.
+func (v *CAPIProviderValidator) clustersUsingProvider(ctx context.Context, capiProvider *turtlesv1.CAPIProvider,
+	crds []unstructured.Unstructured,
+) ([]string, error) {
+	providerType := capiProvider.Spec.Type
+	if providerType != turtlesv1.Core && providerType != turtlesv1.Infrastructure && providerType != turtlesv1.ControlPlane {
+		// Other provider types are not referenced by clusters.
+		return nil, nil
+	}
+
+	providerKinds := make([]schema.GroupKind, 0, len(crds))
+
+	for _, crd := range crds {
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+		providerKinds = append(providerKinds, schema.GroupKind{Group: group, Kind: kind})
+	}
+
+	if providerType != turtlesv1.Core && len(providerKinds) == 0 {
+		return nil, nil
+	}
+
+	clusterList := &clusterv1.ClusterList{}
+	if err := v.Client.List(ctx, clusterList); err != nil {
+		if apimeta.IsNoMatchError(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("listing CAPI clusters: %w", err)
+	}
+
+	clusters := []string{}
+
+	for _, cluster := range clusterList.Items {
+		ref := cluster.Spec.InfrastructureRef
+		if providerType == turtlesv1.ControlPlane {
+			ref = cluster.Spec.ControlPlaneRef
+		}
+
+		if providerType == turtlesv1.Core || slices.Contains(providerKinds, schema.GroupKind{Group: ref.APIGroup, Kind: ref.Kind}) {
+			clusters = append(clusters, client.ObjectKeyFromObject(&cluster).String())
+		}
+	}
+
+	return clusters, nil
+}
+
+// kindsWithObjects returns the kinds of the provider CRDs with at least one object. Only the metadata
+// of a single object is requested per kind, so the check does not depend on the number of objects.
+func (v *CAPIProviderValidator) kindsWithObjects(ctx context.Context, crds []unstructured.Unstructured) ([]string, error) {
+	kinds := []string{}
+
+	for _, crd := range crds {
+		gvk, err := crdStorageVersionKind(crd)
+		if err != nil {
+			return nil, fmt.Errorf("parsing CRD %s: %w", crd.GetName(), err)
+		}
+
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+
+		if err := v.Client.List(ctx, list, client.Limit(1)); err != nil {
+			if apimeta.IsNoMatchError(err) {
+				continue
+			}
+
+			return nil, fmt.Errorf("listing %s objects: %w", gvk.Kind, err)
+		}
+
+		if len(list.Items) > 0 {
+			kinds = append(kinds, gvk.Kind)
+		}
+	}
+
+	return kinds, nil
+}
+
+// crdStorageVersionKind returns the kind of the CRD objects, using the storage version of the CRD.
+func crdStorageVersionKind(crd unstructured.Unstructured) (schema.GroupVersionKind, error) {
+	group, _, err := unstructured.NestedString(crd.Object, "spec", "group")
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+
+	kind, _, err := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+
+	versions, _, err := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+
+	for _, v := range versions {
+		version, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if storage, _ := version["storage"].(bool); storage {
+			name, _ := version["name"].(string)
+
+			return schema.GroupVersionKind{Group: group, Version: name, Kind: kind}, nil
+		}
+	}
+
+	return schema.GroupVersionKind{}, fmt.Errorf("no storage version found for kind %s", kind)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	"github.com/rancher/turtles/internal/provider"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+var _ = Describe("CAPIProviderValidator", func() {
+	var (
+		ctx           context.Context
+		capiProvider  *turtlesv1.CAPIProvider
+		crd           *unstructured.Unstructured
+		dockerCluster *metav1.PartialObjectMetadata
+		capiCluster   *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		capiProvider = &turtlesv1.CAPIProvider{
+			ObjectMeta: metav1.ObjectMeta{Name: "docker", Namespace: "capd-system"},
+			Spec: turtlesv1.CAPIProviderSpec{
+				Type: turtlesv1.Infrastructure,
+			},
+		}
+
+		crd = &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "apiextensions.k8s.io/v1",
+			"kind":       "CustomResourceDefinition",
+			"metadata": map[string]any{
+				"name":   "dockerclusters.infrastructure.cluster.x-k8s.io",
+				"labels": map[string]any{provider.CAPIProviderLabel: "infrastructure-docker"},
+			},
+			"spec": map[string]any{
+				"group": "infrastructure.cluster.x-k8s.io",
+				"names": map[string]any{"kind": "DockerCluster"},
+				"versions": []any{
+					map[string]any{"name": "v1beta1", "storage": false},
+					map[string]any{"name": "v1beta2", "storage": true},
+				},
+			},
+		}}
+
+		// The validator only reads the metadata of the provider objects.
+		dockerCluster = &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "infrastructure.cluster.x-k8s.io/v1beta2", Kind: "DockerCluster"},
+			ObjectMeta: metav1.ObjectMeta{Name: "capi-cluster", Namespace: "default"},
+		}
+
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "capi-cluster", Namespace: "default"},
+			Spec: clusterv1.ClusterSpec{
+				InfrastructureRef: clusterv1.ContractVersionedObjectReference{
+					APIGroup: "infrastructure.cluster.x-k8s.io",
+					Kind:     "DockerCluster",
+					Name:     "capi-cluster",
+				},
+			},
+		}
+	})
+
+	newClient := func(objs ...client.Object) client.Client {
+		// Objects are created instead of passed to the builder, which does not accept PartialObjectMetadata.
+		c := fake.NewClientBuilder().WithScheme(testScheme).Build()
+		for _, obj := range objs {
+			Expect(c.Create(ctx, obj)).To(Succeed())
+		}
+
+		return c
+	}
+
+	It("should deny deletion of a provider with existing objects", func() {
+		v := &CAPIProviderValidator{Client: newClient(crd, dockerCluster)}
+
+		_, err := v.ValidateDelete(ctx, capiProvider)
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("objects of the provider CRDs still exist: DockerCluster"))
+	})
+
+	It("should allow deletion of a provider without existing objects", func() {
+		v := &CAPIProviderValidator{Client: newClient(crd)}
+
+		warnings, err := v.ValidateDelete(ctx, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(BeEmpty())
+	})
+
+	It("should allow deletion with a warning when the force delete annotation is set", func() {
+		capiProvider.Annotations = map[string]string{turtlesannotations.ForceDeleteAnnotation: "true"}
+		v := &CAPIProviderValidator{Client: newClient(crd, dockerCluster)}
+
+		warnings, err := v.ValidateDelete(ctx, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(HaveLen(1))
+	})
+
+	It("should deny deletion of an infrastructure provider used by a cluster", func() {
+		v := &CAPIProviderValidator{Client: newClient(crd, capiCluster)}
+
+		_, err := v.ValidateDelete(ctx, capiProvider)
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("default/capi-cluster"))
+	})
+
+	It("should allow deletion of an infrastructure provider not used by any cluster", func() {
+		capiCluster.Spec.InfrastructureRef.Kind = "AWSCluster"
+		v := &CAPIProviderValidator{Client: newClient(crd, capiCluster)}
+
+		_, err := v.ValidateDelete(ctx, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should deny deletion of the core provider while clusters exist", func() {
+		capiProvider.Spec.Type = turtlesv1.Core
+		v := &CAPIProviderValidator{Client: newClient(capiCluster)}
+
+		_, err := v.ValidateDelete(ctx, capiProvider)
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("default/capi-cluster"))
+	})
+
+	It("should not check cluster references for providers not referenced by clusters", func() {
+		capiProvider.Spec.Type = turtlesv1.Bootstrap
+		v := &CAPIProviderValidator{Client: newClient(crd, capiCluster)}
+
+		_, err := v.ValidateDelete(ctx, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should ignore CRDs of other providers", func() {
+		capiProvider.Name = "aws"
+		v := &CAPIProviderValidator{Client: newClient(crd, dockerCluster)}
+
+		_, err := v.ValidateDelete(ctx, capiProvider)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
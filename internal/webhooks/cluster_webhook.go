@@ -0,0 +1,170 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	"github.com/rancher/turtles/util"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+const (
+	// capiClusterOwner and capiClusterOwnerNamespace are set by the import controller on the
+	// Rancher management Cluster, referencing the CAPI cluster it was created for.
+	capiClusterOwner          = "cluster-api.cattle.io/capi-cluster-owner"
+	capiClusterOwnerNamespace = "cluster-api.cattle.io/capi-cluster-owner-ns"
+)
+
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// ClusterValidator denies the deletion of Rancher management Clusters owned by a CAPI cluster
+// with the deletion protection annotation. It also denies Rancher upgrades of clusters whose
+// Kubernetes version is managed by CAPI, and changes of the version management in Rancher.
+// The webhook is registered in the cluster Turtles runs in, so Rancher Clusters of a separate Rancher Manager
+// cluster, or of a RancherTarget, are not validated.
+type ClusterValidator struct {
+	Client client.Client
+}
+
+var _ admission.Validator[*managementv3.Cluster] = &ClusterValidator{}
+
+// SetupWebhookWithManager registers the validating webhook with the manager.
+func (v *ClusterValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &managementv3.Cluster{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate implements admission.Validator.
+func (v *ClusterValidator) ValidateCreate(_ context.Context, _ *managementv3.Cluster) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate denies changes conflicting with the version management policy of the owner CAPI cluster.
+// Rancher version management can only be enabled when the policy selects Rancher. Otherwise the Kubernetes
+// version in Rancher may only follow the topology version of the CAPI cluster, as Rancher records the
+// version detected on the imported cluster.
+func (v *ClusterValidator) ValidateUpdate(ctx context.Context, oldCluster, newCluster *managementv3.Cluster) (admission.Warnings, error) {
+	oldVersionManagement := oldCluster.GetAnnotations()[turtlesannotations.ImportedClusterVersionManagementAnnotation]
+	newVersionManagement := newCluster.GetAnnotations()[turtlesannotations.ImportedClusterVersionManagementAnnotation]
+	newVersion := newCluster.Spec.KubernetesVersion()
+
+	versionChanged := newVersion != "" && newVersion != oldCluster.Spec.KubernetesVersion()
+	if oldVersionManagement == newVersionManagement && !versionChanged {
+		return nil, nil
+	}
+
+	capiCluster, err := v.ownerCluster(ctx, newCluster)
+	if err != nil || capiCluster == nil {
+		return nil, err
+	}
+
+	owner, err := util.VersionManagement(ctx, v.Client, capiCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	rancherManaged := strconv.FormatBool(owner == turtlesannotations.VersionManagementRancher)
+
+	switch {
+	case oldVersionManagement != newVersionManagement && newVersionManagement != rancherManaged:
+		return nil, v.forbidden(ctx, newCluster, capiCluster,
+			fmt.Errorf("version management is set by the %s annotation on the owner CAPI cluster %s, which selects %s",
+				turtlesannotations.VersionManagementAnnotation, client.ObjectKeyFromObject(capiCluster), owner))
+	case versionChanged && owner != turtlesannotations.VersionManagementRancher &&
+		!sameKubernetesVersion(newVersion, capiCluster.Spec.Topology.Version):
+		return nil, v.forbidden(ctx, newCluster, capiCluster,
+			fmt.Errorf("kubernetes version is managed by the owner CAPI cluster %s, upgrade its topology version instead",
+				client.ObjectKeyFromObject(capiCluster)))
+	}
+
+	return nil, nil
+}
+
+// ValidateDelete denies the deletion of the Rancher cluster if the owning CAPI cluster is protected.
+// Deletion is allowed once the CAPI cluster is deleted itself, so Turtles can clean up the Rancher cluster.
+func (v *ClusterValidator) ValidateDelete(ctx context.Context, rancherCluster *managementv3.Cluster) (admission.Warnings, error) {
+	capiCluster, err := v.ownerCluster(ctx, rancherCluster)
+	if err != nil || capiCluster == nil {
+		return nil, err
+	}
+
+	if !capiCluster.DeletionTimestamp.IsZero() || !turtlesannotations.IsDeletionProtected(capiCluster) {
+		return nil, nil
+	}
+
+	return nil, v.forbidden(ctx, rancherCluster, capiCluster,
+		fmt.Errorf("owner CAPI cluster %s has the %s annotation, remove it to allow the deletion",
+			client.ObjectKeyFromObject(capiCluster), turtlesannotations.DeletionProtectionAnnotation))
+}
+
+// ownerCluster returns the CAPI cluster owning the Rancher cluster, or nil if the Rancher cluster has no owner
+// or the owner no longer exists.
+func (v *ClusterValidator) ownerCluster(ctx context.Context, rancherCluster *managementv3.Cluster) (*clusterv1.Cluster, error) {
+	ownerName := rancherCluster.GetLabels()[capiClusterOwner]
+	ownerNamespace := rancherCluster.GetLabels()[capiClusterOwnerNamespace]
+
+	if ownerName == "" || ownerNamespace == "" {
+		return nil, nil //nolint:nilnil // the Rancher cluster is not owned by a CAPI cluster
+	}
+
+	capiCluster := &clusterv1.Cluster{}
+	if err := v.Client.Get(ctx, client.ObjectKey{Namespace: ownerNamespace, Name: ownerName}, capiCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil //nolint:nilnil // the owner CAPI cluster is deleted
+		}
+
+		return nil, fmt.Errorf("getting owner CAPI cluster %s/%s: %w", ownerNamespace, ownerName, err)
+	}
+
+	return capiCluster, nil
+}
+
+func (v *ClusterValidator) forbidden(ctx context.Context, rancherCluster *managementv3.Cluster, capiCluster *clusterv1.Cluster,
+	err error,
+) error {
+	log.FromContext(ctx).Info("Denying request for Rancher cluster", "cluster", rancherCluster.Name,
+		"capiCluster", client.ObjectKeyFromObject(capiCluster), "reason", err.Error())
+
+	return apierrors.NewForbidden(managementv3.GroupVersion.WithResource("clusters").GroupResource(), rancherCluster.Name, err)
+}
+
+// sameKubernetesVersion returns true if the Rancher version matches the CAPI topology version, ignoring the
+// distribution suffix, i.e. v1.31.2+rke2r1 matches v1.31.2. Clusters without a topology version are not checked.
+func sameKubernetesVersion(rancherVersion, topologyVersion string) bool {
+	if topologyVersion == "" {
+		return true
+	}
+
+	rancherVersion, _, _ = strings.Cut(strings.TrimPrefix(rancherVersion, "v"), "+")
+	topologyVersion, _, _ = strings.Cut(strings.TrimPrefix(topologyVersion, "v"), "+")
+
+	return rancherVersion == topologyVersion
+}
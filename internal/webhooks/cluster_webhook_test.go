@@ -0,0 +1,188 @@
+/*
+Copyright © 2023 - 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
+	turtlesannotations "github.com/rancher/turtles/util/annotations"
+)
+
+var _ = Describe("ClusterValidator", func() {
+	var (
+		ctx            context.Context
+		capiCluster    *clusterv1.Cluster
+		rancherCluster *managementv3.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "capi-cluster",
+				Namespace: "default",
+				Annotations: map[string]string{
+					turtlesannotations.DeletionProtectionAnnotation: "true",
+				},
+			},
+		}
+
+		rancherCluster = &managementv3.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "c-protected",
+				Labels: map[string]string{
+					capiClusterOwner:          "capi-cluster",
+					capiClusterOwnerNamespace: "default",
+				},
+			},
+		}
+	})
+
+	It("should deny deletion when the owner CAPI cluster is protected", func() {
+		v := &ClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(capiCluster).Build()}
+
+		_, err := v.ValidateDelete(ctx, rancherCluster)
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+	})
+
+	It("should allow deletion when the owner CAPI cluster is not protected", func() {
+		capiCluster.Annotations = nil
+		v := &ClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(capiCluster).Build()}
+
+		_, err := v.ValidateDelete(ctx, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should allow deletion when the owner CAPI cluster is being deleted", func() {
+		capiCluster.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		capiCluster.Finalizers = []string{clusterv1.ClusterFinalizer}
+		v := &ClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(capiCluster).Build()}
+
+		_, err := v.ValidateDelete(ctx, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should allow deletion when the owner CAPI cluster does not exist", func() {
+		v := &ClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+		_, err := v.ValidateDelete(ctx, rancherCluster)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should allow deletion of Rancher clusters not created by Turtles", func() {
+		v := &ClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(capiCluster).Build()}
+
+		_, err := v.ValidateDelete(ctx, &managementv3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-other"}})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("ClusterValidator version management", func() {
+	var (
+		ctx            context.Context
+		namespace      *corev1.Namespace
+		capiCluster    *clusterv1.Cluster
+		rancherCluster *managementv3.Cluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "capi-cluster",
+				Namespace: "default",
+			},
+			Spec: clusterv1.ClusterSpec{
+				Topology: clusterv1.Topology{Version: "v1.31.2"},
+			},
+		}
+
+		rancherCluster = &managementv3.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "c-versioned",
+				Labels: map[string]string{
+					capiClusterOwner:          "capi-cluster",
+					capiClusterOwnerNamespace: "default",
+				},
+				Annotations: map[string]string{
+					turtlesannotations.ImportedClusterVersionManagementAnnotation: "false",
+				},
+			},
+			Spec: managementv3.ClusterSpec{
+				RKE2Config: &managementv3.ImportedClusterConfig{KubernetesVersion: "v1.31.2+rke2r1"},
+			},
+		}
+	})
+
+	It("should deny Rancher upgrades of CAPI managed clusters", func() {
+		v := &ClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(namespace, capiCluster).Build()}
+
+		upgraded := rancherCluster.DeepCopy()
+		upgraded.Spec.RKE2Config.KubernetesVersion = "v1.32.0+rke2r1"
+
+		_, err := v.ValidateUpdate(ctx, rancherCluster, upgraded)
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+	})
+
+	It("should allow Rancher to record the CAPI topology version", func() {
+		capiCluster.Spec.Topology.Version = "v1.32.0"
+		v := &ClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(namespace, capiCluster).Build()}
+
+		upgraded := rancherCluster.DeepCopy()
+		upgraded.Spec.RKE2Config.KubernetesVersion = "v1.32.0+rke2r1"
+
+		_, err := v.ValidateUpdate(ctx, rancherCluster, upgraded)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should deny enabling Rancher version management of CAPI managed clusters", func() {
+		v := &ClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(namespace, capiCluster).Build()}
+
+		enabled := rancherCluster.DeepCopy()
+		enabled.Annotations[turtlesannotations.ImportedClusterVersionManagementAnnotation] = "true"
+
+		_, err := v.ValidateUpdate(ctx, rancherCluster, enabled)
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+	})
+
+	It("should allow Rancher upgrades when the namespace policy selects Rancher", func() {
+		namespace.Annotations = map[string]string{
+			turtlesannotations.VersionManagementAnnotation: turtlesannotations.VersionManagementRancher,
+		}
+		rancherCluster.Annotations[turtlesannotations.ImportedClusterVersionManagementAnnotation] = "true"
+		v := &ClusterValidator{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(namespace, capiCluster).Build()}
+
+		upgraded := rancherCluster.DeepCopy()
+		upgraded.Spec.RKE2Config.KubernetesVersion = "v1.32.0+rke2r1"
+
+		_, err := v.ValidateUpdate(ctx, rancherCluster, upgraded)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
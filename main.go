@@ -18,9 +18,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -28,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/component-base/version"
 	"k8s.io/klog/v2"
@@ -35,19 +38,23 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	operatorv1 "sigs.k8s.io/cluster-api-operator/api/v1alpha2"
 	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
 
+	k3sv1 "github.com/rancher/turtles/api/rancher/k3s/v1"
 	managementv3 "github.com/rancher/turtles/api/rancher/management/v3"
 	provisioningv1 "github.com/rancher/turtles/api/rancher/provisioning/v1"
 	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
 	"github.com/rancher/turtles/feature"
 	"github.com/rancher/turtles/internal/controllers"
+	"github.com/rancher/turtles/internal/webhooks"
 )
 
 var (
@@ -67,6 +74,33 @@ var (
 	concurrencyNumber           int
 	managerConcurrency          int
 	insecureSkipVerify          bool
+	importDryRun                bool
+	importPrune                 bool
+	manifestCacheTTL            time.Duration
+	importRateLimit             float64
+	importBurst                 int
+	caBundleSecret              string
+	caBundleConfigMap           string
+	caBundleKey                 string
+	importMinReadyNodes         int
+	importRequireCoreDNS        bool
+	importReadinessChecks       string
+	displayNameTemplate         string
+	descriptionTemplate         string
+	clusterTemplatesConfigMap   string
+	orphanCleanup               bool
+	orphanGracePeriod           time.Duration
+	enableWebhooks              bool
+	webhookPort                 int
+	webhookCertDir              string
+	featureGatesConfigMapRef    string
+	featureGatesReloadInterval  time.Duration
+	manifestPatchesConfigMapRef string
+	rancherKubeconfig           string
+	detectSelfManaged           bool
+	fleetMigrationTimeout       time.Duration
+	etcdSnapshotSyncInterval    time.Duration
+	etcdSnapshotJobImage        string
 )
 
 func init() {
@@ -79,6 +113,7 @@ func init() {
 	utilruntime.Must(managementv3.AddToScheme(scheme))
 	utilruntime.Must(operatorv1.AddToScheme(scheme))
 	utilruntime.Must(turtlesv1.AddToScheme(scheme))
+	utilruntime.Must(k3sv1.AddToScheme(scheme))
 	turtlesv1.AddKnownTypes(scheme)
 }
 
@@ -119,6 +154,88 @@ func initFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&insecureSkipVerify, "insecure-skip-verify", false,
 		"Skip TLS certificate verification when connecting to Rancher. Only used for development and testing purposes. Use at your own risk.")
 
+	fs.BoolVar(&importDryRun, "import-dry-run", false,
+		"Render the import manifest of CAPI clusters into a Secret and report the expected changes, without applying it on the workload cluster.")
+
+	fs.BoolVar(&importPrune, "import-prune", false,
+		"Delete objects from the workload cluster which were applied by a previous import manifest, but are no longer part of it.")
+
+	fs.DurationVar(&manifestCacheTTL, "import-manifest-cache-ttl", 5*time.Minute,
+		"Duration for which downloaded import manifests are cached per registration token and CA. Set to 0 to disable the cache.")
+
+	fs.Float64Var(&importRateLimit, "import-rate-limit", 0,
+		"Maximum number of cluster imports per second across all clusters. Set to 0 to disable the limit.")
+
+	fs.IntVar(&importBurst, "import-burst", 10,
+		"Maximum number of cluster imports allowed in a burst when import-rate-limit is set.")
+
+	fs.StringVar(&caBundleSecret, "rancher-ca-bundle-secret", "",
+		"Secret in the namespace/name format, holding CA certificates trusted when downloading import manifests from the Rancher server URL.")
+
+	fs.StringVar(&caBundleConfigMap, "rancher-ca-bundle-configmap", "",
+		"ConfigMap in the namespace/name format, holding CA certificates trusted when downloading import manifests from the Rancher server URL.")
+
+	fs.StringVar(&caBundleKey, "rancher-ca-bundle-key", "ca.crt",
+		"Key of the CA bundle Secret or ConfigMap containing the PEM encoded CA certificates.")
+
+	fs.IntVar(&importMinReadyNodes, "import-min-ready-nodes", 0,
+		"Minimum number of Ready nodes in the workload cluster before the import manifest is applied. Set to 0 to disable the check.")
+
+	fs.BoolVar(&importRequireCoreDNS, "import-require-coredns", false,
+		"Require an available CoreDNS deployment in the workload cluster before the import manifest is applied.")
+
+	fs.StringVar(&importReadinessChecks, "import-readiness-checks-configmap", "",
+		"ConfigMap in the namespace/name format, holding CEL readiness checks evaluated on the workload cluster before the import manifest is applied.")
+
+	fs.StringVar(&displayNameTemplate, "rancher-cluster-display-name-template", "",
+		"Go template of the Rancher cluster display name, executed with the CAPI cluster .Name, .Namespace, .Labels, .ClusterClass and .KubernetesVersion.")
+
+	fs.StringVar(&descriptionTemplate, "rancher-cluster-description-template", "",
+		"Go template of the Rancher cluster description, executed with the same fields as the display name template.")
+
+	fs.StringVar(&clusterTemplatesConfigMap, "rancher-cluster-templates-configmap", "",
+		"ConfigMap in the namespace/name format, holding displayName and description templates which override the template flags.")
+
+	fs.BoolVar(&orphanCleanup, "orphan-cluster-cleanup", false,
+		"Delete Rancher clusters created by Turtles, whose CAPI cluster no longer exists, after the orphan grace period.")
+
+	fs.DurationVar(&orphanGracePeriod, "orphan-cluster-grace-period", 24*time.Hour,
+		"Duration a Rancher cluster has to stay orphaned before it is deleted, when orphan-cluster-cleanup is enabled (e.g. 24h)")
+
+	fs.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable the admission webhooks protecting Rancher clusters, self-managed CAPI clusters and CAPIProviders from deletion, "+
+			"and enforcing the Kubernetes version management policy.")
+
+	fs.IntVar(&webhookPort, "webhook-port", 9443,
+		"Webhook server port.")
+
+	fs.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs/",
+		"Webhook server certificate directory, holding the tls.crt and tls.key files.")
+
+	fs.StringVar(&featureGatesConfigMapRef, "feature-gates-configmap", "",
+		"ConfigMap in the namespace/name format, holding feature gates which override --feature-gates and are reloaded at runtime.")
+
+	fs.DurationVar(&featureGatesReloadInterval, "feature-gates-reload-interval", 30*time.Second,
+		"Interval at which the feature gates ConfigMap is reloaded, and stopped gated controllers are restarted (duration string)")
+
+	fs.StringVar(&manifestPatchesConfigMapRef, "provider-manifest-patches-configmap", "",
+		"ConfigMap in the namespace/name format, holding patches which are applied to the manifest of every CAPI provider.")
+
+	fs.StringVar(&rancherKubeconfig, "rancher-kubeconfig", "",
+		"Path to a kubeconfig file for the Rancher Manager cluster, when Turtles runs outside of it. Defaults to the manager cluster.")
+
+	fs.BoolVar(&detectSelfManaged, "detect-self-managed-cluster", false,
+		"Detect the CAPI cluster representing the management cluster and link it to the local Rancher cluster instead of importing it.")
+
+	fs.DurationVar(&fleetMigrationTimeout, "fleet-namespace-migration-timeout", 10*time.Minute,
+		"Duration after which an incomplete fleet agent namespace migration of a workload cluster is reported as stuck. Set to 0 to disable.")
+
+	fs.DurationVar(&etcdSnapshotSyncInterval, "etcd-snapshot-sync-interval", 5*time.Minute,
+		"Interval at which the etcd snapshots of RKE2 workload clusters are mirrored, when the etcd-snapshots feature is enabled (duration string)")
+
+	fs.StringVar(&etcdSnapshotJobImage, "etcd-snapshot-job-image", controllers.DefaultEtcdSnapshotJobImage,
+		"Image of the Job taking the requested etcd snapshots on RKE2 workload clusters, when the etcd-snapshots feature is enabled.")
+
 	feature.MutableGates.AddFlag(fs)
 }
 
@@ -164,6 +281,10 @@ func main() {
 			SyncPeriod: &syncPeriod,
 		},
 		HealthProbeBindAddress: healthAddr,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    webhookPort,
+			CertDir: webhookCertDir,
+		}),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -175,6 +296,7 @@ func main() {
 
 	setupChecks(mgr)
 	setupReconcilers(ctx, mgr)
+	setupWebhooks(mgr)
 
 	// +kubebuilder:scaffold:builder
 	setupLog.Info("starting manager", "version", version.Get().String())
@@ -195,6 +317,13 @@ func setupChecks(mgr ctrl.Manager) {
 		setupLog.Error(err, "unable to create health check")
 		os.Exit(1)
 	}
+
+	if enableWebhooks {
+		if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			setupLog.Error(err, "unable to create webhook ready check")
+			os.Exit(1)
+		}
+	}
 }
 
 func setupReconcilers(ctx context.Context, mgr ctrl.Manager) {
@@ -213,12 +342,67 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) {
 		os.Exit(1)
 	}
 
+	caBundle, err := caBundleRef()
+	if err != nil {
+		setupLog.Error(err, "invalid Rancher CA bundle configuration")
+		os.Exit(1)
+	}
+
+	readinessGates, err := importReadinessGates()
+	if err != nil {
+		setupLog.Error(err, "invalid import readiness gates configuration")
+		os.Exit(1)
+	}
+
+	clusterTemplates, err := rancherClusterTemplates()
+	if err != nil {
+		setupLog.Error(err, "invalid Rancher cluster templates configuration")
+		os.Exit(1)
+	}
+
+	// Rancher resources are read from the manager cluster, unless a separate Rancher Manager cluster is configured.
+	var (
+		rancherClient client.Client
+		rancherCache  cache.Cache
+	)
+
+	if rancherKubeconfig != "" {
+		rancherCluster, err := newRancherCluster(mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create Rancher Manager cluster client")
+			os.Exit(1)
+		}
+
+		rancherClient = rancherCluster.GetClient()
+		rancherCache = rancherCluster.GetCache()
+	}
+
+	// Rancher Manager clusters of the RancherTargets are shared by the import and cleanup controllers.
+	rancherTargets := controllers.NewRancherTargets(scheme)
+	if err := mgr.Add(rancherTargets); err != nil {
+		setupLog.Error(err, "unable to add Rancher targets")
+		os.Exit(1)
+	}
+
 	if err := (&controllers.CAPIImportReconciler{
-		Client:             mgr.GetClient(),
-		Scheme:             mgr.GetScheme(),
-		UncachedClient:     uncachedClient,
-		WatchFilterValue:   watchFilterValue,
-		InsecureSkipVerify: insecureSkipVerify,
+		Client:                mgr.GetClient(),
+		Scheme:                mgr.GetScheme(),
+		UncachedClient:        uncachedClient,
+		RancherClient:         rancherClient,
+		RancherCache:          rancherCache,
+		RancherTargets:        rancherTargets,
+		WatchFilterValue:      watchFilterValue,
+		InsecureSkipVerify:    insecureSkipVerify,
+		ImportDryRun:          importDryRun,
+		ImportPrune:           importPrune,
+		ManifestCacheTTL:      manifestCacheTTL,
+		ImportRateLimit:       importRateLimit,
+		ImportBurst:           importBurst,
+		CABundle:              caBundle,
+		ReadinessGates:        readinessGates,
+		ClusterTemplates:      clusterTemplates,
+		DetectSelfManaged:     detectSelfManaged,
+		FleetMigrationTimeout: fleetMigrationTimeout,
 	}).SetupWithManager(ctx, mgr, controller.Options{
 		MaxConcurrentReconciles: concurrencyNumber,
 	}); err != nil {
@@ -226,8 +410,25 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) {
 		os.Exit(1)
 	}
 
+	if err := (&controllers.ClusterHealthReconciler{
+		Client:           mgr.GetClient(),
+		RancherClient:    rancherClient,
+		RancherTargets:   rancherTargets,
+		WatchFilterValue: watchFilterValue,
+	}).SetupWithManager(ctx, mgr, controller.Options{
+		MaxConcurrentReconciles: concurrencyNumber,
+	}); err != nil {
+		setupLog.Error(err, "unable to create cluster health controller")
+		os.Exit(1)
+	}
+
 	if err := (&controllers.CAPICleanupReconciler{
-		Client: mgr.GetClient(),
+		Client:            mgr.GetClient(),
+		RancherClient:     rancherClient,
+		RancherCache:      rancherCache,
+		RancherTargets:    rancherTargets,
+		OrphanCleanup:     orphanCleanup,
+		OrphanGracePeriod: orphanGracePeriod,
 	}).SetupWithManager(ctx, mgr, controller.Options{
 		MaxConcurrentReconciles: concurrencyNumber,
 	}); err != nil {
@@ -235,6 +436,25 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) {
 		os.Exit(1)
 	}
 
+	if err := (&controllers.RancherTargetReconciler{
+		Client:  mgr.GetClient(),
+		Targets: rancherTargets,
+	}).SetupWithManager(ctx, mgr, controller.Options{
+		MaxConcurrentReconciles: concurrencyNumber,
+	}); err != nil {
+		setupLog.Error(err, "unable to create Rancher target controller")
+		os.Exit(1)
+	}
+
+	if err := (&controllers.ImportPolicyReconciler{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(ctx, mgr, controller.Options{
+		MaxConcurrentReconciles: concurrencyNumber,
+	}); err != nil {
+		setupLog.Error(err, "unable to create import policy controller")
+		os.Exit(1)
+	}
+
 	setupLog.Info("enabling Clusterctl Config synchronization controller")
 
 	if err := (&controllers.ClusterctlConfigReconciler{
@@ -248,41 +468,266 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) {
 
 	setupLog.Info("enabling CAPI Operator synchronization controller")
 
-	if err := (&controllers.OperatorReconciler{}).SetupWithManager(ctx, mgr, controller.Options{
+	manifestPatchesKey, err := manifestPatchesConfigMap()
+	if err != nil {
+		setupLog.Error(err, "invalid provider manifest patches config map")
+		os.Exit(1)
+	}
+
+	if err := (&controllers.OperatorReconciler{
+		ManifestPatchesConfigMap: manifestPatchesKey,
+	}).SetupWithManager(ctx, mgr, controller.Options{
 		MaxConcurrentReconciles: concurrencyNumber,
 	}); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Operator")
 		os.Exit(1)
 	}
 
-	setupLog.Info("enabling UI installation controller")
+	featureGatesKey, err := featureGatesConfigMap()
+	if err != nil {
+		setupLog.Error(err, "invalid feature gates config map")
+		os.Exit(1)
+	}
 
-	if feature.Gates.Enabled(feature.UIPlugin) {
-		if err := (&controllers.UIPluginReconciler{
-			Client:         mgr.GetClient(),
-			Scheme:         scheme,
-			UncachedClient: uncachedClient,
-		}).SetupWithManager(ctx, mgr, controller.Options{
-			MaxConcurrentReconciles: concurrencyNumber,
-		}); err != nil {
-			setupLog.Error(err, "unable to create UI Plugin controller")
-			os.Exit(1)
-		}
+	feature.SnapshotCommandLine()
+
+	if err := mgr.AddMetricsServerExtraHandler("/feature-gates", feature.StatusHandler()); err != nil {
+		setupLog.Error(err, "unable to add feature gates endpoint")
+		os.Exit(1)
 	}
 
-	if feature.Gates.Enabled(feature.RancherCCTranslation) {
-		setupLog.Info("enabling Rancher Credential translation controller")
+	if err := mgr.Add(&controllers.FeatureGatesRunner{
+		Client:    mgr.GetAPIReader(),
+		ConfigMap: featureGatesKey,
+		Interval:  featureGatesReloadInterval,
+		Manager:   mgr,
+		Controllers: []controllers.GatedController{{
+			Name: "ui-plugin",
+			Gate: feature.UIPlugin,
+			Setup: func(ctx context.Context, gatedMgr ctrl.Manager) error {
+				return (&controllers.UIPluginReconciler{
+					Client:         gatedMgr.GetClient(),
+					Scheme:         scheme,
+					UncachedClient: uncachedClient,
+					TurtlesVersion: version.Get().GitVersion,
+				}).SetupWithManager(ctx, gatedMgr, controller.Options{
+					MaxConcurrentReconciles: concurrencyNumber,
+				})
+			},
+			Cleanup: func(ctx context.Context) error {
+				return controllers.RemoveUIPlugins(ctx, uncachedClient)
+			},
+		}, {
+			Name: "rancher-credential-translation",
+			Gate: feature.RancherCCTranslation,
+			Setup: func(ctx context.Context, gatedMgr ctrl.Manager) error {
+				return (&controllers.RancherCredentialReconciler{
+					Client:        gatedMgr.GetClient(),
+					RancherClient: rancherClient,
+					RancherCache:  rancherCache,
+					Translators: map[string]controllers.CredentialTranslator{
+						"aws": &controllers.AWSTranslator{},
+					},
+				}).SetupWithManager(ctx, gatedMgr, controller.Options{
+					MaxConcurrentReconciles: concurrencyNumber,
+				})
+			},
+		}, {
+			Name: "etcd-snapshots",
+			Gate: feature.EtcdSnapshots,
+			Setup: func(ctx context.Context, gatedMgr ctrl.Manager) error {
+				if err := (&controllers.EtcdSnapshotInventoryReconciler{
+					Client:           gatedMgr.GetClient(),
+					WatchFilterValue: watchFilterValue,
+					SyncInterval:     etcdSnapshotSyncInterval,
+				}).SetupWithManager(ctx, gatedMgr, controller.Options{
+					MaxConcurrentReconciles: concurrencyNumber,
+				}); err != nil {
+					return err
+				}
+
+				if err := (&controllers.EtcdSnapshotReconciler{
+					Client:   gatedMgr.GetClient(),
+					JobImage: etcdSnapshotJobImage,
+				}).SetupWithManager(ctx, gatedMgr, controller.Options{
+					MaxConcurrentReconciles: concurrencyNumber,
+				}); err != nil {
+					return err
+				}
+
+				return (&controllers.EtcdSnapshotScheduleReconciler{
+					Client: gatedMgr.GetClient(),
+				}).SetupWithManager(ctx, gatedMgr, controller.Options{
+					MaxConcurrentReconciles: concurrencyNumber,
+				})
+			},
+		}},
+	}); err != nil {
+		setupLog.Error(err, "unable to add feature gated controllers")
+		os.Exit(1)
+	}
+}
 
-		if err := (&controllers.RancherCredentialReconciler{
-			Client: mgr.GetClient(),
-			Translators: map[string]controllers.CredentialTranslator{
-				"aws": &controllers.AWSTranslator{},
+// newRancherCluster creates the client and cache for the Rancher Manager cluster from the kubeconfig file,
+// and adds them to the manager, so the cache is started together with the controllers.
+func newRancherCluster(mgr ctrl.Manager) (cluster.Cluster, error) {
+	cfg, err := clientcmd.BuildConfigFromFlags("", rancherKubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("loading Rancher kubeconfig %s: %w", rancherKubeconfig, err)
+	}
+
+	rancherCluster, err := cluster.New(cfg, func(o *cluster.Options) {
+		o.Scheme = scheme
+		o.Cache.SyncPeriod = &syncPeriod
+		o.Client.Cache = &client.CacheOptions{
+			DisableFor: []client.Object{
+				&corev1.ConfigMap{},
+				&corev1.Secret{},
 			},
-		}).SetupWithManager(ctx, mgr, controller.Options{
-			MaxConcurrentReconciles: concurrencyNumber,
-		}); err != nil {
-			setupLog.Error(err, "unable to create Rancher Credential translation controller")
-			os.Exit(1)
 		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating Rancher Manager cluster: %w", err)
+	}
+
+	if err := mgr.Add(rancherCluster); err != nil {
+		return nil, fmt.Errorf("adding Rancher Manager cluster to the manager: %w", err)
+	}
+
+	return rancherCluster, nil
+}
+
+func setupWebhooks(mgr ctrl.Manager) {
+	if !enableWebhooks {
+		return
+	}
+
+	setupLog.Info("enabling admission webhooks")
+
+	// The webhooks only receive requests from the cluster Turtles runs in. Rancher clusters of a separate
+	// Rancher Manager cluster, or of a RancherTarget, are not protected from deletion, and their
+	// Kubernetes version is not enforced.
+	if rancherKubeconfig != "" {
+		setupLog.Info("Rancher cluster webhook is not available for the Rancher Manager cluster set by --rancher-kubeconfig, " +
+			"Rancher clusters are not protected from deletion and their Kubernetes version is not enforced")
+	}
+
+	if err := (&webhooks.ClusterValidator{
+		Client: mgr.GetClient(),
+	}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "Cluster")
+		os.Exit(1)
+	}
+
+	if err := (&webhooks.CAPIClusterValidator{
+		Client: mgr.GetClient(),
+	}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "CAPICluster")
+		os.Exit(1)
+	}
+
+	if err := (&webhooks.CAPIProviderValidator{
+		Client: mgr.GetAPIReader(),
+	}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "CAPIProvider")
+		os.Exit(1)
+	}
+}
+
+// caBundleRef builds the reference to the Rancher CA bundle from the command line flags.
+func caBundleRef() (controllers.CABundleRef, error) {
+	if caBundleSecret != "" && caBundleConfigMap != "" {
+		return controllers.CABundleRef{}, errors.New("only one of --rancher-ca-bundle-secret and --rancher-ca-bundle-configmap can be set")
+	}
+
+	kind, flag, value := "Secret", "rancher-ca-bundle-secret", caBundleSecret
+	if caBundleConfigMap != "" {
+		kind, flag, value = "ConfigMap", "rancher-ca-bundle-configmap", caBundleConfigMap
 	}
+
+	if value == "" {
+		return controllers.CABundleRef{}, nil
+	}
+
+	key, err := parseNamespacedName(flag, value)
+	if err != nil {
+		return controllers.CABundleRef{}, err
+	}
+
+	return controllers.CABundleRef{
+		Kind:      kind,
+		Namespace: key.Namespace,
+		Name:      key.Name,
+		Key:       caBundleKey,
+	}, nil
+}
+
+// featureGatesConfigMap builds the reference to the feature gates ConfigMap from the command line flags.
+func featureGatesConfigMap() (client.ObjectKey, error) {
+	if featureGatesConfigMapRef == "" {
+		return client.ObjectKey{}, nil
+	}
+
+	return parseNamespacedName("feature-gates-configmap", featureGatesConfigMapRef)
+}
+
+// manifestPatchesConfigMap builds the reference to the provider manifest patches ConfigMap from the command line flags.
+func manifestPatchesConfigMap() (client.ObjectKey, error) {
+	if manifestPatchesConfigMapRef == "" {
+		return client.ObjectKey{}, nil
+	}
+
+	return parseNamespacedName("provider-manifest-patches-configmap", manifestPatchesConfigMapRef)
+}
+
+// importReadinessGates builds the import readiness gates from the command line flags.
+func importReadinessGates() (controllers.ImportReadinessGates, error) {
+	gates := controllers.ImportReadinessGates{
+		MinReadyNodes:  importMinReadyNodes,
+		RequireCoreDNS: importRequireCoreDNS,
+	}
+
+	if importReadinessChecks == "" {
+		return gates, nil
+	}
+
+	checks, err := parseNamespacedName("import-readiness-checks-configmap", importReadinessChecks)
+	if err != nil {
+		return gates, err
+	}
+
+	gates.ChecksConfigMap = checks
+
+	return gates, nil
+}
+
+// rancherClusterTemplates builds the Rancher cluster display name and description templates from the command line flags.
+func rancherClusterTemplates() (controllers.ClusterTemplates, error) {
+	templates := controllers.ClusterTemplates{
+		DisplayName: displayNameTemplate,
+		Description: descriptionTemplate,
+	}
+
+	if clusterTemplatesConfigMap == "" {
+		return templates, nil
+	}
+
+	configMap, err := parseNamespacedName("rancher-cluster-templates-configmap", clusterTemplatesConfigMap)
+	if err != nil {
+		return templates, err
+	}
+
+	templates.ConfigMap = configMap
+
+	return templates, nil
+}
+
+// parseNamespacedName parses the namespace/name value of the given command line flag.
+func parseNamespacedName(flag, value string) (client.ObjectKey, error) {
+	namespace, name, found := strings.Cut(value, "/")
+	if !found || namespace == "" || name == "" {
+		return client.ObjectKey{}, fmt.Errorf("invalid --%s value %q, expected namespace/name", flag, value)
+	}
+
+	return client.ObjectKey{Namespace: namespace, Name: name}, nil
 }
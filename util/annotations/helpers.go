@@ -17,6 +17,8 @@ limitations under the License.
 package annotations
 
 import (
+	"strconv"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -44,6 +46,78 @@ const (
 	// CAPIIdentityRefAnnotation is the annotation added to a Rancher Cloud Credential
 	// to reference the translated CAPI identity object name.
 	CAPIIdentityRefAnnotation = "cluster-api.cattle.io/capi-static-identity-ref"
+	// ClusterReimportAnnotation is a CAPI cluster annotation, requesting Turtles to regenerate the registration token
+	// and re-apply the Rancher agent manifest on the workload cluster.
+	ClusterReimportAnnotation = "cluster-api.cattle.io/reimport"
+	// ClusterImportDryRunAnnotation is a CAPI cluster annotation, which makes Turtles render the import manifest
+	// and report the changes it would make on the workload cluster, without applying them.
+	ClusterImportDryRunAnnotation = "cluster-api.cattle.io/import-dry-run"
+	// ImportInventoryAnnotation is a Rancher management Cluster annotation, listing the objects applied
+	// on the workload cluster from the import manifest. It is used to prune objects removed from the manifest.
+	ImportInventoryAnnotation = "cluster-api.cattle.io/import-inventory"
+	// OrphanedSinceAnnotation is a Rancher management Cluster annotation, holding the time since which
+	// the owning CAPI cluster no longer exists.
+	OrphanedSinceAnnotation = "cluster-api.cattle.io/orphaned-since"
+	// DeletionProtectionAnnotation is a CAPI cluster annotation, which makes Turtles deny the deletion
+	// of the Rancher management Cluster created for the CAPI cluster.
+	DeletionProtectionAnnotation = "cluster-api.cattle.io/deletion-protection"
+	// ForceDeleteAnnotation is a CAPIProvider annotation, which allows the deletion of the provider
+	// while objects of its CRDs still exist.
+	ForceDeleteAnnotation = "turtles-capi.cattle.io/force-delete"
+	// UIPluginStateAnnotation is a UIPlugin annotation, reporting the state of the CAPI UI extension managed by Turtles.
+	UIPluginStateAnnotation = "turtles-capi.cattle.io/ui-plugin-state"
+	// UIPluginTurtlesVersionAnnotation is a UIPlugin annotation, holding the Turtles version which last reconciled the UI extension.
+	UIPluginTurtlesVersionAnnotation = "turtles-capi.cattle.io/turtles-version"
+	// RotateCertificatesAnnotation is a CAPIProvider annotation, requesting Turtles to regenerate the provider
+	// webhook serving certificates issued by wrangler. It is removed once the rotation has started.
+	RotateCertificatesAnnotation = "turtles-capi.cattle.io/rotate-certificates"
+	// ManifestPatchesAnnotation is a CAPIProvider annotation, naming a ConfigMap in the provider namespace
+	// with patches applied to the provider manifest.
+	ManifestPatchesAnnotation = "turtles-capi.cattle.io/manifest-patches"
+	// ManifestPatchesHashAnnotation is a CAPIProvider annotation, holding the hash of the manifest patches
+	// applied with the provider manifest.
+	ManifestPatchesHashAnnotation = "turtles-capi.cattle.io/manifest-patches-hash"
+	// RancherTargetAnnotation is a CAPI cluster or namespace annotation, naming the RancherTarget
+	// where the cluster is imported. The cluster annotation takes precedence.
+	RancherTargetAnnotation = "turtles-capi.cattle.io/rancher-target"
+	// SelfManagedClusterAnnotation is a CAPI cluster annotation, marking the cluster as the management cluster
+	// where Turtles and Rancher run. Set to false to disable the self-managed cluster detection.
+	SelfManagedClusterAnnotation = "cluster-api.cattle.io/self-managed"
+	// RancherClusterAnnotation is a CAPI cluster annotation, holding the name of the Rancher management Cluster
+	// it was imported as. It is used to link the cluster again after it was moved between management clusters.
+	RancherClusterAnnotation = "cluster-api.cattle.io/rancher-cluster"
+	// ManagementClusterAnnotation is a Rancher management Cluster annotation, holding the UID of the kube-system
+	// namespace of the CAPI management cluster which imported it.
+	ManagementClusterAnnotation = "cluster-api.cattle.io/management-cluster"
+	// VersionManagementAnnotation is a CAPI cluster or namespace annotation, selecting whether CAPI or Rancher manages
+	// the Kubernetes version of the cluster. The cluster annotation takes precedence. Defaults to capi.
+	// It is mirrored on the Rancher management Cluster.
+	VersionManagementAnnotation = "cluster-api.cattle.io/version-management"
+	// CAPIHealthAnnotation is a Rancher management Cluster annotation, summarizing the health of the CAPI cluster.
+	// It is either Healthy, or lists the unhealthy CAPI cluster conditions.
+	CAPIHealthAnnotation = "cluster-api.cattle.io/capi-health"
+	// CAPIControlPlaneReadyAnnotation is a Rancher management Cluster annotation, reporting whether the control
+	// plane of the CAPI cluster is available.
+	CAPIControlPlaneReadyAnnotation = "cluster-api.cattle.io/capi-control-plane-ready"
+	// CAPIMachinesAnnotation is a Rancher management Cluster annotation, holding the ready and desired
+	// machines of the CAPI cluster in the ready/desired format.
+	CAPIMachinesAnnotation = "cluster-api.cattle.io/capi-machines"
+	// CAPITopologyVersionAnnotation is a Rancher management Cluster annotation, holding the Kubernetes version
+	// of the CAPI cluster topology.
+	CAPITopologyVersionAnnotation = "cluster-api.cattle.io/capi-topology-version"
+	// CAPIPausedAnnotation is a Rancher management Cluster annotation, reporting whether the reconciliation
+	// of the CAPI cluster is paused.
+	CAPIPausedAnnotation = "cluster-api.cattle.io/capi-paused"
+	// SkipRemoteCleanupAnnotation is an EtcdSnapshot annotation, which makes Turtles remove the snapshot
+	// without deleting its snapshot files on the workload cluster.
+	SkipRemoteCleanupAnnotation = "turtles-capi.cattle.io/skip-remote-cleanup"
+)
+
+const (
+	// VersionManagementCAPI is the VersionManagementAnnotation value, for clusters upgraded with the CAPI topology.
+	VersionManagementCAPI = "capi"
+	// VersionManagementRancher is the VersionManagementAnnotation value, for clusters upgraded with Rancher.
+	VersionManagementRancher = "rancher"
 )
 
 // HasClusterImportAnnotation returns true if the object has the `imported` annotation.
@@ -51,6 +125,51 @@ func HasClusterImportAnnotation(o metav1.Object) bool {
 	return HasAnnotation(o, ClusterImportedAnnotation)
 }
 
+// HasClusterReimportAnnotation returns true if the object has the `cluster-api.cattle.io/reimport` annotation.
+func HasClusterReimportAnnotation(o metav1.Object) bool {
+	return HasAnnotation(o, ClusterReimportAnnotation)
+}
+
+// IsClusterImportDryRun returns true if the object has the `cluster-api.cattle.io/import-dry-run` annotation set to true.
+func IsClusterImportDryRun(o metav1.Object) bool {
+	dryRun, err := strconv.ParseBool(o.GetAnnotations()[ClusterImportDryRunAnnotation])
+
+	return err == nil && dryRun
+}
+
+// IsDeletionProtected returns true if the object has the `cluster-api.cattle.io/deletion-protection` annotation set to true.
+func IsDeletionProtected(o metav1.Object) bool {
+	protected, err := strconv.ParseBool(o.GetAnnotations()[DeletionProtectionAnnotation])
+
+	return err == nil && protected
+}
+
+// IsForceDelete returns true if the object has the `turtles-capi.cattle.io/force-delete` annotation set to true.
+func IsForceDelete(o metav1.Object) bool {
+	force, err := strconv.ParseBool(o.GetAnnotations()[ForceDeleteAnnotation])
+
+	return err == nil && force
+}
+
+// IsSkipRemoteCleanup returns true if the object has the `turtles-capi.cattle.io/skip-remote-cleanup` annotation set to true.
+func IsSkipRemoteCleanup(o metav1.Object) bool {
+	skip, err := strconv.ParseBool(o.GetAnnotations()[SkipRemoteCleanupAnnotation])
+
+	return err == nil && skip
+}
+
+// IsSelfManagedCluster returns true if the object has the `cluster-api.cattle.io/self-managed` annotation set to true.
+func IsSelfManagedCluster(o metav1.Object) bool {
+	selfManaged, err := strconv.ParseBool(o.GetAnnotations()[SelfManagedClusterAnnotation])
+
+	return err == nil && selfManaged
+}
+
+// HasRotateCertificatesAnnotation returns true if the object has the `turtles-capi.cattle.io/rotate-certificates` annotation.
+func HasRotateCertificatesAnnotation(o metav1.Object) bool {
+	return HasAnnotation(o, RotateCertificatesAnnotation)
+}
+
 // HasAnnotation returns true if the object has the specified annotation.
 func HasAnnotation(o metav1.Object, annotation string) bool {
 	annotations := o.GetAnnotations()
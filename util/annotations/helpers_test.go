@@ -71,6 +71,98 @@ var _ = Describe("ClusterWithoutImportedAnnotation", func() {
 	})
 })
 
+var _ = Describe("IsClusterImportDryRun", func() {
+	It("should return true when annotation is set to true", func() {
+		obj := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					ClusterImportDryRunAnnotation: "true",
+				},
+			},
+		}
+		Expect(IsClusterImportDryRun(obj)).To(BeTrue())
+	})
+
+	It("should return false when annotation is not a true value", func() {
+		obj := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					ClusterImportDryRunAnnotation: "invalid",
+				},
+			},
+		}
+		Expect(IsClusterImportDryRun(obj)).To(BeFalse())
+		Expect(IsClusterImportDryRun(&clusterv1.Cluster{})).To(BeFalse())
+	})
+})
+
+var _ = Describe("IsDeletionProtected", func() {
+	It("should return true when annotation is set to true", func() {
+		obj := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					DeletionProtectionAnnotation: "true",
+				},
+			},
+		}
+		Expect(IsDeletionProtected(obj)).To(BeTrue())
+	})
+
+	It("should return false when annotation is missing or false", func() {
+		obj := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					DeletionProtectionAnnotation: "false",
+				},
+			},
+		}
+		Expect(IsDeletionProtected(obj)).To(BeFalse())
+		Expect(IsDeletionProtected(&clusterv1.Cluster{})).To(BeFalse())
+	})
+})
+
+var _ = Describe("IsForceDelete", func() {
+	It("should return true only when annotation is set to true", func() {
+		obj := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					ForceDeleteAnnotation: "true",
+				},
+			},
+		}
+		Expect(IsForceDelete(obj)).To(BeTrue())
+		Expect(IsForceDelete(&clusterv1.Cluster{})).To(BeFalse())
+	})
+})
+
+var _ = Describe("IsSkipRemoteCleanup", func() {
+	It("should return true only when annotation is set to true", func() {
+		obj := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					SkipRemoteCleanupAnnotation: "true",
+				},
+			},
+		}
+		Expect(IsSkipRemoteCleanup(obj)).To(BeTrue())
+		Expect(IsSkipRemoteCleanup(&clusterv1.Cluster{})).To(BeFalse())
+	})
+})
+
+var _ = Describe("HasRotateCertificatesAnnotation", func() {
+	It("should return true when annotation is present", func() {
+		obj := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					RotateCertificatesAnnotation: "",
+				},
+			},
+		}
+		Expect(HasRotateCertificatesAnnotation(obj)).To(BeTrue())
+		Expect(HasRotateCertificatesAnnotation(&clusterv1.Cluster{})).To(BeFalse())
+	})
+})
+
 func TestAnnotationHelpers(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "AnnotationHelpers Suite")
@@ -66,6 +66,40 @@ func processIfClusterNotImported(logger logr.Logger, obj client.Object) bool {
 	return true
 }
 
+// ClusterWithReimportAnnotation returns a predicate that returns true only if the provided resource contains
+// the "reimport" annotation. Such clusters are reconciled even if they were imported already.
+func ClusterWithReimportAnnotation(logger logr.Logger) predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return processIfClusterReimport(logger.WithValues("predicate", "ClusterWithReimportAnnotation", "eventType", "update"), e.ObjectNew)
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return processIfClusterReimport(logger.WithValues("predicate", "ClusterWithReimportAnnotation", "eventType", "create"), e.Object)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return processIfClusterReimport(logger.WithValues("predicate", "ClusterWithReimportAnnotation", "eventType", "delete"), e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return processIfClusterReimport(logger.WithValues("predicate", "ClusterWithReimportAnnotation", "eventType", "generic"), e.Object)
+		},
+	}
+}
+
+// processIfClusterReimport returns true if the provided object has the reimport annotation.
+func processIfClusterReimport(logger logr.Logger, obj client.Object) bool {
+	kind := strings.ToLower(obj.GetObjectKind().GroupVersionKind().Kind)
+	log := logger.WithValues("namespace", obj.GetNamespace(), kind, obj.GetName())
+
+	if annotations.HasClusterReimportAnnotation(obj) {
+		log.V(4).Info("Cluster has a reimport annotation, will attempt to map resource")
+		return true
+	}
+
+	log.V(6).Info("Cluster does not have a reimport annotation, will not attempt to map resource")
+
+	return false
+}
+
 // ClusterWithReadyControlPlane returns a predicate that returns true only if the provided resource is a cluster with a
 // ready control plane.
 func ClusterWithReadyControlPlane(logger logr.Logger) predicate.Funcs {
@@ -149,3 +183,37 @@ func processIfClusterOrNamespaceWithImportLabel(ctx context.Context, logger logr
 
 	return shouldImport
 }
+
+// ClusterWithRancherCluster returns a predicate that returns true only if the provided resource is a cluster which was
+// imported as a Rancher cluster, recorded in the "rancherClusterAnnotation" annotation.
+func ClusterWithRancherCluster(logger logr.Logger) predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return processIfClusterWithRancherCluster(logger.WithValues("predicate", "ClusterWithRancherCluster", "eventType", "update"), e.ObjectNew)
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return processIfClusterWithRancherCluster(logger.WithValues("predicate", "ClusterWithRancherCluster", "eventType", "create"), e.Object)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return processIfClusterWithRancherCluster(logger.WithValues("predicate", "ClusterWithRancherCluster", "eventType", "delete"), e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return processIfClusterWithRancherCluster(logger.WithValues("predicate", "ClusterWithRancherCluster", "eventType", "generic"), e.Object)
+		},
+	}
+}
+
+// processIfClusterWithRancherCluster returns true if the provided object has the Rancher cluster annotation.
+func processIfClusterWithRancherCluster(logger logr.Logger, obj client.Object) bool {
+	kind := strings.ToLower(obj.GetObjectKind().GroupVersionKind().Kind)
+	log := logger.WithValues("namespace", obj.GetNamespace(), kind, obj.GetName())
+
+	if annotations.HasAnnotation(obj, annotations.RancherClusterAnnotation) {
+		log.V(6).Info("Cluster has a Rancher cluster annotation, will attempt to map resource")
+		return true
+	}
+
+	log.V(4).Info("Cluster does not have a Rancher cluster annotation, will not attempt to map resource")
+
+	return false
+}
@@ -20,9 +20,12 @@ import (
 	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
+	"github.com/rancher/turtles/util"
 	"github.com/rancher/turtles/util/annotations"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -72,6 +75,73 @@ var _ = Describe("ClusterWithoutImportedAnnotation", func() {
 	})
 })
 
+var _ = Describe("ClusterWithReimportAnnotation", func() {
+	var (
+		logger      logr.Logger
+		capiCluster *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		// Initialize the logger
+		logger = logr.Discard()
+
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-cluster",
+				Namespace: "test-ns",
+			},
+		}
+	})
+
+	It("should return true when cluster has the reimport annotation", func() {
+		capiCluster.Annotations = map[string]string{
+			annotations.ClusterImportedAnnotation: "true",
+			annotations.ClusterReimportAnnotation: "",
+		}
+		result := ClusterWithReimportAnnotation(logger).UpdateFunc(event.UpdateEvent{ObjectNew: capiCluster})
+		Expect(result).To(BeTrue())
+	})
+
+	It("should return false when cluster has no reimport annotation", func() {
+		capiCluster.Annotations = map[string]string{
+			annotations.ClusterImportedAnnotation: "true",
+		}
+		result := ClusterWithReimportAnnotation(logger).UpdateFunc(event.UpdateEvent{ObjectNew: capiCluster})
+		Expect(result).To(BeFalse())
+	})
+})
+
+var _ = Describe("ClusterWithRancherCluster", func() {
+	var (
+		logger      logr.Logger
+		capiCluster *clusterv1.Cluster
+	)
+
+	BeforeEach(func() {
+		logger = logr.Discard()
+
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-cluster",
+				Namespace: "test-ns",
+			},
+		}
+	})
+
+	It("should return true when cluster has the Rancher cluster annotation", func() {
+		capiCluster.Annotations = map[string]string{
+			annotations.RancherClusterAnnotation: "c-test",
+		}
+		result := ClusterWithRancherCluster(logger).UpdateFunc(event.UpdateEvent{ObjectNew: capiCluster})
+		Expect(result).To(BeTrue())
+	})
+
+	It("should return false when cluster has no Rancher cluster annotation", func() {
+		result := ClusterWithRancherCluster(logger).UpdateFunc(event.UpdateEvent{ObjectNew: capiCluster})
+		Expect(result).To(BeFalse())
+	})
+})
+
 var _ = Describe("ClusterWithReadyControlPlane", func() {
 	var (
 		logger      logr.Logger
@@ -166,4 +236,147 @@ var _ = Describe("ClusterOrNamespaceWithImportLabel", func() {
 		result := ClusterOrNamespaceWithImportLabel(ctx, logger, cl, importLabel).UpdateFunc(event.UpdateEvent{ObjectNew: capiCluster})
 		Expect(result).To(BeFalse())
 	})
+
+	It("should follow the import policies when namespace has no import label", func() {
+		namespace.Name = "test-ns-3"
+		namespace.Labels = map[string]string{"env": "prod"}
+		Expect(cl.Create(ctx, namespace)).To(Succeed())
+
+		policy := &turtlesv1.ImportPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "import-prod",
+			},
+			Spec: turtlesv1.ImportPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+				Exclusions: &turtlesv1.ImportPolicyExclusions{
+					ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"import": "skip"}},
+				},
+			},
+		}
+		Expect(cl.Create(ctx, policy)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(cl.Delete(ctx, policy)).To(Succeed())
+		})
+
+		capiCluster.Namespace = namespace.Name
+
+		Eventually(func() bool {
+			return ClusterOrNamespaceWithImportLabel(ctx, logger, cl, importLabel).UpdateFunc(event.UpdateEvent{ObjectNew: capiCluster})
+		}).Should(BeTrue())
+
+		capiCluster.Labels = map[string]string{"import": "skip"}
+		result := ClusterOrNamespaceWithImportLabel(ctx, logger, cl, importLabel).UpdateFunc(event.UpdateEvent{ObjectNew: capiCluster})
+		Expect(result).To(BeFalse())
+
+		capiCluster.Labels = map[string]string{"import": "skip", importLabel: "true"}
+		result = ClusterOrNamespaceWithImportLabel(ctx, logger, cl, importLabel).UpdateFunc(event.UpdateEvent{ObjectNew: capiCluster})
+		Expect(result).To(BeTrue())
+	})
+})
+
+var _ = Describe("MatchImportPolicies", func() {
+	var (
+		logger      = logr.Discard()
+		capiCluster *clusterv1.Cluster
+		namespace   *corev1.Namespace
+	)
+
+	BeforeEach(func() {
+		capiCluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-cluster",
+				Namespace: "test-ns",
+			},
+			Spec: clusterv1.ClusterSpec{
+				Topology: clusterv1.Topology{
+					ClassRef: clusterv1.ClusterClassRef{Name: "rke2-aws"},
+					Version:  "v1.33.0",
+				},
+			},
+		}
+
+		namespace = &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-ns",
+			},
+		}
+	})
+
+	It("should use the policy with the highest priority", func() {
+		policies := []turtlesv1.ImportPolicy{{
+			Spec: turtlesv1.ImportPolicySpec{Priority: 1, AutoImport: ptr.To(false)},
+		}, {
+			Spec: turtlesv1.ImportPolicySpec{Priority: 10, ClusterClassNames: []string{"rke2-aws"}},
+		}}
+
+		matched, autoImport := util.MatchImportPolicies(logger, policies, capiCluster, namespace)
+		Expect(matched).To(BeTrue())
+		Expect(autoImport).To(BeTrue())
+	})
+
+	It("should not import when policies with the same priority disagree", func() {
+		policies := []turtlesv1.ImportPolicy{{
+			Spec: turtlesv1.ImportPolicySpec{AutoImport: ptr.To(true)},
+		}, {
+			Spec: turtlesv1.ImportPolicySpec{AutoImport: ptr.To(false)},
+		}}
+
+		matched, autoImport := util.MatchImportPolicies(logger, policies, capiCluster, namespace)
+		Expect(matched).To(BeTrue())
+		Expect(autoImport).To(BeFalse())
+	})
+
+	It("should not match excluded namespaces and other cluster classes", func() {
+		policies := []turtlesv1.ImportPolicy{{
+			Spec: turtlesv1.ImportPolicySpec{
+				Exclusions: &turtlesv1.ImportPolicyExclusions{Namespaces: []string{"test-ns"}},
+			},
+		}, {
+			Spec: turtlesv1.ImportPolicySpec{ClusterClassNames: []string{"kubeadm-docker"}},
+		}}
+
+		matched, _ := util.MatchImportPolicies(logger, policies, capiCluster, namespace)
+		Expect(matched).To(BeFalse())
+	})
+
+	It("should skip policies with an invalid selector and evaluate the rest", func() {
+		policies := []turtlesv1.ImportPolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid"},
+			Spec: turtlesv1.ImportPolicySpec{
+				Priority: 10,
+				ClusterSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: metav1.LabelSelectorOpIn}},
+				},
+			},
+		}, {
+			ObjectMeta: metav1.ObjectMeta{Name: "valid"},
+			Spec:       turtlesv1.ImportPolicySpec{AutoImport: ptr.To(false)},
+		}}
+
+		matched, autoImport := util.MatchImportPolicies(logger, policies, capiCluster, namespace)
+		Expect(matched).To(BeTrue())
+		Expect(autoImport).To(BeFalse())
+	})
+})
+
+var _ = Describe("ValidateImportPolicy", func() {
+	It("should accept a policy without selectors", func() {
+		Expect(util.ValidateImportPolicy(&turtlesv1.ImportPolicy{})).To(Succeed())
+	})
+
+	It("should report the invalid selector", func() {
+		policy := &turtlesv1.ImportPolicy{
+			Spec: turtlesv1.ImportPolicySpec{
+				Exclusions: &turtlesv1.ImportPolicyExclusions{
+					ClusterSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Matches"}},
+					},
+				},
+			},
+		}
+
+		err := util.ValidateImportPolicy(policy)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.exclusions.clusterSelector"))
+	})
 })
@@ -18,15 +18,21 @@ package util
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"strconv"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
 
+	turtlesv1 "github.com/rancher/turtles/api/v1alpha1"
 	turtlesannotations "github.com/rancher/turtles/util/annotations"
 )
 
@@ -45,7 +51,8 @@ func ShouldImport(obj metav1.Object, label string) (hasLabel bool, labelValue bo
 	return true, autoImport
 }
 
-// ShouldAutoImport checks if the namespace or cluster has the label set to true.
+// ShouldAutoImport checks if the cluster should be imported. The import label on the cluster takes precedence,
+// followed by the matching import policies and the import label on the namespace.
 func ShouldAutoImport(ctx context.Context, logger logr.Logger, cl client.Client, capiCluster *clusterv1.Cluster, label string) (bool, error) {
 	logger.V(2).Info("should we auto import the capi cluster", "name", capiCluster.Name, "namespace", capiCluster.Namespace)
 
@@ -63,7 +70,6 @@ func ShouldAutoImport(ctx context.Context, logger logr.Logger, cl client.Client,
 		return false, nil
 	}
 
-	// Check namespace wide
 	ns := &corev1.Namespace{}
 	key := client.ObjectKey{Name: capiCluster.Namespace}
 
@@ -72,7 +78,133 @@ func ShouldAutoImport(ctx context.Context, logger logr.Logger, cl client.Client,
 		return false, err
 	}
 
+	// Check import policies
+	policies := &turtlesv1.ImportPolicyList{}
+	if err := cl.List(ctx, policies); err != nil {
+		logger.Error(err, "listing import policies")
+		return false, err
+	}
+
+	matched, autoImport := MatchImportPolicies(logger, policies.Items, capiCluster, ns)
+	if matched {
+		logger.V(2).Info("Cluster is matched by import policy", "autoImport", autoImport)
+
+		return autoImport, nil
+	}
+
+	// Check namespace wide
 	_, autoImport = ShouldImport(ns, label)
 
 	return autoImport, nil
 }
+
+// VersionManagement returns whether CAPI or Rancher manages the Kubernetes version of the cluster. The version
+// management annotation on the cluster takes precedence over the annotation on the namespace.
+func VersionManagement(ctx context.Context, cl client.Client, capiCluster *clusterv1.Cluster) (string, error) {
+	policy := capiCluster.GetAnnotations()[turtlesannotations.VersionManagementAnnotation]
+
+	if policy == "" {
+		ns := &corev1.Namespace{}
+		if err := cl.Get(ctx, client.ObjectKey{Name: capiCluster.Namespace}, ns); err != nil {
+			return "", fmt.Errorf("getting namespace %s: %w", capiCluster.Namespace, err)
+		}
+
+		policy = ns.GetAnnotations()[turtlesannotations.VersionManagementAnnotation]
+	}
+
+	switch policy {
+	case "":
+		return turtlesannotations.VersionManagementCAPI, nil
+	case turtlesannotations.VersionManagementCAPI, turtlesannotations.VersionManagementRancher:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid %s annotation value %q, expected %s or %s", turtlesannotations.VersionManagementAnnotation,
+			policy, turtlesannotations.VersionManagementCAPI, turtlesannotations.VersionManagementRancher)
+	}
+}
+
+// MatchImportPolicies evaluates the import policies for the cluster in the namespace. It returns whether any policy
+// matched the cluster, and if the cluster should be imported according to the policy with the highest priority.
+// When matching policies with the same highest priority disagree, the cluster is not imported.
+// Policies with an invalid selector are logged and skipped, the remaining policies are still evaluated.
+func MatchImportPolicies(logger logr.Logger, policies []turtlesv1.ImportPolicy, cluster *clusterv1.Cluster, ns *corev1.Namespace) (bool, bool) {
+	var (
+		matched    bool
+		autoImport bool
+		priority   int32
+	)
+
+	for i := range policies {
+		policy := &policies[i]
+
+		if err := ValidateImportPolicy(policy); err != nil {
+			logger.Error(err, "skipping import policy with an invalid selector", "policy", policy.Name)
+			continue
+		}
+
+		switch {
+		case !importPolicyMatches(policy, cluster, ns):
+			continue
+		case !matched || policy.Spec.Priority > priority:
+			matched, autoImport, priority = true, policy.ShouldAutoImport(), policy.Spec.Priority
+		case policy.Spec.Priority == priority:
+			autoImport = autoImport && policy.ShouldAutoImport()
+		}
+	}
+
+	return matched, autoImport
+}
+
+// ValidateImportPolicy returns an error if a selector of the import policy is invalid.
+func ValidateImportPolicy(policy *turtlesv1.ImportPolicy) error {
+	specPath := field.NewPath("spec")
+
+	errs := metav1validation.ValidateLabelSelector(policy.Spec.NamespaceSelector,
+		metav1validation.LabelSelectorValidationOptions{}, specPath.Child("namespaceSelector"))
+	errs = append(errs, metav1validation.ValidateLabelSelector(policy.Spec.ClusterSelector,
+		metav1validation.LabelSelectorValidationOptions{}, specPath.Child("clusterSelector"))...)
+
+	if policy.Spec.Exclusions != nil {
+		errs = append(errs, metav1validation.ValidateLabelSelector(policy.Spec.Exclusions.ClusterSelector,
+			metav1validation.LabelSelectorValidationOptions{}, specPath.Child("exclusions", "clusterSelector"))...)
+	}
+
+	return errs.ToAggregate()
+}
+
+// importPolicyMatches returns true if the policy matches the cluster. The selectors of the policy must be valid.
+func importPolicyMatches(policy *turtlesv1.ImportPolicy, cluster *clusterv1.Cluster, ns *corev1.Namespace) bool {
+	if exclusions := policy.Spec.Exclusions; exclusions != nil {
+		if slices.Contains(exclusions.Namespaces, cluster.Namespace) {
+			return false
+		}
+
+		if exclusions.ClusterSelector != nil && selectorMatches(exclusions.ClusterSelector, cluster.Labels) {
+			return false
+		}
+	}
+
+	if len(policy.Spec.ClusterClassNames) > 0 &&
+		(!cluster.Spec.Topology.IsDefined() || !slices.Contains(policy.Spec.ClusterClassNames, cluster.Spec.Topology.ClassRef.Name)) {
+		return false
+	}
+
+	if policy.Spec.NamespaceSelector != nil && !selectorMatches(policy.Spec.NamespaceSelector, ns.Labels) {
+		return false
+	}
+
+	if policy.Spec.ClusterSelector != nil && !selectorMatches(policy.Spec.ClusterSelector, cluster.Labels) {
+		return false
+	}
+
+	return true
+}
+
+func selectorMatches(selector *metav1.LabelSelector, objLabels map[string]string) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+
+	return s.Matches(labels.Set(objLabels))
+}